@@ -1,14 +1,18 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/CESARBR/knot-babeltower/internal/config"
 	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/jwt"
 	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/server"
+	thingCommands "github.com/CESARBR/knot-babeltower/pkg/thing/commands"
 	thingControllers "github.com/CESARBR/knot-babeltower/pkg/thing/controllers"
 	thingDeliveryAMQP "github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
 	thingDeliveryHTTP "github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
@@ -26,6 +30,103 @@ func monitorSignals(sigs chan os.Signal, quit chan bool, logger logging.Logger)
 	quit <- true
 }
 
+// newTransport creates the messaging transport selected in the configuration
+func newTransport(config config.Config, logrus *logging.Logrus) network.Transport {
+	switch config.Messaging.Transport {
+	case "mqtt":
+		return network.NewMQTT(
+			config.MQTT.URL,
+			config.MQTT.ClientID,
+			config.MQTT.SharedSubscriptions,
+			config.MQTT.PublishTimeout,
+			logrus.Get("MQTT"),
+		)
+	case "memory":
+		return network.NewMemoryBus(logrus.Get("MemoryBus"))
+	}
+
+	return network.NewAmqp(
+		config.RabbitMQ.URL,
+		config.RabbitMQ.ManagementURL,
+		config.RabbitMQ.Prefetch,
+		config.RabbitMQ.PublishTimeout,
+		config.RabbitMQ.PublishChannels,
+		logrus.Get("Amqp"),
+	)
+}
+
+// newHTTPClient creates the client to an upstream service, which has its own circuit breaker
+func newHTTPClient(name string, timeout time.Duration, config config.Config, logrus *logging.Logrus) *network.HTTPClient {
+	breaker := network.NewCircuitBreaker(name, config.Upstream.BreakerThreshold, config.Upstream.BreakerCooldown, logrus.Get("CircuitBreaker"))
+	return network.NewHTTPClient(name, timeout, config.Upstream.Retries, breaker, logrus.Get("HTTPClient"))
+}
+
+// newThingProxy creates the things proxy selected in the configuration. The things service's
+// things are cached as selected in the configuration, while the embedded things aren't.
+func newThingProxy(config config.Config, logrus *logging.Logrus, client *network.HTTPClient, redis *network.Redis) (thingDeliveryHTTP.ThingProxy, error) {
+	if config.Things.Backend == "embedded" {
+		// the owners of the things are taken from the tokens, which are trusted only if verified
+		if config.Things.TokenSecret == "" {
+			return nil, errors.New("the embedded things backend requires things.tokenSecret")
+		}
+		verifier := jwt.NewVerifier(config.Things.TokenSecret)
+		return thingDeliveryHTTP.NewEmbeddedThingProxy(logrus.Get("ThingProxy"), config.Things.Database, verifier)
+	}
+
+	thingProxy := thingDeliveryHTTP.NewThingProxy(logrus.Get("ThingProxy"), client, config.Things.Hostname, config.Things.Protocol, config.Things.Port)
+
+	switch config.Things.Cache {
+	case "redis":
+		return thingDeliveryHTTP.NewCachedThingProxy(logrus.Get("ThingCache"), thingProxy, cache.NewThingCache(redis, config.Things.CacheTTL)), nil
+	case "memory":
+		return thingDeliveryHTTP.NewCachedThingProxy(logrus.Get("ThingCache"), thingProxy, cache.NewMemoryThingCache(config.Things.CacheTTL)), nil
+	}
+
+	return thingProxy, nil
+}
+
+// newSessionStore creates the session store selected in the configuration
+func newSessionStore(config config.Config, redis *network.Redis) cache.SessionStore {
+	if config.Redis.Backend == "memory" {
+		return cache.NewMemorySessionStore(config.Redis.ExpirationTime)
+	}
+
+	return cache.NewSessionStore(redis, config.Redis.ExpirationTime)
+}
+
+// newCommandTracker creates the commands tracker selected in the configuration, and the
+// function that stops it. The commands tracked in Redis are timed out by checking them on
+// every expiration interval.
+func newCommandTracker(config config.Config, logrus *logging.Logrus, publisher thingDeliveryAMQP.Publisher, redis *network.Redis) (thingCommands.Tracker, func()) {
+	if config.Commands.Backend == "memory" {
+		return thingCommands.NewTracker(logrus.Get("CommandTracker"), publisher, config.Commands.Timeout, config.Commands.Retention), func() {}
+	}
+
+	tracker := thingCommands.NewRedisTracker(logrus.Get("CommandTracker"), publisher, redis, config.Commands.Timeout, config.Commands.Retention)
+	go tracker.Start(config.Commands.ExpirationInterval)
+	return tracker, tracker.Stop
+}
+
+// newCommandQueue creates the queue of commands to offline things selected in the
+// configuration, and the function that stops it. The commands queued in Redis are timed out
+// by checking them on every expiration interval.
+func newCommandQueue(config config.Config, logrus *logging.Logrus, publisher thingDeliveryAMQP.Publisher, redis *network.Redis) (thingCommands.Queue, func()) {
+	if config.Commands.Backend == "memory" {
+		return thingCommands.NewQueue(logrus.Get("CommandQueue"), publisher, config.Commands.QueueTTL, config.Commands.QueueSize, config.Commands.QueuePolicy), func() {}
+	}
+
+	queue := thingCommands.NewRedisQueue(logrus.Get("CommandQueue"), publisher, redis, config.Commands.QueueTTL, config.Commands.QueueSize, config.Commands.QueuePolicy)
+	go queue.Start(config.Commands.ExpirationInterval)
+	return queue, queue.Stop
+}
+
+// usesRedis reports whether the session store, the things cache or the commands are stored in Redis
+func usesRedis(config config.Config) bool {
+	return config.Redis.Backend != "memory" ||
+		config.Commands.Backend != "memory" ||
+		(config.Things.Backend != "embedded" && config.Things.Cache == "redis")
+}
+
 func main() {
 	config := config.Load()
 	logrus := logging.NewLogrus(config.Logger.Level, config.Logger.Syslog)
@@ -42,21 +143,27 @@ func main() {
 
 	// Redis
 	redisStartedChan := make(chan bool, 1)
-	redis := network.NewRedis(config.Redis.URL, logrus.Get("Redis"))
-	sessionStore := cache.NewSessionStore(redis, config.ExpirationTime)
+	redis := network.NewRedis(config.Redis.URL, config.Redis.HealthInterval, logrus.Get("Redis"))
+	sessionStore := newSessionStore(config, redis)
 
-	// AMQP
-	amqpStartedChan := make(chan bool, 1)
-	amqp := network.NewAmqp(config.RabbitMQ.URL, logrus.Get("Amqp"))
+	// Messaging transport
+	transportStartedChan := make(chan bool, 1)
+	transport := newTransport(config, logrus)
 
 	// AMQP Publishers
-	clientPublisher := thingDeliveryAMQP.NewMsgClientPublisher(logrus.Get("ClientPublisher"), amqp)
-	commandSender := thingDeliveryAMQP.NewCommandSender(logrus.Get("Command Sender"), amqp)
+	clientPublisher := thingDeliveryAMQP.NewMsgClientPublisher(logrus.Get("ClientPublisher"), transport)
+	commandSender := thingDeliveryAMQP.NewCommandSender(logrus.Get("Command Sender"), transport)
 
 	// Services
-	usersProxy := userDeliveryHTTP.NewUsersProxy(logrus.Get("UsersProxy"), config.Users.Hostname, config.Users.Port)
-	authProxy := userDeliveryHTTP.NewAuthProxy(logrus.Get("AuthProxy"), config.Auth.Hostname, config.Auth.Port)
-	thingProxy := thingDeliveryHTTP.NewThingProxy(logrus.Get("ThingProxy"), config.Things.Hostname, config.Things.Protocol, config.Things.Port)
+	usersClient := newHTTPClient("users", config.Users.Timeout, config, logrus)
+	authClient := newHTTPClient("auth", config.Auth.Timeout, config, logrus)
+	thingsClient := newHTTPClient("things", config.Things.Timeout, config, logrus)
+	usersProxy := userDeliveryHTTP.NewUsersProxy(logrus.Get("UsersProxy"), usersClient, config.Users.Hostname, config.Users.Port)
+	authProxy := userDeliveryHTTP.NewAuthProxy(logrus.Get("AuthProxy"), authClient, config.Auth.Hostname, config.Auth.Port)
+	thingProxy, err := newThingProxy(config, logrus, thingsClient, redis)
+	if err != nil {
+		logger.Fatalf("error creating things proxy: %s", err)
+	}
 
 	// ID generator
 	generator := userInteractors.NewGenerator()
@@ -65,52 +172,98 @@ func main() {
 	createUser := userInteractors.NewCreateUser(logrus.Get("CreateUser"), usersProxy)
 	createToken := userInteractors.NewCreateToken(logrus.Get("CreateToken"), usersProxy, authProxy)
 	createSession := userInteractors.NewCreateSession(thingProxy, generator, sessionStore)
+	listSessions := userInteractors.NewListSessions(thingProxy, sessionStore)
+	revokeSession := userInteractors.NewRevokeSession(thingProxy, sessionStore, clientPublisher)
+	keepAliveSession := userInteractors.NewKeepAliveSession(thingProxy, sessionStore)
+	updateSession := userInteractors.NewUpdateSession(thingProxy, sessionStore)
+	expireSessions := userInteractors.NewExpireSessions(logrus.Get("ExpireSessions"), sessionStore, clientPublisher)
 
-	thingInteractor := thingInteractors.NewThingInteractor(logrus.Get("ThingInteractor"), clientPublisher, thingProxy, sessionStore)
+	commandTracker, stopCommandTracker := newCommandTracker(config, logrus, clientPublisher, redis)
+	commandQueue, stopCommandQueue := newCommandQueue(config, logrus, clientPublisher, redis)
+	thingInteractor := thingInteractors.NewThingInteractor(logrus.Get("ThingInteractor"), clientPublisher, thingProxy, sessionStore, commandTracker, commandQueue, config.Redis.UnavailablePolicy, config.Things.RegistrationPolicy)
 
 	// Controllers
 	thingController := thingControllers.NewThingController(logrus.Get("ThingController"), thingInteractor, commandSender, clientPublisher)
-	userController := userControllers.NewUserController(logrus.Get("UserController"), createUser, createToken, createSession)
+	userController := userControllers.NewUserController(logrus.Get("UserController"), createUser, createToken, createSession, listSessions, revokeSession, keepAliveSession, updateSession)
 
 	// Server
 	serverStartedChan := make(chan bool, 1)
-	http := server.NewServer(config.Server.Port, logrus.Get("Server"), userController)
+	upstreams := []server.Upstream{usersClient, authClient}
+	if usesRedis(config) {
+		upstreams = append(upstreams, redis)
+	}
+	if config.Things.Backend != "embedded" {
+		upstreams = append(upstreams, thingsClient)
+	}
+	http := server.NewServer(config.Server.Port, logrus.Get("Server"), userController, thingController, upstreams)
 
 	// AMQP Handler
 	msgStartedChan := make(chan bool, 1)
-	msgHandler := server.NewMsgHandler(logrus.Get("MsgHandler"), amqp, thingController)
+	msgHandler := server.NewMsgHandler(
+		logrus.Get("MsgHandler"),
+		transport,
+		thingController,
+		config.Messaging.Workers,
+		config.Messaging.QueueSize,
+		config.RabbitMQ.MaxRetries,
+		config.Messaging.StatsInterval,
+		config.Messaging.HandleTimeout,
+	)
 
 	// Start goroutines
-	go amqp.Start(amqpStartedChan)
+	go transport.Start(transportStartedChan)
 	go http.Start(serverStartedChan)
-	go redis.Start(redisStartedChan)
+	if usesRedis(config) {
+		go redis.Start(redisStartedChan)
+	}
+	go expireSessions.Start(config.Redis.ExpirationInterval)
 
 	// Main loop
+	msgHandlerStarted := false
 	for {
 		select {
 		case started := <-serverStartedChan:
 			if started {
 				logger.Info("server started")
 			}
-		case started := <-amqpStartedChan:
-			if started {
-				logger.Info("AMQP connection started")
+		case started := <-transportStartedChan:
+			if !started {
+				logger.Info("messaging connection closed")
+				continue
+			}
+
+			logger.Info("messaging connection started")
+			// subscriptions are restored by the transport when it reconnects
+			if !msgHandlerStarted {
+				msgHandlerStarted = true
 				go msgHandler.Start(msgStartedChan)
 			}
 		case started := <-msgStartedChan:
 			if started {
 				logger.Info("message handler started")
-			} else {
-				quit <- true
+				continue
+			}
+
+			// the quit channel is only read by this loop, so it must not block when a
+			// signal was already received
+			select {
+			case quit <- true:
+			default:
 			}
 		case started := <-redisStartedChan:
 			if started {
 				logger.Info("redis connection started")
+			} else {
+				logger.Warn("redis unavailable")
 			}
 		case <-quit:
+			expireSessions.Stop()
+			stopCommandTracker()
+			stopCommandQueue()
 			msgHandler.Stop()
-			amqp.Stop()
+			transport.Stop()
 			http.Stop()
+			redis.Stop()
 			os.Exit(0)
 		}
 	}
@@ -55,7 +55,7 @@ var doc = `{
                 "summary": "Generate a user's session ID",
                 "parameters": [
                     {
-                        "description": "User or application token",
+                        "description": "User or application token and the optional filter of the session's data",
                         "name": "user",
                         "in": "body",
                         "required": true,
@@ -71,6 +71,47 @@ var doc = `{
                             "$ref": "#/definitions/network.CreateSessionResponse"
                         }
                     },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the user's session IDs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User or application token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session IDs",
+                        "schema": {
+                            "$ref": "#/definitions/network.ListSessionsResponse"
+                        }
+                    },
                     "403": {
                         "description": "Invalid credentials",
                         "schema": {
@@ -86,6 +127,237 @@ var doc = `{
                 }
             }
         },
+        "/sessions/{id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revoke a user's session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User or application token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Session revoked"
+                    },
+                    "403": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Replace the filter of a user's session data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User or application token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Filter of the session's data",
+                        "name": "session",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/network.UpdateSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Session updated"
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}/keepalive": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Extend the expiration of a user's session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User or application token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Session expiration extended"
+                    },
+                    "403": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/things/{id}": {
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update a thing's name and metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User or application token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Thing's ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Thing's name and metadata",
+                        "name": "thing",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UpdateThingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated thing",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Thing"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid name or metadata",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Thing not found",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid request format",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.DetailedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/tokens": {
             "post": {
                 "consumes": [
@@ -194,6 +466,145 @@ var doc = `{
                 }
             }
         },
+        "controllers.UpdateThingRequest": {
+            "type": "object",
+            "properties": {
+                "metadata": {
+                    "$ref": "#/definitions/entities.Metadata"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Config": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "$ref": "#/definitions/entities.Event"
+                },
+                "schema": {
+                    "$ref": "#/definitions/entities.Schema"
+                },
+                "sensorId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Event": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "boolean"
+                },
+                "lowerThreshold": {
+                    "type": "object"
+                },
+                "timeSec": {
+                    "type": "integer"
+                },
+                "upperThreshold": {
+                    "type": "object"
+                }
+            }
+        },
+        "entities.Location": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "entities.Metadata": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "firmwareVersion": {
+                    "type": "string"
+                },
+                "hardwareVersion": {
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/entities.Location"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "entities.Schema": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "typeId": {
+                    "type": "integer"
+                },
+                "unit": {
+                    "type": "integer"
+                },
+                "valueType": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.SessionFilter": {
+            "type": "object",
+            "properties": {
+                "sensorIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "thingIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "entities.Thing": {
+            "type": "object",
+            "properties": {
+                "config": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Config"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "$ref": "#/definitions/entities.Metadata"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
         "entities.User": {
             "type": "object",
             "properties": {
@@ -211,6 +622,9 @@ var doc = `{
         "network.CreateSessionRequest": {
             "type": "object",
             "properties": {
+                "filter": {
+                    "$ref": "#/definitions/entities.SessionFilter"
+                },
                 "token": {
                     "type": "string"
                 }
@@ -224,11 +638,36 @@ var doc = `{
                 }
             }
         },
+        "network.ListSessionsResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "network.UpdateSessionRequest": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "$ref": "#/definitions/entities.SessionFilter"
+                }
+            }
+        },
         "server.Health": {
             "type": "object",
             "properties": {
                 "status": {
                     "type": "string"
+                },
+                "upstreams": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 }
             }
         }
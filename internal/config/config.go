@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 
@@ -24,30 +25,89 @@ type Logger struct {
 type Users struct {
 	Hostname string
 	Port     uint16
+	Timeout  time.Duration
 }
 
 // Auth represents the auth service to proxy request
 type Auth struct {
 	Hostname string
 	Port     uint16
+	Timeout  time.Duration
 }
 
 // RabbitMQ represents the rabbitmq configuration properties
 type RabbitMQ struct {
-	URL string
+	URL             string
+	ManagementURL   string
+	Prefetch        int
+	MaxRetries      int
+	PublishTimeout  time.Duration
+	PublishChannels int
 }
 
-// Things represents the things service to proxy request
+// MQTT represents the MQTT broker configuration properties
+type MQTT struct {
+	URL                 string
+	ClientID            string
+	SharedSubscriptions bool
+	PublishTimeout      time.Duration
+}
+
+// Messaging represents the message handling configuration properties. The transport
+// can be `amqp`, `mqtt` or `memory`.
+type Messaging struct {
+	Transport     string
+	Workers       int
+	QueueSize     int
+	StatsInterval time.Duration
+	HandleTimeout time.Duration
+}
+
+// Commands represents the configuration of the commands sent to the things. Pending
+// commands time out after Timeout and finished commands are kept for Retention. The
+// backend can be `redis` or `memory`.
+type Commands struct {
+	Backend            string
+	ExpirationInterval time.Duration
+	Timeout            time.Duration
+	Retention          time.Duration
+	QueueTTL           time.Duration
+	QueueSize          int
+	QueuePolicy        string
+}
+
+// Upstream represents the resilience properties of the requests to the upstream services
+type Upstream struct {
+	Retries          int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Things represents the things service to proxy request. The backend can be `mainflux`,
+// which uses the things service, or `embedded`, which stores the things in Database. The
+// registration policy can be `fail` or `existing`.
 type Things struct {
-	Protocol string
-	Hostname string
-	Port     uint16
+	Backend            string
+	Database           string
+	TokenSecret        string
+	RegistrationPolicy string
+	Protocol           string
+	Hostname           string
+	Port               uint16
+	Timeout            time.Duration
+	Cache              string
+	CacheTTL           time.Duration
 }
 
-// Redis represents the redis configuration properties
+// Redis represents the redis configuration properties. The backend can be `redis` or `memory`
+// and the unavailable policy can be `fail` or `skip`.
 type Redis struct {
-	URL            string
-	ExpirationTime string
+	URL                string
+	Backend            string
+	ExpirationTime     string
+	ExpirationInterval time.Duration
+	HealthInterval     time.Duration
+	UnavailablePolicy  string
 }
 
 // Config represents the service configuration
@@ -57,7 +117,11 @@ type Config struct {
 	Users
 	Auth
 	RabbitMQ
+	MQTT
+	Messaging
+	Commands
 	Things
+	Upstream
 	Redis
 }
 
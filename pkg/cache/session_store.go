@@ -1,17 +1,54 @@
 package cache
 
 import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+)
+
+const (
+	// sessionKeyPrefix prefixes the keys that map a session ID to its owner's email
+	sessionKeyPrefix = "session:"
+	// userSessionsKeyPrefix prefixes the keys of the sets indexing the sessions of each user
+	userSessionsKeyPrefix = "sessions:"
+	// sessionsExpirationKey is the sorted set of the session IDs scored by their expiration
+	sessionsExpirationKey = "sessions-expiration"
+	// sessionFilterKeyPrefix prefixes the keys that map a session ID to its data filter
+	sessionFilterKeyPrefix = "session-filter:"
+	// sessionKeysTTLFactor multiplies the expiration time to obtain the TTL of the session's
+	// keys. They outlive the session, so it can still be refreshed when it expires, and are
+	// removed by Redis if the session is never removed, e.g. when no instance is running.
+	sessionKeysTTLFactor = 2
 )
 
 // SessionStore abstracts the operations for storing session related data.
-// It provides basic `Get/Save` methods without any explicit dependency with
-// the underlying database technology.
+// It provides basic `Get/List/Save/Delete` methods without any explicit dependency
+// with the underlying database technology. The sessions are keyed by their IDs and
+// indexed by the owner's email, so a user can have several sessions at once.
+//
+// The sessions have a sliding expiration, which is extended by `Refresh`. They aren't
+// removed when they expire, but listed by `Expired` to be removed with `Delete`. An
+// expired session is claimed with `Claim` before, so only one of the instances looking
+// for the expired sessions handles it, and given back with `Release` if it isn't removed.
+//
+// Each session has a filter that selects the data published to it, which is replaced by
+// `SetFilter`. The sessions without a filter receive all the data.
 type SessionStore interface {
-	Get(email string) (string, error)
-	Save(email string, id string) error
+	Get(ctx context.Context, id string) (string, error)
+	List(ctx context.Context, email string) ([]string, error)
+	Save(ctx context.Context, email string, id string) error
+	GetFilter(ctx context.Context, id string) (entities.SessionFilter, error)
+	SetFilter(ctx context.Context, id string, filter entities.SessionFilter) error
+	Refresh(ctx context.Context, id string) error
+	Expired(ctx context.Context) ([]entities.Session, error)
+	Claim(ctx context.Context, id string) (bool, error)
+	Release(ctx context.Context, id string) error
+	Delete(ctx context.Context, email string, id string) error
 }
 
 type sessionStore struct {
@@ -21,32 +58,439 @@ type sessionStore struct {
 
 // NewSessionStore creates a new sessionStore instance that implements SessionStore interface.
 // It takes a Redis instance as dependency, which is the database abstracted by SessionStore.
+// The session's keys have a TTL of twice the expiration time, which is also extended by
+// `Refresh`, so the sessions never removed are eventually dropped by Redis.
 func NewSessionStore(redis *network.Redis, expirationTime string) SessionStore {
 	return &sessionStore{redis, expirationTime}
 }
 
-// Get retrieves a session from the database based on the user email.
-func (ss *sessionStore) Get(email string) (string, error) {
-	session, err := ss.redis.Get(email)
+// Get retrieves the e-mail of the user who owns the session. An empty string is returned
+// when the session doesn't exist.
+func (ss *sessionStore) Get(ctx context.Context, id string) (string, error) {
+	email, err := ss.redis.Get(ctx, sessionKeyPrefix+id)
 	if err != nil {
 		return "", err
 	}
 
-	return session, nil
+	return email, nil
+}
+
+// List retrieves the IDs of the user's sessions. The sessions that no longer exist are
+// removed from the user's index.
+func (ss *sessionStore) List(ctx context.Context, email string) ([]string, error) {
+	ids, err := ss.redis.SMembers(ctx, userSessionsKeyPrefix+email)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []string{}
+	removed := []interface{}{}
+	for _, id := range ids {
+		owner, err := ss.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if owner == "" {
+			removed = append(removed, id)
+			continue
+		}
+		sessions = append(sessions, id)
+	}
+
+	if len(removed) > 0 {
+		err = ss.redis.SRem(ctx, userSessionsKeyPrefix+email, removed...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
 }
 
 // Save stores a new session to the database, which is represented by a user email and the
-// respective session ID.
-func (ss *sessionStore) Save(email, id string) error {
+// respective session ID, and adds it to the user's index.
+func (ss *sessionStore) Save(ctx context.Context, email, id string) error {
+	expiresAt, err := ss.expiresAt()
+	if err != nil {
+		return err
+	}
+
+	ttl, err := ss.keysTTL()
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.Set(ctx, sessionKeyPrefix+id, email, ttl)
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.SAdd(ctx, userSessionsKeyPrefix+email, id)
+	if err != nil {
+		return err
+	}
+
+	return ss.redis.ZAdd(ctx, sessionsExpirationKey, expiresAt, id)
+}
+
+// GetFilter retrieves the filter of the session's data. An empty filter is returned when
+// the session has no filter.
+func (ss *sessionStore) GetFilter(ctx context.Context, id string) (entities.SessionFilter, error) {
+	filter := entities.SessionFilter{}
+	value, err := ss.redis.Get(ctx, sessionFilterKeyPrefix+id)
+	if err != nil || value == "" {
+		return filter, err
+	}
+
+	err = json.Unmarshal([]byte(value), &filter)
+	return filter, err
+}
+
+// SetFilter replaces the filter of the session's data.
+func (ss *sessionStore) SetFilter(ctx context.Context, id string, filter entities.SessionFilter) error {
+	value, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := ss.keysTTL()
+	if err != nil {
+		return err
+	}
+
+	return ss.redis.Set(ctx, sessionFilterKeyPrefix+id, string(value), ttl)
+}
+
+// Refresh extends the session expiration by the expiration time from now on, along with
+// the TTL of its keys. Sessions that don't exist aren't refreshed.
+func (ss *sessionStore) Refresh(ctx context.Context, id string) error {
+	expiresAt, err := ss.expiresAt()
+	if err != nil {
+		return err
+	}
+
+	ttl, err := ss.keysTTL()
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.ZAddXX(ctx, sessionsExpirationKey, expiresAt, id)
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.Expire(ctx, sessionKeyPrefix+id, ttl)
+	if err != nil {
+		return err
+	}
+
+	return ss.redis.Expire(ctx, sessionFilterKeyPrefix+id, ttl)
+}
+
+// Expired retrieves the sessions whose expiration has passed.
+func (ss *sessionStore) Expired(ctx context.Context) ([]entities.Session, error) {
+	ids, err := ss.redis.ZRangeByScore(ctx, sessionsExpirationKey, float64(time.Now().Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []entities.Session{}
+	for _, id := range ids {
+		email, err := ss.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, entities.Session{ID: id, Email: email})
+	}
+
+	return sessions, nil
+}
+
+// Claim extends the expiration of an expired session by the expiration time, reporting if
+// it was still expired. The expiration is checked and extended atomically, so a session is
+// claimed by a single instance. A session whose claimer stops is expired again afterwards.
+func (ss *sessionStore) Claim(ctx context.Context, id string) (bool, error) {
+	expiresAt, err := ss.expiresAt()
+	if err != nil {
+		return false, err
+	}
+
+	return ss.redis.ZAddIfMax(ctx, sessionsExpirationKey, float64(time.Now().Unix()), expiresAt, id)
+}
+
+// Release expires a claimed session again, so it is listed by Expired and retried.
+func (ss *sessionStore) Release(ctx context.Context, id string) error {
+	return ss.redis.ZAddXX(ctx, sessionsExpirationKey, float64(time.Now().Unix()), id)
+}
+
+// Delete removes a session and its filter from the database and from the user's index.
+func (ss *sessionStore) Delete(ctx context.Context, email, id string) error {
+	err := ss.redis.Del(ctx, sessionKeyPrefix+id)
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.Del(ctx, sessionFilterKeyPrefix+id)
+	if err != nil {
+		return err
+	}
+
+	err = ss.redis.SRem(ctx, userSessionsKeyPrefix+email, id)
+	if err != nil {
+		return err
+	}
+
+	return ss.redis.ZRem(ctx, sessionsExpirationKey, id)
+}
+
+// expiresAt returns the Unix time a session saved or refreshed now expires
+func (ss *sessionStore) expiresAt() (float64, error) {
+	duration, err := time.ParseDuration(ss.expirationTime)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(time.Now().Add(duration).Unix()), nil
+}
+
+// keysTTL returns the TTL of the keys of a session saved or refreshed now
+func (ss *sessionStore) keysTTL() (time.Duration, error) {
 	duration, err := time.ParseDuration(ss.expirationTime)
+	if err != nil {
+		return 0, err
+	}
+
+	return sessionKeysTTLFactor * duration, nil
+}
+
+type memorySession struct {
+	email     string
+	expiresAt time.Time
+	// droppedAt is when the session is dropped if it's never removed, like the Redis keys
+	droppedAt time.Time
+	filter    entities.SessionFilter
+}
+
+type memorySessionStore struct {
+	expirationTime string
+	mutex          sync.Mutex
+	sessions       map[string]memorySession
+	users          map[string]map[string]bool
+}
+
+// NewMemorySessionStore creates a new memorySessionStore instance that implements SessionStore
+// interface by storing the sessions in memory. It is suited for development, tests and single
+// node deployments. The expired sessions aren't returned by Get and List, and are kept to be
+// listed by Expired until they are removed. The ones never removed are dropped when accessed
+// after twice the expiration time, like the Redis keys.
+func NewMemorySessionStore(expirationTime string) SessionStore {
+	return &memorySessionStore{
+		expirationTime: expirationTime,
+		sessions:       map[string]memorySession{},
+		users:          map[string]map[string]bool{},
+	}
+}
+
+// Get retrieves the e-mail of the user who owns the session. An empty string is returned
+// when the session doesn't exist or is expired.
+func (mss *memorySessionStore) Get(_ context.Context, id string) (string, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	session, ok := mss.get(id, time.Now())
+	if !ok {
+		return "", nil
+	}
+
+	return session.email, nil
+}
+
+// List retrieves the IDs of the user's sessions that aren't expired, sorted in ascending
+// order.
+func (mss *memorySessionStore) List(_ context.Context, email string) ([]string, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	sessions := []string{}
+	for id := range mss.users[email] {
+		if _, ok := mss.get(id, now); ok {
+			sessions = append(sessions, id)
+		}
+	}
+	sort.Strings(sessions)
+
+	return sessions, nil
+}
+
+// Save stores a new session in memory, which is represented by a user email and the
+// respective session ID, and adds it to the user's index.
+func (mss *memorySessionStore) Save(_ context.Context, email, id string) error {
+	duration, err := time.ParseDuration(mss.expirationTime)
 	if err != nil {
 		return err
 	}
 
-	err = ss.redis.Set(email, id, duration)
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	mss.sessions[id] = memorySession{
+		email:     email,
+		expiresAt: now.Add(duration),
+		droppedAt: now.Add(sessionKeysTTLFactor * duration),
+	}
+	if _, ok := mss.users[email]; !ok {
+		mss.users[email] = map[string]bool{}
+	}
+	mss.users[email][id] = true
+	return nil
+}
+
+// GetFilter retrieves the filter of the session's data. An empty filter is returned when
+// the session has no filter.
+func (mss *memorySessionStore) GetFilter(_ context.Context, id string) (entities.SessionFilter, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	return mss.sessions[id].filter, nil
+}
+
+// SetFilter replaces the filter of the session's data. Sessions that don't exist aren't
+// filtered.
+func (mss *memorySessionStore) SetFilter(_ context.Context, id string, filter entities.SessionFilter) error {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	session, ok := mss.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	session.filter = filter
+	mss.sessions[id] = session
+	return nil
+}
+
+// Refresh extends the session expiration by the expiration time from now on. Sessions that
+// don't exist aren't refreshed.
+func (mss *memorySessionStore) Refresh(_ context.Context, id string) error {
+	duration, err := time.ParseDuration(mss.expirationTime)
 	if err != nil {
 		return err
 	}
 
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	session, ok := mss.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	session.expiresAt = now.Add(duration)
+	session.droppedAt = now.Add(sessionKeysTTLFactor * duration)
+	mss.sessions[id] = session
+	return nil
+}
+
+// Expired retrieves the sessions whose expiration has passed, ordered by their expiration.
+func (mss *memorySessionStore) Expired(_ context.Context) ([]entities.Session, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	ids := []string{}
+	for id, session := range mss.sessions {
+		if now.After(session.droppedAt) {
+			mss.delete(session.email, id)
+			continue
+		}
+		if now.After(session.expiresAt) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return mss.sessions[ids[i]].expiresAt.Before(mss.sessions[ids[j]].expiresAt)
+	})
+
+	sessions := []entities.Session{}
+	for _, id := range ids {
+		sessions = append(sessions, entities.Session{ID: id, Email: mss.sessions[id].email})
+	}
+
+	return sessions, nil
+}
+
+// Claim extends the expiration of an expired session by the expiration time, reporting if
+// it was still expired.
+func (mss *memorySessionStore) Claim(_ context.Context, id string) (bool, error) {
+	duration, err := time.ParseDuration(mss.expirationTime)
+	if err != nil {
+		return false, err
+	}
+
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	session, ok := mss.sessions[id]
+	if !ok || !now.After(session.expiresAt) {
+		return false, nil
+	}
+
+	session.expiresAt = now.Add(duration)
+	mss.sessions[id] = session
+	return true, nil
+}
+
+// Release expires a claimed session again, so it is listed by Expired and retried.
+func (mss *memorySessionStore) Release(_ context.Context, id string) error {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	session, ok := mss.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	session.expiresAt = time.Now()
+	mss.sessions[id] = session
 	return nil
 }
+
+// Delete removes a session and its filter from memory and from the user's index.
+func (mss *memorySessionStore) Delete(_ context.Context, email, id string) error {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	mss.delete(email, id)
+	return nil
+}
+
+// get returns the session unless it's expired, dropping it when it was never removed. It
+// must be called with the mutex locked.
+func (mss *memorySessionStore) get(id string, now time.Time) (memorySession, bool) {
+	session, ok := mss.sessions[id]
+	if !ok {
+		return session, false
+	}
+	if now.After(session.droppedAt) {
+		mss.delete(session.email, id)
+		return session, false
+	}
+
+	return session, !now.After(session.expiresAt)
+}
+
+// delete removes the session from memory and from the user's index. It must be called with
+// the mutex locked.
+func (mss *memorySessionStore) delete(email, id string) {
+	delete(mss.sessions, id)
+	delete(mss.users[email], id)
+	if len(mss.users[email]) == 0 {
+		delete(mss.users, email)
+	}
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type memorySessionStoreTestCase struct {
+	name             string
+	expirationTime   string
+	act              func(s SessionStore)
+	expectedSessions []string
+	expectedExpired  []entities.Session
+}
+
+var memorySessionStoreCases = []memorySessionStoreTestCase{
+	{
+		"sessions saved",
+		"1m",
+		func(_ SessionStore) {},
+		[]string{"first-session", "second-session"},
+		[]entities.Session{},
+	},
+	{
+		"sessions expired",
+		"50ms",
+		func(_ SessionStore) { time.Sleep(60 * time.Millisecond) },
+		[]string{},
+		[]entities.Session{
+			{ID: "first-session", Email: "user1@cesar.org.br"},
+			{ID: "second-session", Email: "user1@cesar.org.br"},
+		},
+	},
+	{
+		"session refreshed",
+		"100ms",
+		func(s SessionStore) {
+			time.Sleep(60 * time.Millisecond)
+			_ = s.Refresh(context.Background(), "second-session")
+			time.Sleep(60 * time.Millisecond)
+		},
+		[]string{"second-session"},
+		[]entities.Session{{ID: "first-session", Email: "user1@cesar.org.br"}},
+	},
+	{
+		"session deleted",
+		"1m",
+		func(s SessionStore) { _ = s.Delete(context.Background(), "user1@cesar.org.br", "first-session") },
+		[]string{"second-session"},
+		[]entities.Session{},
+	},
+	{
+		"session that doesn't exist isn't refreshed",
+		"1m",
+		func(s SessionStore) { _ = s.Refresh(context.Background(), "unknown-session") },
+		[]string{"first-session", "second-session"},
+		[]entities.Session{},
+	},
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	for _, tc := range memorySessionStoreCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			sessionStore := NewMemorySessionStore(tc.expirationTime)
+			assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "first-session"))
+			assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "second-session"))
+			assert.NoError(t, sessionStore.Save(ctx, "user2@cesar.org.br", "another-user-session"))
+
+			tc.act(sessionStore)
+
+			sessions, err := sessionStore.List(ctx, "user1@cesar.org.br")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSessions, sessions)
+
+			for _, id := range tc.expectedSessions {
+				email, err := sessionStore.Get(ctx, id)
+				assert.NoError(t, err)
+				assert.Equal(t, "user1@cesar.org.br", email)
+			}
+
+			expired, err := sessionStore.Expired(ctx)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedExpired, withoutUser(expired, "user2@cesar.org.br"))
+		})
+	}
+}
+
+func TestMemorySessionStoreClaim(t *testing.T) {
+	ctx := context.Background()
+	sessionStore := NewMemorySessionStore("50ms")
+	assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "session-id"))
+
+	claimed, err := sessionStore.Claim(ctx, "session-id")
+	assert.NoError(t, err)
+	assert.False(t, claimed, "session claimed before expiring")
+
+	time.Sleep(60 * time.Millisecond)
+	claimed, err = sessionStore.Claim(ctx, "session-id")
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = sessionStore.Claim(ctx, "session-id")
+	assert.NoError(t, err)
+	assert.False(t, claimed, "session claimed twice")
+
+	// the released session is expired again
+	assert.NoError(t, sessionStore.Release(ctx, "session-id"))
+	time.Sleep(time.Millisecond)
+	expired, err := sessionStore.Expired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Session{{ID: "session-id", Email: "user1@cesar.org.br"}}, expired)
+}
+
+func TestMemorySessionStoreDropsSessionsNeverRemoved(t *testing.T) {
+	ctx := context.Background()
+	sessionStore := NewMemorySessionStore("10ms")
+	assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "first-session"))
+	assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "second-session"))
+
+	// the sessions are dropped after twice the expiration time, whether accessed or listed
+	time.Sleep(30 * time.Millisecond)
+	email, err := sessionStore.Get(ctx, "first-session")
+	assert.NoError(t, err)
+	assert.Empty(t, email)
+	expired, err := sessionStore.Expired(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	mss := sessionStore.(*memorySessionStore)
+	assert.Empty(t, mss.sessions)
+	assert.Empty(t, mss.users)
+}
+
+func TestMemorySessionStoreInvalidExpirationTime(t *testing.T) {
+	sessionStore := NewMemorySessionStore("invalid")
+	assert.Error(t, sessionStore.Save(context.Background(), "user1@cesar.org.br", "session-id"))
+
+	email, err := sessionStore.Get(context.Background(), "session-id")
+	assert.NoError(t, err)
+	assert.Empty(t, email)
+}
+
+func TestMemorySessionStoreFilter(t *testing.T) {
+	ctx := context.Background()
+	filter := entities.SessionFilter{ThingIDs: []string{"fc3fcf912d0c290a"}, SensorIDs: []int{0}, Tags: []string{"kitchen"}}
+	sessionStore := NewMemorySessionStore("1m")
+	assert.NoError(t, sessionStore.Save(ctx, "user1@cesar.org.br", "session-id"))
+	assert.NoError(t, sessionStore.SetFilter(ctx, "session-id", filter))
+	assert.NoError(t, sessionStore.SetFilter(ctx, "unknown-session", filter))
+
+	saved, err := sessionStore.GetFilter(ctx, "session-id")
+	assert.NoError(t, err)
+	assert.Equal(t, filter, saved)
+
+	unknown, err := sessionStore.GetFilter(ctx, "unknown-session")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.SessionFilter{}, unknown)
+
+	assert.NoError(t, sessionStore.Delete(ctx, "user1@cesar.org.br", "session-id"))
+	deleted, err := sessionStore.GetFilter(ctx, "session-id")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.SessionFilter{}, deleted)
+}
+
+func withoutUser(sessions []entities.Session, email string) []entities.Session {
+	filtered := []entities.Session{}
+	for _, s := range sessions {
+		if s.Email != email {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// ThingCache abstracts the operations for caching the things' metadata obtained from the
+// things service. The things are indexed by their KNoT ID and scoped by the authorization
+// token used to obtain them, so a user never receives a thing cached for another user.
+type ThingCache interface {
+	// Get returns the cached thing, or nil if it isn't cached or has expired
+	Get(ctx context.Context, authorization, id string) (*entities.Thing, error)
+
+	// Version returns the thing's version, which changes every time the thing is deleted
+	Version(ctx context.Context, id string) (int64, error)
+
+	// Save caches the thing obtained with the authorization token, unless it was deleted
+	// after version was read, so a thing fetched before being changed isn't cached
+	Save(ctx context.Context, authorization string, thing *entities.Thing, version int64) error
+
+	// Delete removes the thing cached for every authorization token
+	Delete(ctx context.Context, id string) error
+}
+
+type cachedThing struct {
+	Thing     *entities.Thing `json:"thing"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+type thingCache struct {
+	redis *network.Redis
+	ttl   time.Duration
+}
+
+// NewThingCache creates a new thingCache instance that implements ThingCache interface by
+// storing the things in Redis. Each thing is stored in a hash whose fields are the tokens
+// that obtained it, so removing the hash invalidates the thing for every user. The thing's
+// version is a counter shared by every instance, which is incremented when it's removed.
+func NewThingCache(redis *network.Redis, ttl time.Duration) ThingCache {
+	return &thingCache{redis, ttl}
+}
+
+// Get retrieves a thing from the database based on the authorization token and its KNoT ID.
+func (tc *thingCache) Get(ctx context.Context, authorization, id string) (*entities.Thing, error) {
+	value, err := tc.redis.HGet(ctx, thingKey(id), scope(authorization))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	cached := &cachedThing{}
+	err = json.Unmarshal([]byte(value), cached)
+	if err != nil {
+		return nil, err
+	}
+
+	// the hash expiration is renewed by every token, so each token's entry expires by itself
+	if time.Now().After(cached.ExpiresAt) {
+		return nil, nil
+	}
+
+	return cached.Thing, nil
+}
+
+// Version retrieves the thing's version from the database, which is 0 when it was never removed.
+func (tc *thingCache) Version(ctx context.Context, id string) (int64, error) {
+	value, err := tc.redis.Get(ctx, versionKey(id))
+	if err != nil || value == "" {
+		return 0, err
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Save stores a thing to the database, which is scoped by the authorization token. The
+// version is checked by the database, so a thing removed by another instance isn't stored.
+func (tc *thingCache) Save(ctx context.Context, authorization string, thing *entities.Thing, version int64) error {
+	value, err := json.Marshal(cachedThing{thing, time.Now().Add(tc.ttl)})
+	if err != nil {
+		return err
+	}
+
+	return tc.redis.HSetIfEqual(ctx, thingKey(thing.ID), scope(authorization), value, tc.ttl, versionKey(thing.ID), strconv.FormatInt(version, 10))
+}
+
+// Delete removes a thing from the database for every authorization token. The version is
+// incremented first, so a thing being saved concurrently is either removed or not stored.
+// It expires with the cached thing, since only the fetches started before it are checked.
+func (tc *thingCache) Delete(ctx context.Context, id string) error {
+	err := tc.redis.Incr(ctx, versionKey(id), tc.ttl)
+	if err != nil {
+		return err
+	}
+
+	return tc.redis.Del(ctx, thingKey(id))
+}
+
+type memoryVersion struct {
+	value     int64
+	expiresAt time.Time
+}
+
+type memoryThingCache struct {
+	ttl      time.Duration
+	mutex    sync.Mutex
+	things   map[string]map[string]cachedThing
+	versions map[string]memoryVersion
+	sweptAt  time.Time
+}
+
+// NewMemoryThingCache creates a new memoryThingCache instance that implements ThingCache
+// interface by storing the things in memory. It is suited for single node deployments.
+// The expired things and versions are swept at most once per TTL, like the Redis keys
+// expire, so the things that are no longer requested don't pile up.
+func NewMemoryThingCache(ttl time.Duration) ThingCache {
+	return &memoryThingCache{
+		ttl:      ttl,
+		things:   map[string]map[string]cachedThing{},
+		versions: map[string]memoryVersion{},
+		sweptAt:  time.Now(),
+	}
+}
+
+// Get retrieves a thing from memory based on the authorization token and its KNoT ID.
+func (mtc *memoryThingCache) Get(_ context.Context, authorization, id string) (*entities.Thing, error) {
+	mtc.mutex.Lock()
+	defer mtc.mutex.Unlock()
+
+	cached, ok := mtc.things[id][scope(authorization)]
+	if !ok || time.Now().After(cached.ExpiresAt) {
+		return nil, nil
+	}
+
+	thing := *cached.Thing
+	return &thing, nil
+}
+
+// Version retrieves the thing's version from memory.
+func (mtc *memoryThingCache) Version(_ context.Context, id string) (int64, error) {
+	mtc.mutex.Lock()
+	defer mtc.mutex.Unlock()
+
+	return mtc.version(id, time.Now()), nil
+}
+
+// Save stores a thing in memory, which is scoped by the authorization token. The expired
+// entries of the thing are dropped, so tokens that are no longer used don't pile up.
+func (mtc *memoryThingCache) Save(_ context.Context, authorization string, thing *entities.Thing, version int64) error {
+	mtc.mutex.Lock()
+	defer mtc.mutex.Unlock()
+
+	now := time.Now()
+	mtc.sweep(now)
+	if mtc.version(thing.ID, now) != version {
+		return nil
+	}
+
+	entries, ok := mtc.things[thing.ID]
+	if !ok {
+		entries = map[string]cachedThing{}
+		mtc.things[thing.ID] = entries
+	}
+	for s, cached := range entries {
+		if now.After(cached.ExpiresAt) {
+			delete(entries, s)
+		}
+	}
+
+	cached := *thing
+	entries[scope(authorization)] = cachedThing{&cached, now.Add(mtc.ttl)}
+	return nil
+}
+
+// Delete removes a thing from memory for every authorization token.
+func (mtc *memoryThingCache) Delete(_ context.Context, id string) error {
+	mtc.mutex.Lock()
+	defer mtc.mutex.Unlock()
+
+	now := time.Now()
+	mtc.sweep(now)
+	delete(mtc.things, id)
+	mtc.versions[id] = memoryVersion{mtc.version(id, now) + 1, now.Add(mtc.ttl)}
+	return nil
+}
+
+// version returns the thing's version, which is 0 once it expires like the Redis counter
+func (mtc *memoryThingCache) version(id string, now time.Time) int64 {
+	version, ok := mtc.versions[id]
+	if !ok || now.After(version.expiresAt) {
+		return 0
+	}
+
+	return version.value
+}
+
+// sweep drops the expired things and versions, which is done once per TTL at most
+func (mtc *memoryThingCache) sweep(now time.Time) {
+	if now.Before(mtc.sweptAt.Add(mtc.ttl)) {
+		return
+	}
+	mtc.sweptAt = now
+
+	for id, entries := range mtc.things {
+		for s, cached := range entries {
+			if now.After(cached.ExpiresAt) {
+				delete(entries, s)
+			}
+		}
+		if len(entries) == 0 {
+			delete(mtc.things, id)
+		}
+	}
+	for id, version := range mtc.versions {
+		if now.After(version.expiresAt) {
+			delete(mtc.versions, id)
+		}
+	}
+}
+
+func thingKey(id string) string {
+	return "thing:" + id
+}
+
+func versionKey(id string) string {
+	return "thing-version:" + id
+}
+
+// scope hashes the authorization token, so the tokens aren't stored in the cache
+func scope(authorization string) string {
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])
+}
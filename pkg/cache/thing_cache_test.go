@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+var cachedThingExample = &entities.Thing{ID: "fc3fcf912d0c290a", Token: "thing-token", Name: "thing"}
+
+type memoryThingCacheTestCase struct {
+	name          string
+	ttl           time.Duration
+	authorization string
+	act           func(c ThingCache)
+	expectedThing *entities.Thing
+}
+
+var memoryThingCacheCases = []memoryThingCacheTestCase{
+	{
+		"thing cached",
+		time.Minute,
+		"authorization-token",
+		func(_ ThingCache) {},
+		cachedThingExample,
+	},
+	{
+		"thing not cached for another token",
+		time.Minute,
+		"another-token",
+		func(_ ThingCache) {},
+		nil,
+	},
+	{
+		"thing expired",
+		10 * time.Millisecond,
+		"authorization-token",
+		func(_ ThingCache) { time.Sleep(20 * time.Millisecond) },
+		nil,
+	},
+	{
+		"thing deleted",
+		time.Minute,
+		"authorization-token",
+		func(c ThingCache) { _ = c.Delete(context.Background(), cachedThingExample.ID) },
+		nil,
+	},
+}
+
+func TestMemoryThingCache(t *testing.T) {
+	for _, tc := range memoryThingCacheCases {
+		t.Run(tc.name, func(t *testing.T) {
+			thingCache := NewMemoryThingCache(tc.ttl)
+			assert.NoError(t, thingCache.Save(context.Background(), "authorization-token", cachedThingExample, 0))
+
+			tc.act(thingCache)
+
+			thing, err := thingCache.Get(context.Background(), tc.authorization, cachedThingExample.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedThing, thing)
+		})
+	}
+}
+
+func TestMemoryThingCacheSkipsThingDeletedAfterVersion(t *testing.T) {
+	thingCache := NewMemoryThingCache(time.Minute)
+	version, err := thingCache.Version(context.Background(), cachedThingExample.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, thingCache.Delete(context.Background(), cachedThingExample.ID))
+
+	assert.NoError(t, thingCache.Save(context.Background(), "authorization-token", cachedThingExample, version))
+	thing, err := thingCache.Get(context.Background(), "authorization-token", cachedThingExample.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, thing)
+}
+
+func TestMemoryThingCacheDropsExpiredThings(t *testing.T) {
+	thingCache := NewMemoryThingCache(50 * time.Millisecond).(*memoryThingCache)
+	assert.NoError(t, thingCache.Save(context.Background(), "authorization-token", cachedThingExample, 0))
+	assert.NoError(t, thingCache.Delete(context.Background(), "another-thing"))
+	time.Sleep(60 * time.Millisecond)
+
+	another := &entities.Thing{ID: "a0e10e8ccb51a9ba", Token: "another-token", Name: "another"}
+	assert.NoError(t, thingCache.Save(context.Background(), "authorization-token", another, 0))
+	assert.Len(t, thingCache.things, 1)
+	assert.Contains(t, thingCache.things, another.ID)
+	assert.Empty(t, thingCache.versions)
+}
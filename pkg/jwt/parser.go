@@ -2,11 +2,17 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+// appTokenKind is the Mainflux key type that represents application tokens
+const appTokenKind = 2
+
 var (
+	// ErrParseToken is returned when the token is malformed or, when it's verified, expired
+	// or signed with another secret
 	ErrParseToken      = errors.New("failed to parse authorization token")
 	ErrClaimsAssertion = errors.New("unable to extract token claims")
 )
@@ -25,6 +31,7 @@ type TokenClaims struct {
 // GetEmail returns the email of the responsible for creating the JWT token, which can be of
 // 'app' or 'user' type. Depending on this, the e-mail can be extract from the `Iss` role claim
 // or the `Sub` role claim.
+// The token's signature isn't verified, so it must be validated by the service that issued it.
 func GetEmail(token string) (string, error) {
 	parser := new(jwt.Parser)
 	// second return value can be ignore since it's only the individual parts of the token
@@ -33,10 +40,45 @@ func GetEmail(token string) (string, error) {
 		return "", ErrParseToken
 	}
 
+	return getEmail(t)
+}
+
+// Verifier verifies the tokens' signature with the secret shared with the service that
+// issued them, e.g. the Mainflux authn service, which signs them with HMAC.
+type Verifier struct {
+	secret []byte
+}
+
+// NewVerifier creates a Verifier of the tokens signed with the secret
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{[]byte(secret)}
+}
+
+// GetEmail returns the email of the responsible for creating the JWT token, like GetEmail,
+// only if the token is signed with the verifier's secret and isn't expired.
+func (v *Verifier) GetEmail(token string) (string, error) {
+	t, err := jwt.ParseWithClaims(token, &TokenClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+		}
+		return v.secret, nil
+	})
+	if err != nil {
+		return "", ErrParseToken
+	}
+
+	return getEmail(t)
+}
+
+func getEmail(t *jwt.Token) (string, error) {
 	claims, ok := t.Claims.(*TokenClaims)
 	if !ok {
 		return "", ErrClaimsAssertion
 	}
 
+	if claims.Kind == appTokenKind {
+		return claims.Iss, nil
+	}
+
 	return claims.Sub, nil
 }
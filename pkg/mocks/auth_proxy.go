@@ -1,11 +1,14 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 	"github.com/stretchr/testify/mock"
 )
 
-// FakeAuthProxy represents a mocking type for the user's proxy service
+// FakeAuthProxy represents a mocking type for the user's proxy service. The context
+// isn't part of the mocked calls.
 type FakeAuthProxy struct {
 	mock.Mock
 	Token string
@@ -13,7 +16,7 @@ type FakeAuthProxy struct {
 }
 
 // CreateAppToken provides a mock function to create a new application token
-func (fup *FakeAuthProxy) CreateAppToken(user entities.User, duration int) (string, error) {
+func (fup *FakeAuthProxy) CreateAppToken(_ context.Context, user entities.User, duration int) (string, error) {
 	args := fup.Called(user, duration)
 	return args.String(0), args.Error(1)
 }
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+// FakeCommandQueue represents a mocking type for the queue of commands to offline things
+type FakeCommandQueue struct {
+	mock.Mock
+}
+
+// Enqueue provides a mock function to queue a command
+func (fcq *FakeCommandQueue) Enqueue(ctx context.Context, command entities.Command) error {
+	args := fcq.Called(command)
+	return args.Error(0)
+}
+
+// Flush provides a mock function to remove the commands queued to a thing
+func (fcq *FakeCommandQueue) Flush(ctx context.Context, thingID string) ([]entities.Command, error) {
+	args := fcq.Called(thingID)
+	return args.Get(0).([]entities.Command), args.Error(1)
+}
+
+// List provides a mock function to list the commands queued to a thing
+func (fcq *FakeCommandQueue) List(ctx context.Context, thingID string) ([]entities.Command, error) {
+	args := fcq.Called(thingID)
+	return args.Get(0).([]entities.Command), args.Error(1)
+}
+
+// Get provides a mock function to get a queued command
+func (fcq *FakeCommandQueue) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	args := fcq.Called(commandID)
+	return args.Get(0).(*entities.Command), args.Error(1)
+}
+
+// Cancel provides a mock function to cancel a queued command
+func (fcq *FakeCommandQueue) Cancel(ctx context.Context, thingID, commandID string) error {
+	args := fcq.Called(thingID, commandID)
+	return args.Error(0)
+}
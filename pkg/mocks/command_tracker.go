@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+// FakeCommandTracker represents a mocking type for the commands tracker
+type FakeCommandTracker struct {
+	mock.Mock
+}
+
+// Add provides a mock function to start tracking a command
+func (fct *FakeCommandTracker) Add(ctx context.Context, thingID, commandID, kind string, sensorIDs []int) (*entities.Command, error) {
+	args := fct.Called(thingID, commandID, kind, sensorIDs)
+	return args.Get(0).(*entities.Command), args.Error(1)
+}
+
+// Remove provides a mock function to stop tracking a command
+func (fct *FakeCommandTracker) Remove(ctx context.Context, commandID string) error {
+	args := fct.Called(commandID)
+	return args.Error(0)
+}
+
+// Ack provides a mock function to acknowledge a command
+func (fct *FakeCommandTracker) Ack(ctx context.Context, thingID, commandID string, failure error) error {
+	args := fct.Called(thingID, commandID, failure)
+	return args.Error(0)
+}
+
+// Match provides a mock function to complete the commands that received data
+func (fct *FakeCommandTracker) Match(ctx context.Context, thingID string, sensorIDs []int) error {
+	args := fct.Called(thingID, sensorIDs)
+	return args.Error(0)
+}
+
+// Get provides a mock function to get a tracked command
+func (fct *FakeCommandTracker) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	args := fct.Called(commandID)
+	return args.Get(0).(*entities.Command), args.Error(1)
+}
@@ -27,21 +27,45 @@ func (fp *FakePublisher) PublishUnregisteredDevice(thingID, token string, err er
 	return ret.Error(0)
 }
 
+// PublishBulkRegisteredDevices provides a mock function to send a bulk register devices response
+func (fp *FakePublisher) PublishBulkRegisteredDevices(results []entities.BulkResult, err error) error {
+	ret := fp.Called(results, err)
+	return ret.Error(0)
+}
+
+// PublishBulkUnregisteredDevices provides a mock function to send a bulk unregister devices response
+func (fp *FakePublisher) PublishBulkUnregisteredDevices(results []entities.BulkResult, token string, err error) error {
+	ret := fp.Called(results, err)
+	return ret.Error(0)
+}
+
 // PublishUpdatedConfig provides a mock function to send an update config response
 func (fp *FakePublisher) PublishUpdatedConfig(thingID string, config []entities.Config, changed bool, err error) error {
 	ret := fp.Called(thingID, config, changed, err)
 	return ret.Error(0)
 }
 
+// PublishUpdatedMetadata provides a mock function to send an update metadata response
+func (fp *FakePublisher) PublishUpdatedMetadata(thingID string, metadata entities.Metadata, err error) error {
+	ret := fp.Called(thingID, metadata, err)
+	return ret.Error(0)
+}
+
+// PublishUpdatedDevice provides a mock function to send an updated device response
+func (fp *FakePublisher) PublishUpdatedDevice(thingID, name string, metadata entities.Metadata, err error) error {
+	ret := fp.Called(thingID, name, metadata, err)
+	return ret.Error(0)
+}
+
 // PublishUpdateData provides a mock function to send an update data command
-func (fp *FakePublisher) PublishUpdateData(thingID string, data []entities.Data) error {
-	args := fp.Called(thingID, data)
+func (fp *FakePublisher) PublishUpdateData(thingID, commandID string, data []entities.Data) error {
+	args := fp.Called(thingID, commandID, data)
 	return args.Error(0)
 }
 
 // PublishRequestData provides a mock function to send a request data command
-func (fp *FakePublisher) PublishRequestData(thingID string, sensorIds []int) error {
-	args := fp.Called(thingID, sensorIds)
+func (fp *FakePublisher) PublishRequestData(thingID, commandID string, sensorIds []int) error {
+	args := fp.Called(thingID, commandID, sensorIds)
 	return args.Error(0)
 }
 
@@ -56,3 +80,27 @@ func (fp *FakePublisher) PublishSessionData(thingID, token, sessionID string, da
 	args := fp.Called(thingID, token, sessionID, data)
 	return args.Error(0)
 }
+
+// PublishSessionExpired provides a mock function to publish an expired session
+func (fp *FakePublisher) PublishSessionExpired(sessionID string) error {
+	args := fp.Called(sessionID)
+	return args.Error(0)
+}
+
+// DeleteSessionExchange provides a mock function to delete a session's exchange
+func (fp *FakePublisher) DeleteSessionExchange(sessionID string, ifUnused bool) error {
+	args := fp.Called(sessionID, ifUnused)
+	return args.Error(0)
+}
+
+// PublishCommandCompleted provides a mock function to publish a completed command
+func (fp *FakePublisher) PublishCommandCompleted(command entities.Command) error {
+	args := fp.Called(command)
+	return args.Error(0)
+}
+
+// PublishCommandTimeout provides a mock function to publish a timed out command
+func (fp *FakePublisher) PublishCommandTimeout(command entities.Command) error {
+	args := fp.Called(command)
+	return args.Error(0)
+}
@@ -1,29 +1,80 @@
 package mocks
 
 import (
+	"context"
+
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 	"github.com/stretchr/testify/mock"
 )
 
 // FakeSessionStore represents a mocking type for session store capabilities.
 // It is composed by the testify mock.Mock type to extend the mocking features
-// provided by the library.
+// provided by the library. The context isn't part of the mocked calls.
 type FakeSessionStore struct {
 	mock.Mock
-	GetReturnErr  error
-	SaveReturnErr error
-	Session       string
+	ListReturnErr error
+	Sessions      []string
+}
+
+// Get provides a mock function to get the e-mail of the user who owns a session available
+// on caching according to the session ID.
+func (fss *FakeSessionStore) Get(_ context.Context, id string) (string, error) {
+	ret := fss.Called(id)
+	return ret.String(0), ret.Error(1)
 }
 
-// Get provides a mock function to get a session avaialble on caching according to the
+// List provides a mock function to list the sessions available on caching according to the
 // user e-mail.
-func (fss *FakeSessionStore) Get(email string) (string, error) {
+func (fss *FakeSessionStore) List(_ context.Context, email string) ([]string, error) {
 	ret := fss.Called(email)
-	return ret.String(0), ret.Error(1)
+	return ret.Get(0).([]string), ret.Error(1)
 }
 
 // Save provides a mock function to save a new session to the caching store.
 // It receives a key (email) and the respective value (session ID).
-func (fss *FakeSessionStore) Save(email, id string) error {
+func (fss *FakeSessionStore) Save(_ context.Context, email, id string) error {
+	ret := fss.Called(email, id)
+	return ret.Error(0)
+}
+
+// GetFilter provides a mock function to get the filter of a session's data.
+func (fss *FakeSessionStore) GetFilter(_ context.Context, id string) (entities.SessionFilter, error) {
+	ret := fss.Called(id)
+	return ret.Get(0).(entities.SessionFilter), ret.Error(1)
+}
+
+// SetFilter provides a mock function to replace the filter of a session's data.
+func (fss *FakeSessionStore) SetFilter(_ context.Context, id string, filter entities.SessionFilter) error {
+	ret := fss.Called(id, filter)
+	return ret.Error(0)
+}
+
+// Refresh provides a mock function to extend the expiration of a session.
+func (fss *FakeSessionStore) Refresh(_ context.Context, id string) error {
+	ret := fss.Called(id)
+	return ret.Error(0)
+}
+
+// Expired provides a mock function to list the sessions whose expiration has passed.
+func (fss *FakeSessionStore) Expired(_ context.Context) ([]entities.Session, error) {
+	ret := fss.Called()
+	return ret.Get(0).([]entities.Session), ret.Error(1)
+}
+
+// Claim provides a mock function to claim an expired session.
+func (fss *FakeSessionStore) Claim(_ context.Context, id string) (bool, error) {
+	ret := fss.Called(id)
+	return ret.Bool(0), ret.Error(1)
+}
+
+// Release provides a mock function to expire a claimed session again.
+func (fss *FakeSessionStore) Release(_ context.Context, id string) error {
+	ret := fss.Called(id)
+	return ret.Error(0)
+}
+
+// Delete provides a mock function to remove a user's session from the caching store.
+func (fss *FakeSessionStore) Delete(_ context.Context, email, id string) error {
 	ret := fss.Called(email, id)
 	return ret.Error(0)
 }
@@ -1,11 +1,14 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 	"github.com/stretchr/testify/mock"
 )
 
-// FakeThingProxy represents a mocking type for the thing's proxy service
+// FakeThingProxy represents a mocking type for the thing's proxy service. The context
+// isn't part of the mocked calls.
 type FakeThingProxy struct {
 	mock.Mock
 	ReturnErr error
@@ -15,31 +18,49 @@ type FakeThingProxy struct {
 }
 
 // Create provides a mock function to create a thing on the thing's service
-func (ftp *FakeThingProxy) Create(id, name, authorization string) (idGenerated string, err error) {
-	ret := ftp.Called(id, name, authorization)
+func (ftp *FakeThingProxy) Create(_ context.Context, id, name, authorization string, metadata entities.Metadata) (idGenerated string, err error) {
+	ret := ftp.Called(id, name, authorization, metadata)
+	return ret.String(0), ret.Error(1)
+}
+
+// Recreate provides a mock function to replace a thing on the thing's service
+func (ftp *FakeThingProxy) Recreate(_ context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	ret := ftp.Called(id, name, authorization, metadata)
 	return ret.String(0), ret.Error(1)
 }
 
 // UpdateConfig provides a mock function to update thing's config on the thing's service
-func (ftp *FakeThingProxy) UpdateConfig(authorization, thingID string, config []entities.Config) error {
+func (ftp *FakeThingProxy) UpdateConfig(_ context.Context, authorization, thingID string, config []entities.Config) error {
 	ret := ftp.Called(authorization, thingID, config)
 	return ret.Error(0)
 }
 
+// UpdateMetadata provides a mock function to update thing's metadata on the thing's service
+func (ftp *FakeThingProxy) UpdateMetadata(_ context.Context, authorization, thingID string, metadata entities.Metadata) error {
+	ret := ftp.Called(authorization, thingID, metadata)
+	return ret.Error(0)
+}
+
+// Update provides a mock function to update thing's name and metadata on the thing's service
+func (ftp *FakeThingProxy) Update(_ context.Context, authorization, thingID, name string, metadata entities.Metadata) error {
+	ret := ftp.Called(authorization, thingID, name, metadata)
+	return ret.Error(0)
+}
+
 // Get provides a mock function to receive a thing from the thing's service
-func (ftp *FakeThingProxy) Get(authorization, thingID string) (*entities.Thing, error) {
+func (ftp *FakeThingProxy) Get(_ context.Context, authorization, thingID string) (*entities.Thing, error) {
 	args := ftp.Called(authorization, thingID)
 	return args.Get(0).(*entities.Thing), args.Error(1)
 }
 
 // List provides a mock function to list things from the thing's service
-func (ftp *FakeThingProxy) List(authorization string) ([]*entities.Thing, error) {
+func (ftp *FakeThingProxy) List(_ context.Context, authorization string) ([]*entities.Thing, error) {
 	args := ftp.Called(authorization)
 	return args.Get(0).([]*entities.Thing), args.Error(1)
 }
 
 // Remove provides a mock function to remove a thing on the thing's service
-func (ftp *FakeThingProxy) Remove(authorization, thingID string) error {
+func (ftp *FakeThingProxy) Remove(_ context.Context, authorization, thingID string) error {
 	ret := ftp.Called(authorization, thingID)
 	return ret.Error(0)
 }
@@ -1,11 +1,14 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 	"github.com/stretchr/testify/mock"
 )
 
-// FakeUsersProxy represents a mocking type for the user's proxy service
+// FakeUsersProxy represents a mocking type for the user's proxy service. The context
+// isn't part of the mocked calls.
 type FakeUsersProxy struct {
 	mock.Mock
 	Token string
@@ -13,7 +16,7 @@ type FakeUsersProxy struct {
 }
 
 // Create provides a mock function to create a new user
-func (fup *FakeUsersProxy) Create(user entities.User) (err error) {
+func (fup *FakeUsersProxy) Create(_ context.Context, user entities.User) (err error) {
 	ret := fup.Called(user)
 
 	rf, ok := ret.Get(0).(func(entities.User) error)
@@ -27,7 +30,7 @@ func (fup *FakeUsersProxy) Create(user entities.User) (err error) {
 }
 
 // CreateToken provides a mock function to create a new user's token
-func (fup *FakeUsersProxy) CreateToken(user entities.User) (string, error) {
+func (fup *FakeUsersProxy) CreateToken(_ context.Context, user entities.User) (string, error) {
 	args := fup.Called(user)
 	return args.String(0), args.Error(1)
 }
@@ -1,7 +1,11 @@
 package network
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 
@@ -9,13 +13,68 @@ import (
 	"github.com/streadway/amqp"
 )
 
-// Amqp handles the connection, queues and exchanges declared
+// Dead-letter exchanges receive the messages that failed to be handled
+const (
+	deadLetterSuffix       = ".dead-letter"
+	deadLetterExchangeType = "fanout"
+)
+
+var (
+	// ErrPublishNacked is returned when the broker doesn't confirm a published message
+	ErrPublishNacked = errors.New("message not confirmed by the broker")
+
+	// ErrPublishTimeout is returned when the broker doesn't confirm a published message in time
+	ErrPublishTimeout = errors.New("timeout waiting for the broker confirmation")
+
+	// ErrPublishUnroutable is returned when a mandatory message isn't routed to any queue
+	ErrPublishUnroutable = errors.New("message not routed to any queue")
+
+	// ErrExchangeInUse is returned when an exchange whose bound queues have consumers is
+	// deleted only if unused
+	ErrExchangeInUse = errors.New("exchange has queues bound to it being consumed")
+
+	// ErrQueueNotMigrated is returned when a queue was declared with other arguments, e.g.
+	// without the dead-letter exchange by a previous version, and must be migrated
+	ErrQueueNotMigrated = errors.New("queue declared with other arguments, see the queues migration in the README")
+)
+
+// Amqp handles the connection, queues and exchanges declared. Messages are published
+// through a pool of channels and each consumed queue has its own channel, so they can
+// be safely used by concurrent goroutines.
 type Amqp struct {
-	url     string
-	logger  logging.Logger
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   *amqp.Queue
+	url             string
+	managementURL   string
+	prefetch        int
+	publishTimeout  time.Duration
+	publishChannels int
+	logger          logging.Logger
+
+	connMutex sync.RWMutex
+	conn      *amqp.Connection
+	pool      *channelPool
+
+	// exchanges already declared in the current connection
+	exchangesMutex sync.Mutex
+	exchanges      map[string]bool
+
+	// subscriptions made through OnMessage are restored on every reconnection, each
+	// queue being consumed in its own channel
+	subscriptionsMutex sync.Mutex
+	subscriptions      []subscription
+	consumers          map[string]*amqp.Channel
+
+	// dial overrides how the TCP connection to the broker is established
+	dial func(network, addr string) (net.Conn, error)
+}
+
+// subscription represents the exchange, queue and binding declared to receive messages
+// on a channel
+type subscription struct {
+	msgChan      chan InMsg
+	queueName    string
+	exchangeName string
+	exchangeType string
+	key          string
 }
 
 // InMsg represents the message received from the AMQP broker
@@ -26,18 +85,65 @@ type InMsg struct {
 	CorrelationID string
 	Headers       map[string]interface{}
 	Body          []byte
+	ack           func() error
+	nack          func() error
+	reject        func() error
 }
 
-// MessageOptions represents the message publishing options
+// MessageOptions represents the message publishing options. Mandatory messages that
+// can't be routed to any queue are reported as failed instead of being dropped. The
+// ReplyTo is the key where the reply of a request/reply command must be published.
 type MessageOptions struct {
 	Authorization string
+	ReplyTo       string
 	CorrelationID string
 	Expiration    string
+	Mandatory     bool
+}
+
+// NewAmqp constructs the AMQP connection handler. The managementURL is the address of
+// the broker's management API, used to verify if an exchange is consumed. The prefetch
+// parameter limits the number of unacknowledged messages delivered to each consumer,
+// publishTimeout is how long to wait for the broker to confirm a published message and
+// publishChannels is the number of messages that can be published at the same time.
+func NewAmqp(url, managementURL string, prefetch int, publishTimeout time.Duration, publishChannels int, logger logging.Logger) *Amqp {
+	return &Amqp{
+		url:             url,
+		managementURL:   managementURL,
+		prefetch:        prefetch,
+		publishTimeout:  publishTimeout,
+		publishChannels: publishChannels,
+		logger:          logger,
+		exchanges:       map[string]bool{},
+		consumers:       map[string]*amqp.Channel{},
+	}
 }
 
-// NewAmqp constructs the AMQP connection handler
-func NewAmqp(url string, logger logging.Logger) *Amqp {
-	return &Amqp{url, logger, nil, nil, nil}
+// Ack acknowledges the message, which means it was successfully handled and can be
+// removed from the broker.
+func (m InMsg) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack()
+}
+
+// Nack informs the message handling was interrupted, e.g. the service is stopping, so the
+// message is sent back to its queue to be handled again.
+func (m InMsg) Nack() error {
+	if m.nack == nil {
+		return nil
+	}
+	return m.nack()
+}
+
+// Reject informs the message can't be handled at all, e.g. it is malformed, so it is
+// dead-lettered without being retried.
+func (m InMsg) Reject() error {
+	if m.reject == nil {
+		return nil
+	}
+	return m.reject()
 }
 
 // Start starts the handler
@@ -53,30 +159,34 @@ func (a *Amqp) Start(started chan bool) {
 	started <- true
 }
 
-// Stop closes the connection started
+// Stop closes the connection started and all of its channels
 func (a *Amqp) Stop() {
-	if a.conn != nil && !a.conn.IsClosed() {
-		defer a.conn.Close()
-	}
+	a.connMutex.RLock()
+	defer a.connMutex.RUnlock()
 
-	if a.channel != nil {
-		defer a.channel.Close()
+	if a.conn != nil && !a.conn.IsClosed() {
+		a.conn.Close()
 	}
 
 	a.logger.Debug("AMQP handler stopped")
 }
 
-// PublishPersistentMessage sends a persistent message to RabbitMQ
+// PublishPersistentMessage sends a persistent message to RabbitMQ and waits for the
+// broker to confirm it. An error is returned if the message is nacked, isn't confirmed
+// in time or, when mandatory, can't be routed to any queue.
 func (a *Amqp) PublishPersistentMessage(exchange, exchangeType, key string, msg MessageSerializer, options *MessageOptions) error {
 	var headers map[string]interface{}
-	var corrID, expTime string
+	var replyTo, corrID, expTime string
+	var mandatory bool
 
 	if options != nil {
 		headers = map[string]interface{}{
 			"Authorization": options.Authorization,
 		}
+		replyTo = options.ReplyTo
 		corrID = options.CorrelationID
 		expTime = options.Expiration
+		mandatory = options.Mandatory
 	}
 
 	body, err := msg.Serialize()
@@ -84,106 +194,301 @@ func (a *Amqp) PublishPersistentMessage(exchange, exchangeType, key string, msg
 		return fmt.Errorf("error serializing message: %w", err)
 	}
 
-	err = a.declareExchange(exchange, exchangeType)
-	if err != nil {
-		return fmt.Errorf("error declaring exchange: %w", err)
-	}
+	return a.publish(exchange, exchangeType, key, mandatory, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     "text/plain",
+		ContentEncoding: "",
+		DeliveryMode:    amqp.Persistent,
+		Priority:        0,
+		CorrelationId:   corrID,
+		ReplyTo:         replyTo,
+		Body:            body,
+		Expiration:      expTime,
+	})
+}
 
-	err = a.channel.Publish(
-		exchange,
-		key,
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			Headers:         headers,
-			ContentType:     "text/plain",
-			ContentEncoding: "",
-			DeliveryMode:    amqp.Persistent,
-			Priority:        0,
-			CorrelationId:   corrID,
-			Body:            body,
-			Expiration:      expTime,
-		},
-	)
+// OnMessage receive messages and put them on channel. The subscription is kept and
+// declared again whenever the connection is reestablished. Each queue is consumed
+// only once, so the messages are delivered to the channel of its first subscription.
+func (a *Amqp) OnMessage(msgChan chan InMsg, queueName, exchangeName, exchangeType, key string) error {
+	a.subscriptionsMutex.Lock()
+	defer a.subscriptionsMutex.Unlock()
+
+	sub := subscription{msgChan, queueName, exchangeName, exchangeType, key}
+	err := a.subscribe(sub, a.isConsuming(queueName))
 	if err != nil {
-		return fmt.Errorf("error publishing message in channel: %w", err)
+		a.logger.Error(err)
+		return err
 	}
 
+	a.subscriptions = append(a.subscriptions, sub)
 	return nil
 }
 
-// OnMessage receive messages and put them on channel
-func (a *Amqp) OnMessage(msgChan chan InMsg, queueName, exchangeName, exchangeType, key string) error {
-	err := a.declareExchange(exchangeName, exchangeType)
+func (a *Amqp) isConsuming(queueName string) bool {
+	for _, sub := range a.subscriptions {
+		if sub.queueName == queueName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subscribe declares the subscription in the channel dedicated to its queue. A failed
+// declaration closes the channel, so it is discarded to be opened again next time.
+func (a *Amqp) subscribe(sub subscription, consuming bool) (err error) {
+	channel, err := a.consumerChannel(sub.queueName)
 	if err != nil {
-		a.logger.Error(err)
 		return err
 	}
+	defer func() {
+		if err != nil {
+			delete(a.consumers, sub.queueName)
+			channel.Close()
+		}
+	}()
 
-	err = a.declareQueue(queueName)
+	err = declareExchange(channel, sub.exchangeName, sub.exchangeType)
 	if err != nil {
-		a.logger.Error(err)
 		return err
 	}
 
-	err = a.channel.QueueBind(
-		queueName,
-		key,
-		exchangeName,
+	err = declareQueue(channel, sub.queueName)
+	if err != nil {
+		return err
+	}
+
+	err = channel.QueueBind(
+		sub.queueName,
+		sub.key,
+		sub.exchangeName,
 		false, // noWait
 		nil,   // arguments
 	)
 	if err != nil {
-		a.logger.Error(err)
 		return err
 	}
 
-	deliveries, err := a.channel.Consume(
-		queueName,
+	if consuming {
+		return nil
+	}
+
+	deliveries, err := channel.Consume(
+		sub.queueName,
 		"",    // consumerTag
-		true,  // noAck
+		false, // noAck
 		false, // exclusive
 		false, // noLocal
 		false, // noWait
 		nil,   // arguments
 	)
 	if err != nil {
-		a.logger.Error(err)
 		return err
 	}
 
-	go convertDeliveryToInMsg(deliveries, msgChan)
+	go a.convertDeliveryToInMsg(deliveries, sub.msgChan)
 
 	return nil
 }
 
-func (a *Amqp) connect() error {
-	conn, err := amqp.Dial(a.url)
+// consumerChannel returns the channel dedicated to consume the queue, opening it when
+// the queue is consumed for the first time in the current connection
+func (a *Amqp) consumerChannel(queueName string) (*amqp.Channel, error) {
+	channel, ok := a.consumers[queueName]
+	if ok {
+		return channel, nil
+	}
+
+	a.connMutex.RLock()
+	channel, err := a.conn.Channel()
+	a.connMutex.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("error opening consumer channel: %w", err)
+	}
+
+	err = channel.Qos(
+		a.prefetch,
+		0,     // prefetchSize
+		false, // global
+	)
+	if err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("error setting consumer prefetch: %w", err)
+	}
+
+	a.consumers[queueName] = channel
+	return channel, nil
+}
+
+// resubscribe declares again the exchanges, queues and bindings of every subscription
+// and restarts consuming their queues. The previous consumers are finished when the
+// connection is closed, so no duplicated consumer is created.
+func (a *Amqp) resubscribe() error {
+	a.subscriptionsMutex.Lock()
+	defer a.subscriptionsMutex.Unlock()
+
+	a.consumers = map[string]*amqp.Channel{}
+	consuming := map[string]bool{}
+	for _, sub := range a.subscriptions {
+		err := a.subscribe(sub, consuming[sub.queueName])
+		if err != nil {
+			return fmt.Errorf("error restoring subscription to %s: %w", sub.queueName, err)
+		}
+		consuming[sub.queueName] = true
+	}
+
+	a.logger.Infof("%d subscriptions restored", len(a.subscriptions))
+	return nil
+}
+
+func (a *Amqp) reconnect() error {
+	err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	err = a.resubscribe()
 	if err != nil {
 		a.logger.Error(err)
+		a.conn.Close()
 		return err
 	}
 
-	a.conn = conn
+	return nil
+}
 
-	channel, err := a.conn.Channel()
+func (a *Amqp) connect() error {
+	conn, err := amqp.DialConfig(a.url, amqp.Config{
+		Heartbeat: 10 * time.Second,
+		Locale:    "en_US",
+		Dial:      a.dial,
+	})
 	if err != nil {
 		a.logger.Error(err)
 		return err
 	}
 
+	pool, err := newChannelPool(conn, a.publishChannels)
+	if err != nil {
+		a.logger.Error(err)
+		conn.Close()
+		return err
+	}
+
+	a.connMutex.Lock()
+	a.conn = conn
+	a.pool = pool
+	a.connMutex.Unlock()
+
+	a.exchangesMutex.Lock()
+	a.exchanges = map[string]bool{}
+	a.exchangesMutex.Unlock()
+
 	a.logger.Debug("AMQP handler connected")
-	a.channel = channel
+	return nil
+}
+
+// publish takes a channel from the pool to publish the message, declaring the exchange
+// if it wasn't declared yet in the current connection. Messages sent to the default
+// exchange, which has no type, don't need it to be declared.
+func (a *Amqp) publish(exchange, exchangeType, key string, mandatory bool, msg amqp.Publishing) error {
+	a.connMutex.RLock()
+	pool := a.pool
+	a.connMutex.RUnlock()
+	if pool == nil {
+		return fmt.Errorf("error publishing message in channel: %w", amqp.ErrClosed)
+	}
+
+	pc := pool.acquire()
+	defer pool.release(pc)
+
+	if exchangeType != "" {
+		err := a.ensureExchange(pc, exchange, exchangeType)
+		if err != nil {
+			return fmt.Errorf("error declaring exchange: %w", err)
+		}
+	}
 
+	return pc.publish(exchange, key, mandatory, msg, a.publishTimeout)
+}
+
+// DeleteExchange deletes the exchange through a channel of the pool. When ifUnused is
+// true, the consumers of the queues bound to it are verified through the management
+// API first. The broker closes the channel when the exchange can't be deleted, so it is
+// replaced when released.
+func (a *Amqp) DeleteExchange(name string, ifUnused bool) error {
+	if ifUnused {
+		consumed, err := a.exchangeConsumed(name)
+		if err != nil {
+			return fmt.Errorf("error verifying exchange consumers: %w", err)
+		}
+		if consumed {
+			return ErrExchangeInUse
+		}
+	}
+
+	a.connMutex.RLock()
+	pool := a.pool
+	a.connMutex.RUnlock()
+	if pool == nil {
+		return fmt.Errorf("error deleting exchange: %w", amqp.ErrClosed)
+	}
+
+	pc := pool.acquire()
+	defer pool.release(pc)
+
+	// the exchange is declared again when a message is published to it
+	a.exchangesMutex.Lock()
+	delete(a.exchanges, name)
+	a.exchangesMutex.Unlock()
+
+	err := pc.channel.ExchangeDelete(name, false, false)
+	if err == nil {
+		return nil
+	}
+
+	pc.failed = true
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return nil
+	}
+
+	return fmt.Errorf("error deleting exchange: %w", err)
+}
+
+// ensureExchange declares the exchange only once per connection, the declaration made
+// in a channel is valid for all channels of the same connection.
+func (a *Amqp) ensureExchange(pc *publishChannel, name, exchangeType string) error {
+	a.exchangesMutex.Lock()
+	declared := a.exchanges[name]
+	a.exchangesMutex.Unlock()
+	if declared {
+		return nil
+	}
+
+	err := declareExchange(pc.channel, name, exchangeType)
+	if err != nil {
+		pc.failed = true
+		return err
+	}
+
+	a.exchangesMutex.Lock()
+	a.exchanges[name] = true
+	a.exchangesMutex.Unlock()
 	return nil
 }
 
+// notifyWhenClosed reconnects and restores the subscriptions when the connection is
+// closed by an error. A connection closed by Stop isn't reestablished.
 func (a *Amqp) notifyWhenClosed(started chan bool) {
 	errReason := <-a.conn.NotifyClose(make(chan *amqp.Error))
 	a.logger.Infof("AMQP connection closed: %s", errReason)
 	started <- false
 	if errReason != nil {
-		err := backoff.Retry(a.connect, backoff.NewExponentialBackOff())
+		retryPolicy := backoff.NewExponentialBackOff()
+		retryPolicy.MaxElapsedTime = 0 // keep trying until the broker is back
+		err := backoff.Retry(a.reconnect, retryPolicy)
 		if err != nil {
 			a.logger.Error(err)
 			started <- false
@@ -195,8 +500,8 @@ func (a *Amqp) notifyWhenClosed(started chan bool) {
 	}
 }
 
-func (a *Amqp) declareExchange(name, exchangeType string) error {
-	return a.channel.ExchangeDeclare(
+func declareExchange(channel *amqp.Channel, name, exchangeType string) error {
+	return channel.ExchangeDeclare(
 		name,
 		exchangeType, // type
 		true,         // durable
@@ -207,22 +512,63 @@ func (a *Amqp) declareExchange(name, exchangeType string) error {
 	)
 }
 
-func (a *Amqp) declareQueue(name string) error {
-	queue, err := a.channel.QueueDeclare(
-		name,
+// declareQueue declares a durable queue and its dead-letter exchange and queue, which
+// receive the messages rejected or that exceeded the retries limit.
+func declareQueue(channel *amqp.Channel, name string) error {
+	deadLetter := name + deadLetterSuffix
+	err := declareExchange(channel, deadLetter, deadLetterExchangeType)
+	if err != nil {
+		return fmt.Errorf("error declaring dead-letter exchange: %w", err)
+	}
+
+	_, err = channel.QueueDeclare(
+		deadLetter,
 		true,  // durable
 		false, // delete when unused
 		false, // exclusive
 		false, // noWait
 		nil,   // arguments
 	)
+	if err != nil {
+		return fmt.Errorf("error declaring dead-letter queue: %w", err)
+	}
+
+	err = channel.QueueBind(deadLetter, "", deadLetter, false, nil)
+	if err != nil {
+		return fmt.Errorf("error binding dead-letter queue: %w", err)
+	}
+
+	_, err = channel.QueueDeclare(
+		name,
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // noWait
+		amqp.Table{"x-dead-letter-exchange": deadLetter},
+	)
+
+	// the broker refuses to declare an existing queue with different arguments
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return fmt.Errorf("error declaring queue %s: %w: %s", name, ErrQueueNotMigrated, amqpErr.Reason)
+	}
 
-	a.queue = &queue
 	return err
 }
 
-func convertDeliveryToInMsg(deliveries <-chan amqp.Delivery, outMsg chan InMsg) {
+func (a *Amqp) convertDeliveryToInMsg(deliveries <-chan amqp.Delivery, outMsg chan InMsg) {
 	for d := range deliveries {
-		outMsg <- InMsg{d.Exchange, d.RoutingKey, d.ReplyTo, d.CorrelationId, d.Headers, d.Body}
+		d := d
+		outMsg <- InMsg{
+			Exchange:      d.Exchange,
+			RoutingKey:    d.RoutingKey,
+			ReplyTo:       d.ReplyTo,
+			CorrelationID: d.CorrelationId,
+			Headers:       d.Headers,
+			Body:          d.Body,
+			ack:           func() error { return d.Ack(false) },
+			nack:          func() error { return d.Nack(false, true) },
+			reject:        func() error { return d.Nack(false, false) },
+		}
 	}
 }
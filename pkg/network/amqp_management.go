@@ -0,0 +1,90 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// managementTimeout is how long to wait for the broker's management API to answer
+const managementTimeout = 5 * time.Second
+
+// managementBinding represents a binding listed by the management API
+type managementBinding struct {
+	Destination     string `json:"destination"`
+	DestinationType string `json:"destination_type"`
+}
+
+// managementQueue represents the queue details obtained from the management API
+type managementQueue struct {
+	Consumers int `json:"consumers"`
+}
+
+// exchangeConsumed verifies through the management API if any queue bound to the exchange
+// has consumers. AMQP doesn't list the bindings of an exchange, and the queues left bound
+// by clients that are gone, e.g. durable ones, don't keep the exchange in use.
+func (a *Amqp) exchangeConsumed(name string) (bool, error) {
+	uri, err := amqp.ParseURI(a.url)
+	if err != nil {
+		return false, err
+	}
+
+	var bindings []managementBinding
+	found, err := a.getManagement(&bindings, "exchanges", uri.Vhost, name, "bindings", "source")
+	if err != nil || !found {
+		return false, err
+	}
+
+	for _, binding := range bindings {
+		if binding.DestinationType != "queue" {
+			continue
+		}
+
+		var queue managementQueue
+		found, err := a.getManagement(&queue, "queues", uri.Vhost, binding.Destination)
+		if err != nil {
+			return false, err
+		}
+		if found && queue.Consumers > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getManagement decodes the resource of the management API whose path is made of the
+// segments, reporting if it was found. The credentials are taken from the management URL.
+func (a *Amqp) getManagement(v interface{}, segments ...string) (bool, error) {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	resource := strings.TrimSuffix(a.managementURL, "/") + "/api/" + strings.Join(escaped, "/")
+
+	client := &http.Client{Timeout: managementTimeout}
+	resp, err := client.Get(resource)
+	if err != nil {
+		return false, fmt.Errorf("error requesting management API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("error requesting management API: status code %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return false, fmt.Errorf("error decoding management API response: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type exchangeConsumedTestCase struct {
+	name             string
+	resources        map[string]string
+	expectedConsumed bool
+	expectedError    bool
+}
+
+var exchangeConsumedCases = []exchangeConsumedTestCase{
+	{
+		"bound queue with consumers",
+		map[string]string{
+			"/api/exchanges/%2F/data.session.published/bindings/source": `[{"destination":"client","destination_type":"queue"}]`,
+			"/api/queues/%2F/client":                                    `{"consumers":1}`,
+		},
+		true,
+		false,
+	},
+	{
+		"bound queue without consumers",
+		map[string]string{
+			"/api/exchanges/%2F/data.session.published/bindings/source": `[{"destination":"client","destination_type":"queue"}]`,
+			"/api/queues/%2F/client":                                    `{"consumers":0}`,
+		},
+		false,
+		false,
+	},
+	{
+		"exchange without bindings",
+		map[string]string{
+			"/api/exchanges/%2F/data.session.published/bindings/source": `[]`,
+		},
+		false,
+		false,
+	},
+	{
+		"exchange not found",
+		map[string]string{},
+		false,
+		false,
+	},
+	{
+		"invalid management response",
+		map[string]string{
+			"/api/exchanges/%2F/data.session.published/bindings/source": `not json`,
+		},
+		false,
+		true,
+	},
+}
+
+func TestAmqpExchangeConsumed(t *testing.T) {
+	for _, tc := range exchangeConsumedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, password, _ := r.BasicAuth()
+				assert.Equal(t, "guest:secret", user+":"+password)
+
+				body, ok := tc.resources[r.URL.EscapedPath()]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			managementURL := "http://guest:secret@" + server.Listener.Addr().String() + "/"
+			a := NewAmqp("amqp://localhost/", managementURL, 10, time.Second, 1, &mocks.FakeLogger{})
+
+			consumed, err := a.exchangeConsumed("data.session.published")
+			assert.Equal(t, tc.expectedError, err != nil)
+			assert.Equal(t, tc.expectedConsumed, consumed)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// publishChannel is an AMQP channel in confirm mode used only for publishing. It
+// publishes one message at a time, so each confirmation can be matched against the
+// message waiting for it by its delivery tag.
+type publishChannel struct {
+	channel     *amqp.Channel
+	deliveryTag uint64
+	confirms    chan amqp.Confirmation
+	returns     chan amqp.Return
+	failed      bool
+}
+
+// channelPool holds the publishing channels of a connection. A channel is taken from
+// the pool by a single publisher and given back after the broker confirms its message.
+type channelPool struct {
+	conn     *amqp.Connection
+	channels chan *publishChannel
+}
+
+func newChannelPool(conn *amqp.Connection, size int) (*channelPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &channelPool{conn, make(chan *publishChannel, size)}
+	for i := 0; i < size; i++ {
+		pc, err := pool.newChannel()
+		if err != nil {
+			return nil, err
+		}
+		pool.channels <- pc
+	}
+
+	return pool, nil
+}
+
+func (p *channelPool) newChannel() (*publishChannel, error) {
+	channel, err := p.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error opening publishing channel: %w", err)
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		return nil, fmt.Errorf("error enabling publisher confirms: %w", err)
+	}
+
+	return &publishChannel{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  channel.NotifyReturn(make(chan amqp.Return, 16)),
+	}, nil
+}
+
+func (p *channelPool) acquire() *publishChannel {
+	return <-p.channels
+}
+
+// release gives the channel back to the pool. A channel that has failed may have been
+// closed by the broker, and one that timed out waiting for a confirmation may still
+// receive it while idle in the pool, blocking the connection, so they are replaced by
+// new ones when possible.
+func (p *channelPool) release(pc *publishChannel) {
+	if pc.failed {
+		newPc, err := p.newChannel()
+		if err == nil {
+			pc.channel.Close()
+			pc = newPc
+		}
+		pc.failed = false
+	}
+
+	p.channels <- pc
+}
+
+// publish sends the message and blocks until the broker confirms it. The channel is
+// discarded when the confirmation times out. If it can't be replaced, the confirmations
+// and returns that arrive after their publisher gave up waiting are discarded by
+// comparing the delivery tags, which are also sent as the message ID. Returned
+// messages are received before the confirmation.
+func (pc *publishChannel) publish(exchange, key string, mandatory bool, msg amqp.Publishing, timeout time.Duration) error {
+	pc.discardReturned()
+	msg.MessageId = strconv.FormatUint(pc.deliveryTag+1, 10)
+	err := pc.channel.Publish(exchange, key, mandatory, false, msg)
+	if err != nil {
+		pc.failed = true
+		return fmt.Errorf("error publishing message in channel: %w", err)
+	}
+	pc.deliveryTag++
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case confirm, ok := <-pc.confirms:
+			if !ok {
+				pc.failed = true
+				return fmt.Errorf("error publishing message in channel: %w", amqp.ErrClosed)
+			}
+			if confirm.DeliveryTag < pc.deliveryTag {
+				continue
+			}
+			if !confirm.Ack {
+				return ErrPublishNacked
+			}
+			return pc.checkReturned(msg.MessageId)
+		case <-timer.C:
+			pc.failed = true
+			return ErrPublishTimeout
+		}
+	}
+}
+
+func (pc *publishChannel) discardReturned() {
+	for {
+		select {
+		case <-pc.returns:
+		default:
+			return
+		}
+	}
+}
+
+func (pc *publishChannel) checkReturned(messageID string) error {
+	for {
+		select {
+		case r := <-pc.returns:
+			if r.MessageId != messageID {
+				continue
+			}
+			return fmt.Errorf("%w: %s", ErrPublishUnroutable, r.ReplyText)
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned when a request isn't sent because the service's circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops sending requests to a failing service. The circuit opens after a
+// number of consecutive failures and, once the cooldown has passed, a single request is
+// allowed to probe the service: the circuit closes again if it succeeds.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	logger    logging.Logger
+	mutex     sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker instance for the named service. A zero
+// threshold disables the circuit breaker.
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration, logger logging.Logger) *CircuitBreaker {
+	return &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, logger: logger, state: BreakerClosed}
+}
+
+// Allow returns ErrCircuitOpen if the request must not be sent
+func (b *CircuitBreaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+	case BreakerHalfOpen:
+		// a probe request is already in flight
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// Success records a successful request, closing the circuit
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.setState(BreakerClosed)
+}
+
+// Failure records a failed request, opening the circuit when the threshold is reached or
+// the probe request fails
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.threshold > 0 && (b.state == BreakerHalfOpen || b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// Cancel records a request given up by the caller, which says nothing about the service.
+// A canceled probe request reopens the circuit, so the service is probed again after the
+// cooldown instead of the circuit waiting for a probe that never finishes.
+func (b *CircuitBreaker) Cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// State returns the circuit state
+func (b *CircuitBreaker) State() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+// setState changes the circuit state and logs the transition. It must be called with the
+// mutex locked.
+func (b *CircuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	b.logger.Infof("%s service circuit breaker changed from %s to %s", b.name, b.state, state)
+	b.state = state
+}
@@ -0,0 +1,122 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type breakerTestCase struct {
+	name          string
+	threshold     int
+	act           func(b *CircuitBreaker)
+	expectedState string
+	expectedErr   error
+}
+
+var breakerCases = []breakerTestCase{
+	{
+		"closed while failures are below the threshold",
+		3,
+		func(b *CircuitBreaker) {
+			b.Failure()
+			b.Failure()
+		},
+		BreakerClosed,
+		nil,
+	},
+	{
+		"opened when failures reach the threshold",
+		3,
+		func(b *CircuitBreaker) {
+			b.Failure()
+			b.Failure()
+			b.Failure()
+		},
+		BreakerOpen,
+		ErrCircuitOpen,
+	},
+	{
+		"failures reset by a success",
+		3,
+		func(b *CircuitBreaker) {
+			b.Failure()
+			b.Failure()
+			b.Success()
+			b.Failure()
+		},
+		BreakerClosed,
+		nil,
+	},
+	{
+		"never opened when disabled",
+		0,
+		func(b *CircuitBreaker) {
+			b.Failure()
+			b.Failure()
+			b.Failure()
+		},
+		BreakerClosed,
+		nil,
+	},
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	for _, tc := range breakerCases {
+		t.Run(tc.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker("things", tc.threshold, time.Minute, &mocks.FakeLogger{})
+
+			tc.act(breaker)
+
+			assert.Equal(t, tc.expectedState, breaker.State())
+			assert.True(t, errors.Is(breaker.Allow(), tc.expectedErr))
+		})
+	}
+}
+
+func TestCircuitBreakerProbesAfterCooldown(t *testing.T) {
+	breaker := NewCircuitBreaker("things", 1, 20*time.Millisecond, &mocks.FakeLogger{})
+	breaker.Failure()
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	// only a single probe request is allowed
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	breaker.Failure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestCircuitBreakerReopensWhenProbeCanceled(t *testing.T) {
+	breaker := NewCircuitBreaker("things", 1, 20*time.Millisecond, &mocks.FakeLogger{})
+	breaker.Failure()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+	breaker.Cancel()
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.Equal(t, ErrCircuitOpen, breaker.Allow())
+
+	// the next cooldown allows another probe request
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestCircuitBreakerKeptClosedWhenRequestCanceled(t *testing.T) {
+	breaker := NewCircuitBreaker("things", 1, time.Minute, &mocks.FakeLogger{})
+	assert.NoError(t, breaker.Allow())
+	breaker.Cancel()
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
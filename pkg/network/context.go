@@ -0,0 +1,20 @@
+package network
+
+import "context"
+
+// CorrelationIDHeader is the HTTP header that carries the correlation ID to the upstream services
+const CorrelationIDHeader = "X-Correlation-ID"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx that carries the correlation ID, which identifies
+// the operations started by the same message or request.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or an empty string if there is none
+func CorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
@@ -0,0 +1,131 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// ErrServiceFailure is returned when the service keeps failing to handle the request, i.e.
+// the response of the last attempt is a 5xx
+var ErrServiceFailure = errors.New("service failed to handle the request")
+
+// retryInterval is the interval before the first retry, which grows exponentially
+const retryInterval = 100 * time.Millisecond
+
+// HTTPClient is the client shared by the proxies to the upstream services. It times out
+// the requests, retries the idempotent ones that fail with exponential backoff and stops
+// sending requests to the service while its circuit breaker is open. The service failures
+// are the transport errors and the 5xx responses.
+type HTTPClient struct {
+	name    string
+	client  *http.Client
+	retries int
+	breaker *CircuitBreaker
+	logger  logging.Logger
+}
+
+// NewHTTPClient creates a new HTTPClient instance for the named service
+func NewHTTPClient(name string, timeout time.Duration, retries int, breaker *CircuitBreaker, logger logging.Logger) *HTTPClient {
+	return &HTTPClient{name, &http.Client{Timeout: timeout}, retries, breaker, logger}
+}
+
+// Name returns the upstream service name
+func (c *HTTPClient) Name() string {
+	return c.name
+}
+
+// State returns the state of the upstream service's circuit breaker
+func (c *HTTPClient) State() string {
+	return c.breaker.State()
+}
+
+// Do sends the request, retrying it when it is idempotent. The last response is returned
+// so the caller can map its status code, unless it's a service failure, which is returned
+// as ErrServiceFailure. The correlation ID carried by the request's context is sent in the
+// X-Correlation-ID header.
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if isIdempotent(req.Method) {
+		attempts += c.retries
+	}
+
+	if correlationID := CorrelationID(req.Context()); correlationID != "" {
+		req.Header.Set(CorrelationIDHeader, correlationID)
+	}
+
+	retryPolicy := backoff.NewExponentialBackOff()
+	retryPolicy.InitialInterval = retryInterval
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(req)
+		if errors.Is(err, ErrCircuitOpen) || !isFailure(resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			err = fmt.Errorf("%s service responded with status code %d: %w", c.name, resp.StatusCode, ErrServiceFailure)
+		}
+		if attempt == attempts {
+			return nil, err
+		}
+
+		wait := retryPolicy.NextBackOff()
+		c.logger.Infof("retrying %s %s in %s: %s", req.Method, req.URL.Path, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (c *HTTPClient) send(req *http.Request) (*http.Response, error) {
+	err := c.breaker.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%s service unavailable: %w", c.name, err)
+	}
+
+	resp, err := c.client.Do(req)
+	if req.Context().Err() != nil {
+		// the caller gave up on the request, which says nothing about the service
+		c.breaker.Cancel()
+		return resp, err
+	}
+
+	if isFailure(resp, err) {
+		c.breaker.Failure()
+	} else {
+		c.breaker.Success()
+	}
+
+	return resp, err
+}
+
+func isFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
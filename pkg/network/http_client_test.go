@@ -0,0 +1,176 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type httpClientTestCase struct {
+	name             string
+	method           string
+	failures         int32
+	threshold        int
+	expectedStatus   int
+	expectedRequests int32
+	expectedState    string
+}
+
+var httpClientCases = []httpClientTestCase{
+	{
+		"idempotent request retried until it succeeds",
+		http.MethodGet,
+		2,
+		0,
+		http.StatusOK,
+		3,
+		BreakerClosed,
+	},
+	{
+		"idempotent request retried until retries are exhausted",
+		http.MethodPut,
+		5,
+		0,
+		http.StatusInternalServerError,
+		3,
+		BreakerClosed,
+	},
+	{
+		"non idempotent request not retried",
+		http.MethodPost,
+		1,
+		0,
+		http.StatusInternalServerError,
+		1,
+		BreakerClosed,
+	},
+	{
+		"retries stopped when the circuit opens",
+		http.MethodGet,
+		5,
+		2,
+		0,
+		2,
+		BreakerOpen,
+	},
+}
+
+func TestHTTPClient(t *testing.T) {
+	for _, tc := range httpClientCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				assert.Equal(t, "body", string(body))
+				if atomic.AddInt32(&requests, 1) <= tc.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			breaker := NewCircuitBreaker("things", tc.threshold, time.Minute, &mocks.FakeLogger{})
+			client := NewHTTPClient("things", time.Second, 2, breaker, &mocks.FakeLogger{})
+			req, err := http.NewRequest(tc.method, server.URL, strings.NewReader("body"))
+			assert.NoError(t, err)
+
+			resp, err := client.Do(req)
+			switch tc.expectedStatus {
+			case 0:
+				assert.True(t, errors.Is(err, ErrCircuitOpen))
+			case http.StatusInternalServerError:
+				assert.True(t, errors.Is(err, ErrServiceFailure))
+				assert.Nil(t, resp)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+				resp.Body.Close()
+			}
+			assert.Equal(t, tc.expectedRequests, atomic.LoadInt32(&requests))
+			assert.Equal(t, tc.expectedState, client.State())
+		})
+	}
+}
+
+func TestHTTPClientTimesOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker("users", 1, time.Minute, &mocks.FakeLogger{})
+	client := NewHTTPClient("users", 10*time.Millisecond, 0, breaker, &mocks.FakeLogger{})
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	assert.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, BreakerOpen, client.State())
+}
+
+func TestHTTPClientSendsCorrelationID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "correlation-id", r.Header.Get(CorrelationIDHeader))
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker("things", 1, time.Minute, &mocks.FakeLogger{})
+	client := NewHTTPClient("things", time.Second, 0, breaker, &mocks.FakeLogger{})
+	ctx := WithCorrelationID(context.Background(), "correlation-id")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestHTTPClientCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker("things", 1, time.Minute, &mocks.FakeLogger{})
+	client := NewHTTPClient("things", time.Second, 2, breaker, &mocks.FakeLogger{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, BreakerClosed, client.State())
+}
+
+func TestHTTPClientCanceledProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker("things", 1, 20*time.Millisecond, &mocks.FakeLogger{})
+	breaker.Failure()
+	time.Sleep(30 * time.Millisecond)
+
+	client := NewHTTPClient("things", time.Second, 0, breaker, &mocks.FakeLogger{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, BreakerOpen, client.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+}
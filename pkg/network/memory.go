@@ -0,0 +1,225 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+)
+
+const memoryQueueSize = 1024
+
+// MemoryBus is an in-process message broker that implements the same publish/subscribe
+// contract of the AMQP transport. It supports direct and fanout exchanges, routing keys,
+// reply-to and correlation IDs, allowing the whole events flow to run in tests and in
+// single node deployments without a RabbitMQ server.
+type MemoryBus struct {
+	logger    logging.Logger
+	mutex     sync.Mutex
+	exchanges map[string]*memoryExchange
+	queues    map[string]*memoryQueue
+}
+
+type memoryExchange struct {
+	kind     string
+	bindings []memoryBinding
+}
+
+type memoryBinding struct {
+	key   string
+	queue *memoryQueue
+}
+
+// memoryQueue buffers the messages until they are consumed. Like in the AMQP
+// transport, a queue is consumed only once.
+type memoryQueue struct {
+	name      string
+	messages  chan memoryMessage
+	consuming bool
+}
+
+type memoryMessage struct {
+	exchange      string
+	routingKey    string
+	replyTo       string
+	correlationID string
+	headers       map[string]interface{}
+	body          []byte
+}
+
+// NewMemoryBus creates the in-memory message bus. Like in the AMQP transport, the nacked
+// messages are sent back to their queue and the rejected ones are dead-lettered.
+func NewMemoryBus(logger logging.Logger) *MemoryBus {
+	return &MemoryBus{
+		logger:    logger,
+		exchanges: map[string]*memoryExchange{},
+		queues:    map[string]*memoryQueue{},
+	}
+}
+
+// Start starts the bus, which is always available
+func (mb *MemoryBus) Start(started chan bool) {
+	mb.logger.Debug("memory bus started")
+	started <- true
+}
+
+// Stop stops the bus
+func (mb *MemoryBus) Stop() {
+	mb.logger.Debug("memory bus stopped")
+}
+
+// PublishPersistentMessage routes the message to the queues bound to the exchange. The
+// message is confirmed as soon as it is stored in the queues.
+func (mb *MemoryBus) PublishPersistentMessage(exchange, exchangeType, key string, msg MessageSerializer, options *MessageOptions) error {
+	body, err := msg.Serialize()
+	if err != nil {
+		return fmt.Errorf("error serializing message: %w", err)
+	}
+
+	m := memoryMessage{exchange: exchange, routingKey: key, body: body}
+	mandatory := false
+	if options != nil {
+		m.headers = map[string]interface{}{
+			"Authorization": options.Authorization,
+		}
+		m.replyTo = options.ReplyTo
+		m.correlationID = options.CorrelationID
+		mandatory = options.Mandatory
+	}
+
+	mb.mutex.Lock()
+	queues := mb.route(mb.declareExchange(exchange, exchangeType), key)
+	mb.mutex.Unlock()
+
+	if len(queues) == 0 && mandatory {
+		return ErrPublishUnroutable
+	}
+
+	for _, q := range queues {
+		q.messages <- m
+	}
+
+	return nil
+}
+
+// OnMessage binds the queue to the exchange and starts delivering its messages to the
+// channel, if it isn't consumed yet.
+func (mb *MemoryBus) OnMessage(msgChan chan InMsg, queueName, exchangeName, exchangeType, key string) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	e := mb.declareExchange(exchangeName, exchangeType)
+	if e.kind != exchangeType {
+		return fmt.Errorf("exchange %s already declared as %s", exchangeName, e.kind)
+	}
+
+	q := mb.declareQueue(queueName)
+	e.bindings = append(e.bindings, memoryBinding{key, q})
+
+	if !q.consuming {
+		q.consuming = true
+		go mb.consume(q, msgChan)
+	}
+
+	return nil
+}
+
+// DeleteExchange removes the exchange and its bindings. The queues that were bound to
+// it are kept, but don't receive its messages anymore.
+func (mb *MemoryBus) DeleteExchange(name string, ifUnused bool) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	e, ok := mb.exchanges[name]
+	if !ok {
+		return nil
+	}
+	if ifUnused {
+		for _, b := range e.bindings {
+			if b.queue.consuming {
+				return ErrExchangeInUse
+			}
+		}
+	}
+
+	delete(mb.exchanges, name)
+	return nil
+}
+
+func (mb *MemoryBus) declareExchange(name, kind string) *memoryExchange {
+	e, ok := mb.exchanges[name]
+	if !ok {
+		e = &memoryExchange{kind: kind}
+		mb.exchanges[name] = e
+	}
+
+	return e
+}
+
+func (mb *MemoryBus) declareQueue(name string) *memoryQueue {
+	q, ok := mb.queues[name]
+	if !ok {
+		q = &memoryQueue{name: name, messages: make(chan memoryMessage, memoryQueueSize)}
+		mb.queues[name] = q
+	}
+
+	return q
+}
+
+// route selects the queues that must receive a message published with the key. Each
+// queue receives the message once, even if it is bound more than once.
+func (mb *MemoryBus) route(e *memoryExchange, key string) []*memoryQueue {
+	var queues []*memoryQueue
+	selected := map[*memoryQueue]bool{}
+	for _, b := range e.bindings {
+		if e.kind != "fanout" && b.key != key {
+			continue
+		}
+		if !selected[b.queue] {
+			selected[b.queue] = true
+			queues = append(queues, b.queue)
+		}
+	}
+
+	return queues
+}
+
+func (mb *MemoryBus) consume(q *memoryQueue, msgChan chan InMsg) {
+	for m := range q.messages {
+		m := m
+		msgChan <- InMsg{
+			Exchange:      m.exchange,
+			RoutingKey:    m.routingKey,
+			ReplyTo:       m.replyTo,
+			CorrelationID: m.correlationID,
+			Headers:       m.headers,
+			Body:          m.body,
+			nack:          func() error { return mb.requeue(q, m) },
+			reject:        func() error { return mb.deadLetter(q, m) },
+		}
+	}
+}
+
+// requeue sends the message back to its queue to be handled again
+func (mb *MemoryBus) requeue(q *memoryQueue, m memoryMessage) error {
+	select {
+	case q.messages <- m:
+		return nil
+	default:
+		return fmt.Errorf("queue %s is full", q.name)
+	}
+}
+
+// deadLetter moves the message to the `<queue>.dead-letter` queue
+func (mb *MemoryBus) deadLetter(q *memoryQueue, m memoryMessage) error {
+	mb.mutex.Lock()
+	dl := mb.declareQueue(q.name + deadLetterSuffix)
+	mb.mutex.Unlock()
+
+	select {
+	case dl.messages <- m:
+		return nil
+	default:
+		return fmt.Errorf("dead-letter queue %s is full", dl.name)
+	}
+}
@@ -0,0 +1,172 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryTestCase struct {
+	name         string
+	exchange     string
+	exchangeType string
+	publishKey   string
+	bindingKey   string
+	options      *MessageOptions
+	delivered    bool
+	expectedErr  error
+}
+
+var memoryCases = []memoryTestCase{
+	{
+		"command received through direct exchange",
+		"device",
+		"direct",
+		"device.register",
+		"device.register",
+		&MessageOptions{Authorization: "authorization-token"},
+		true,
+		nil,
+	},
+	{
+		"event received through fanout exchange",
+		"data.sent",
+		"fanout",
+		"",
+		"",
+		&MessageOptions{Authorization: "authorization-token"},
+		true,
+		nil,
+	},
+	{
+		"reply received with reply-to and correlation ID",
+		"device",
+		"direct",
+		"reply-queue",
+		"reply-queue",
+		&MessageOptions{ReplyTo: "reply-key", CorrelationID: "correlation-id"},
+		true,
+		nil,
+	},
+	{
+		"message not routed to queue bound to other key",
+		"device",
+		"direct",
+		"device.unregister",
+		"device.auth",
+		nil,
+		false,
+		nil,
+	},
+	{
+		"mandatory message without queue bound",
+		"device",
+		"direct",
+		"device.unregister",
+		"device.list",
+		&MessageOptions{Mandatory: true},
+		false,
+		ErrPublishUnroutable,
+	},
+}
+
+func receive(t *testing.T, msgChan chan InMsg) InMsg {
+	select {
+	case msg := <-msgChan:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return InMsg{}
+}
+
+func TestMemoryBusPublishAndSubscribe(t *testing.T) {
+	for _, tc := range memoryCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := NewMemoryBus(&mocks.FakeLogger{})
+			msgChan := make(chan InMsg, 1)
+			err := mb.OnMessage(msgChan, "queue", tc.exchange, tc.exchangeType, tc.bindingKey)
+			assert.NoError(t, err)
+
+			err = mb.PublishPersistentMessage(tc.exchange, tc.exchangeType, tc.publishKey, NewMessage(tc.name), tc.options)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			if !tc.delivered {
+				select {
+				case msg := <-msgChan:
+					t.Errorf("unexpected message: %s", msg.Body)
+				case <-time.After(100 * time.Millisecond):
+				}
+				return
+			}
+
+			msg := receive(t, msgChan)
+			assert.Equal(t, tc.exchange, msg.Exchange)
+			assert.Equal(t, tc.publishKey, msg.RoutingKey)
+			assert.Equal(t, tc.options.Authorization, msg.Headers["Authorization"])
+			assert.Equal(t, tc.options.ReplyTo, msg.ReplyTo)
+			assert.Equal(t, tc.options.CorrelationID, msg.CorrelationID)
+			assert.Equal(t, `"`+tc.name+`"`, string(msg.Body))
+			assert.NoError(t, msg.Ack())
+		})
+	}
+}
+
+func TestMemoryBusDeliversFanoutToEveryQueue(t *testing.T) {
+	mb := NewMemoryBus(&mocks.FakeLogger{})
+	first := make(chan InMsg, 1)
+	second := make(chan InMsg, 1)
+	assert.NoError(t, mb.OnMessage(first, "first", "data.published", "fanout", ""))
+	assert.NoError(t, mb.OnMessage(second, "second", "data.published", "fanout", ""))
+
+	err := mb.PublishPersistentMessage("data.published", "fanout", "", NewMessage("data"), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `"data"`, string(receive(t, first).Body))
+	assert.Equal(t, `"data"`, string(receive(t, second).Body))
+}
+
+func TestMemoryBusRequeuesNackedMessage(t *testing.T) {
+	mb := NewMemoryBus(&mocks.FakeLogger{})
+	msgChan := make(chan InMsg, 1)
+	assert.NoError(t, mb.OnMessage(msgChan, "queue", "device", "direct", "device.register"))
+
+	err := mb.PublishPersistentMessage("device", "direct", "device.register", NewMessage("register"), nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, receive(t, msgChan).Nack())
+	assert.Equal(t, `"register"`, string(receive(t, msgChan).Body))
+}
+
+func TestMemoryBusDeadLettersRejectedMessage(t *testing.T) {
+	mb := NewMemoryBus(&mocks.FakeLogger{})
+	msgChan := make(chan InMsg, 1)
+	deadLetterChan := make(chan InMsg, 1)
+	assert.NoError(t, mb.OnMessage(msgChan, "queue", "device", "direct", "device.register"))
+	assert.NoError(t, mb.OnMessage(deadLetterChan, "queue"+deadLetterSuffix, "dead-letter", "fanout", ""))
+
+	err := mb.PublishPersistentMessage("device", "direct", "device.register", NewMessage("register"), nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, receive(t, msgChan).Reject())
+
+	msg := receive(t, deadLetterChan)
+	assert.Equal(t, "device.register", msg.RoutingKey)
+	assert.Equal(t, `"register"`, string(msg.Body))
+}
+
+func TestMemoryBusDeletesExchange(t *testing.T) {
+	mb := NewMemoryBus(&mocks.FakeLogger{})
+	msgChan := make(chan InMsg, 1)
+	assert.NoError(t, mb.OnMessage(msgChan, "queue", "data.session.published", "fanout", ""))
+
+	assert.Equal(t, ErrExchangeInUse, mb.DeleteExchange("data.session.published", true))
+	assert.NoError(t, mb.DeleteExchange("data.session.published", false))
+	assert.NoError(t, mb.DeleteExchange("data.session.published", true))
+
+	err := mb.PublishPersistentMessage("data.session.published", "fanout", "", NewMessage("data"), &MessageOptions{Mandatory: true})
+	assert.Equal(t, ErrPublishUnroutable, err)
+}
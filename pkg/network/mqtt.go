@@ -0,0 +1,319 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+)
+
+const (
+	mqttQoS              = 1
+	mqttSharedPrefix     = "$share/"
+	mqttDeadLetterSuffix = "/dead-letter"
+
+	// mqttQueueSize is how many messages of each subscription are buffered while the
+	// previous ones are handled. The broker stops sending when its window of messages
+	// not acknowledged yet is full, which is usually much smaller.
+	mqttQueueSize = 1024
+)
+
+// MQTT exposes the same events of the AMQP transport through MQTT topics, allowing
+// constrained gateways to communicate without AMQP. Topics are formed by the routing
+// key of direct exchanges, or by the exchange name when it is a fanout exchange, with
+// the dots replaced by slashes, e.g. `device.register` becomes `device/register` and
+// `data.sent` becomes `data/sent`. Since MQTT 3.1.1 has no message properties, the
+// headers, reply-to and correlation ID are sent in an envelope with the payload.
+//
+// MQTT doesn't report the messages that aren't delivered to any subscriber nor the
+// subscribers of a topic, so the mandatory messages and the deletion of exchanges only
+// if unused fail with ErrUnsupported.
+type MQTT struct {
+	url            string
+	clientID       string
+	shared         bool
+	publishTimeout time.Duration
+	logger         logging.Logger
+	client         mqtt.Client
+	connectedOnce  sync.Once
+
+	// subscriptions made through OnMessage are restored on every reconnection
+	subscriptionsMutex sync.Mutex
+	subscriptions      []mqttSubscription
+}
+
+// mqttSubscription buffers the messages received by paho's router, which is shared by
+// all subscriptions, so a busy handler doesn't stall the other topics and keep-alives.
+type mqttSubscription struct {
+	subscription
+	messages chan mqtt.Message
+}
+
+// mqttEnvelope represents the MQTT message payload, which carries the message
+// properties along with the KNoT message.
+type mqttEnvelope struct {
+	Headers       map[string]interface{} `json:"headers,omitempty"`
+	ReplyTo       string                 `json:"replyTo,omitempty"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	Payload       json.RawMessage        `json:"payload"`
+}
+
+// NewMQTT constructs the MQTT connection handler. When shared is true, the queues are
+// mapped to MQTT shared subscriptions, so the messages are distributed among the
+// babeltower instances like in an AMQP queue. The publishTimeout is how long to wait
+// for the broker to acknowledge a published message.
+func NewMQTT(url, clientID string, shared bool, publishTimeout time.Duration, logger logging.Logger) *MQTT {
+	return &MQTT{
+		url:            url,
+		clientID:       clientID,
+		shared:         shared,
+		publishTimeout: publishTimeout,
+		logger:         logger,
+	}
+}
+
+// Start starts the handler. The channel is informed when the first connection is made
+// or fails, since paho reconnects by itself and restores the subscriptions.
+func (m *MQTT) Start(started chan bool) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(m.url).
+		SetClientID(m.clientID).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetAutoAckDisabled(true).
+		SetOnConnectHandler(func(mqtt.Client) {
+			err := m.resubscribe()
+			if err != nil {
+				m.logger.Error(err)
+			}
+			m.connectedOnce.Do(func() { started <- true })
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Infof("MQTT connection lost: %s", err)
+		})
+	m.client = mqtt.NewClient(opts)
+
+	err := backoff.Retry(m.connect, backoff.NewExponentialBackOff())
+	if err != nil {
+		m.logger.Error(err)
+		started <- false
+	}
+}
+
+// Stop closes the connection started
+func (m *MQTT) Stop() {
+	if m.client != nil && m.client.IsConnected() {
+		m.client.Disconnect(250)
+	}
+
+	m.logger.Debug("MQTT handler stopped")
+}
+
+// PublishPersistentMessage sends the message with QoS 1 and waits for the broker to
+// acknowledge it. The expiration option is ignored and the mandatory messages aren't
+// published, since MQTT doesn't support them.
+func (m *MQTT) PublishPersistentMessage(exchange, exchangeType, key string, msg MessageSerializer, options *MessageOptions) error {
+	if options != nil && options.Mandatory {
+		return fmt.Errorf("error publishing mandatory message: %w", ErrUnsupported)
+	}
+
+	body, err := msg.Serialize()
+	if err != nil {
+		return fmt.Errorf("error serializing message: %w", err)
+	}
+
+	envelope := mqttEnvelope{Payload: body}
+	if options != nil {
+		envelope.Headers = map[string]interface{}{
+			"Authorization": options.Authorization,
+		}
+		envelope.ReplyTo = options.ReplyTo
+		envelope.CorrelationID = options.CorrelationID
+	}
+
+	return m.publish(mqttTopic(exchange, exchangeType, key), envelope)
+}
+
+// DeleteExchange does nothing, since the MQTT topics aren't declared. The topics'
+// subscribers aren't known, so it fails with ErrUnsupported when ifUnused is true.
+func (m *MQTT) DeleteExchange(name string, ifUnused bool) error {
+	if ifUnused {
+		return fmt.Errorf("error verifying exchange consumers: %w", ErrUnsupported)
+	}
+
+	return nil
+}
+
+// OnMessage subscribes to the topic mapped from the exchange and binding key. The
+// subscription is kept and made again whenever the connection is reestablished.
+func (m *MQTT) OnMessage(msgChan chan InMsg, queueName, exchangeName, exchangeType, key string) error {
+	m.subscriptionsMutex.Lock()
+	defer m.subscriptionsMutex.Unlock()
+
+	sub := mqttSubscription{
+		subscription{msgChan, queueName, exchangeName, exchangeType, key},
+		make(chan mqtt.Message, mqttQueueSize),
+	}
+	err := m.subscribe(sub)
+	if err != nil {
+		m.logger.Error(err)
+		return err
+	}
+
+	m.subscriptions = append(m.subscriptions, sub)
+	go m.consume(sub)
+	return nil
+}
+
+func (m *MQTT) connect() error {
+	token := m.client.Connect()
+	token.Wait()
+	err := token.Error()
+	if err != nil {
+		m.logger.Error(err)
+		return err
+	}
+
+	m.logger.Debug("MQTT handler connected")
+	return nil
+}
+
+func (m *MQTT) publish(topic string, envelope mqttEnvelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("error encoding MQTT envelope: %w", err)
+	}
+
+	return m.publishPayload(topic, payload)
+}
+
+func (m *MQTT) publishPayload(topic string, payload []byte) error {
+	token := m.client.Publish(topic, mqttQoS, false, payload)
+	if !token.WaitTimeout(m.publishTimeout) {
+		return ErrPublishTimeout
+	}
+
+	err := token.Error()
+	if err != nil {
+		return fmt.Errorf("error publishing message: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MQTT) subscribe(sub mqttSubscription) error {
+	topic := mqttTopic(sub.exchangeName, sub.exchangeType, sub.key)
+	if m.shared && sub.queueName != "" {
+		topic = mqttSharedPrefix + sub.queueName + "/" + topic
+	}
+
+	token := m.client.Subscribe(topic, mqttQoS, func(_ mqtt.Client, msg mqtt.Message) {
+		m.receive(sub, msg)
+	})
+	token.Wait()
+	err := token.Error()
+	if err != nil {
+		return fmt.Errorf("error subscribing to %s: %w", topic, err)
+	}
+
+	return nil
+}
+
+func (m *MQTT) resubscribe() error {
+	m.subscriptionsMutex.Lock()
+	defer m.subscriptionsMutex.Unlock()
+
+	for _, sub := range m.subscriptions {
+		err := m.subscribe(sub)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receive hands the message off to the subscription's buffer without blocking paho's
+// router. The message is dead-lettered if the buffer is full, since it can't be requeued.
+func (m *MQTT) receive(sub mqttSubscription, msg mqtt.Message) {
+	select {
+	case sub.messages <- msg:
+	default:
+		m.logger.Errorf("MQTT subscription %s is full", sub.queueName)
+		err := m.deadLetter(sub, msg)
+		if err != nil {
+			m.logger.Errorf("error dead-lettering MQTT message: %s", err)
+		}
+	}
+}
+
+func (m *MQTT) consume(sub mqttSubscription) {
+	for msg := range sub.messages {
+		m.onMessage(sub, msg)
+	}
+}
+
+// onMessage converts the MQTT message to the same representation of the AMQP messages.
+// The message is only acknowledged to the broker after it is handled. MQTT has no negative
+// acknowledgement and the unacknowledged messages are discarded with the clean session, so
+// the messages that are nacked, rejected or can't be decoded are published to the
+// `<queue>/dead-letter` topic instead of being requeued.
+func (m *MQTT) onMessage(sub mqttSubscription, msg mqtt.Message) {
+	var envelope mqttEnvelope
+	err := json.Unmarshal(msg.Payload(), &envelope)
+	if err != nil {
+		m.logger.Errorf("error decoding MQTT envelope: %s", err)
+		err = m.deadLetter(sub, msg)
+		if err != nil {
+			m.logger.Errorf("error dead-lettering MQTT message: %s", err)
+		}
+		return
+	}
+
+	ack := func() error {
+		msg.Ack()
+		return nil
+	}
+	deadLetter := func() error {
+		return m.deadLetter(sub, msg)
+	}
+
+	sub.msgChan <- InMsg{
+		Exchange:      sub.exchangeName,
+		RoutingKey:    sub.key,
+		ReplyTo:       envelope.ReplyTo,
+		CorrelationID: envelope.CorrelationID,
+		Headers:       envelope.Headers,
+		Body:          envelope.Payload,
+		ack:           ack,
+		nack:          deadLetter,
+		reject:        deadLetter,
+	}
+}
+
+// deadLetter publishes the message unchanged to the `<queue>/dead-letter` topic and
+// acknowledges it, so it isn't lost when it can't be handled
+func (m *MQTT) deadLetter(sub mqttSubscription, msg mqtt.Message) error {
+	err := m.publishPayload(sub.queueName+mqttDeadLetterSuffix, msg.Payload())
+	if err != nil {
+		return err
+	}
+
+	msg.Ack()
+	return nil
+}
+
+func mqttTopic(exchange, exchangeType, key string) string {
+	name := key
+	if exchangeType == "fanout" {
+		name = exchange
+	}
+
+	return strings.ReplaceAll(name, ".", "/")
+}
@@ -0,0 +1,233 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	broker "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/stretchr/testify/assert"
+)
+
+type mqttTestCase struct {
+	name          string
+	exchange      string
+	exchangeType  string
+	publishKey    string
+	bindingKey    string
+	options       *MessageOptions
+	expectedToken string
+}
+
+var mqttCases = []mqttTestCase{
+	{
+		"command received through direct exchange",
+		"device",
+		"direct",
+		"device.register",
+		"device.register",
+		&MessageOptions{Authorization: "authorization-token"},
+		"authorization-token",
+	},
+	{
+		"event received through fanout exchange",
+		"data.sent",
+		"fanout",
+		"",
+		"",
+		&MessageOptions{Authorization: "authorization-token"},
+		"authorization-token",
+	},
+	{
+		"reply received with correlation ID",
+		"device",
+		"direct",
+		"reply-queue",
+		"reply-queue",
+		&MessageOptions{CorrelationID: "correlation-id"},
+		"",
+	},
+}
+
+func startMQTTBroker(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	server := broker.New()
+	err = server.AddListener(listeners.NewTCP("t1", address), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return "tcp://" + address
+}
+
+func startMQTT(t *testing.T, url, clientID string) *MQTT {
+	m := NewMQTT(url, clientID, false, 5*time.Second, &mocks.FakeLogger{})
+	started := make(chan bool, 1)
+	go m.Start(started)
+
+	select {
+	case ok := <-started:
+		assert.True(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout connecting to the MQTT broker")
+	}
+	t.Cleanup(m.Stop)
+
+	return m
+}
+
+func TestMQTTPublishAndSubscribe(t *testing.T) {
+	url := startMQTTBroker(t)
+	subscriber := startMQTT(t, url, "subscriber")
+	publisher := startMQTT(t, url, "publisher")
+
+	for i, tc := range mqttCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgChan := make(chan InMsg, 1)
+			queue := fmt.Sprintf("queue-%d", i)
+			err := subscriber.OnMessage(msgChan, queue, tc.exchange, tc.exchangeType, tc.bindingKey)
+			assert.NoError(t, err)
+
+			err = publisher.PublishPersistentMessage(tc.exchange, tc.exchangeType, tc.publishKey, NewMessage(tc.name), tc.options)
+			assert.NoError(t, err)
+
+			select {
+			case msg := <-msgChan:
+				assert.Equal(t, tc.exchange, msg.Exchange)
+				assert.Equal(t, tc.bindingKey, msg.RoutingKey)
+				assert.Equal(t, tc.options.CorrelationID, msg.CorrelationID)
+				assert.Equal(t, `"`+tc.name+`"`, string(msg.Body))
+				if tc.expectedToken != "" {
+					assert.Equal(t, tc.expectedToken, msg.Headers["Authorization"])
+				}
+				assert.NoError(t, msg.Ack())
+			case <-time.After(5 * time.Second):
+				t.Error("timeout waiting for message")
+			}
+		})
+	}
+}
+
+func TestMQTTTopicMapping(t *testing.T) {
+	assert.Equal(t, "device/register", mqttTopic("device", "direct", "device.register"))
+	assert.Equal(t, "device/fc3fcf912d0c290a/data/update", mqttTopic("device", "direct", "device.fc3fcf912d0c290a.data.update"))
+	assert.Equal(t, "data/sent", mqttTopic("data.sent", "fanout", ""))
+	assert.Equal(t, "data/session-id/published", mqttTopic("data.session-id.published", "fanout", ""))
+}
+
+func TestMQTTDeadLettersRejectedMessage(t *testing.T) {
+	url := startMQTTBroker(t)
+	m := startMQTT(t, url, "babeltower")
+
+	msgChan := make(chan InMsg, 1)
+	deadLetters := make(chan InMsg, 1)
+	assert.NoError(t, m.OnMessage(msgChan, "queue", "device", "direct", "device.register"))
+	assert.NoError(t, m.OnMessage(deadLetters, "", "device", "direct", "queue"+mqttDeadLetterSuffix))
+
+	err := m.PublishPersistentMessage("device", "direct", "device.register", NewMessage("rejected"), nil)
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-msgChan:
+		assert.NoError(t, msg.Reject())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+
+	select {
+	case msg := <-deadLetters:
+		assert.Equal(t, `"rejected"`, string(msg.Body))
+		assert.NoError(t, msg.Ack())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for dead-lettered message")
+	}
+}
+
+func TestMQTTUnsupportedOperations(t *testing.T) {
+	m := startMQTT(t, startMQTTBroker(t), "babeltower")
+
+	err := m.PublishPersistentMessage("device", "direct", "device.fc3fcf912d0c290a.data.request", NewMessage("command"), &MessageOptions{Mandatory: true})
+	assert.True(t, errors.Is(err, ErrUnsupported))
+
+	assert.True(t, errors.Is(m.DeleteExchange("data.session-id.published", true), ErrUnsupported))
+	assert.NoError(t, m.DeleteExchange("data.session-id.published", false))
+}
+
+func TestMQTTDeadLettersMalformedEnvelope(t *testing.T) {
+	url := startMQTTBroker(t)
+	m := startMQTT(t, url, "babeltower")
+
+	msgChan := make(chan InMsg, 1)
+	deadLetters := make(chan []byte, 1)
+	assert.NoError(t, m.OnMessage(msgChan, "queue", "device", "direct", "device.register"))
+	token := m.client.Subscribe("queue"+mqttDeadLetterSuffix, mqttQoS, func(_ mqtt.Client, msg mqtt.Message) {
+		deadLetters <- msg.Payload()
+		msg.Ack()
+	})
+	token.Wait()
+	assert.NoError(t, token.Error())
+
+	assert.NoError(t, m.publishPayload("device/register", []byte("malformed")))
+
+	select {
+	case payload := <-deadLetters:
+		assert.Equal(t, "malformed", string(payload))
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for dead-lettered message")
+	}
+
+	select {
+	case msg := <-msgChan:
+		t.Errorf("malformed message delivered: %s", msg.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMQTTBusySubscriptionDoesNotStallOthers(t *testing.T) {
+	url := startMQTTBroker(t)
+	m := startMQTT(t, url, "babeltower")
+
+	busy := make(chan InMsg)
+	msgChan := make(chan InMsg, 1)
+	assert.NoError(t, m.OnMessage(busy, "busy-queue", "device", "direct", "device.register"))
+	assert.NoError(t, m.OnMessage(msgChan, "queue", "device", "direct", "device.unregister"))
+
+	assert.NoError(t, m.PublishPersistentMessage("device", "direct", "device.register", NewMessage("first"), nil))
+	assert.NoError(t, m.PublishPersistentMessage("device", "direct", "device.register", NewMessage("second"), nil))
+	assert.NoError(t, m.PublishPersistentMessage("device", "direct", "device.unregister", NewMessage("other"), nil))
+
+	select {
+	case msg := <-msgChan:
+		assert.Equal(t, `"other"`, string(msg.Body))
+		assert.NoError(t, msg.Ack())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+
+	for _, expected := range []string{`"first"`, `"second"`} {
+		select {
+		case msg := <-busy:
+			assert.Equal(t, expected, string(msg.Body))
+			assert.NoError(t, msg.Ack())
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for busy subscription message")
+		}
+	}
+}
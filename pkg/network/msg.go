@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	userEntities "github.com/CESARBR/knot-babeltower/pkg/user/entities"
 )
 
 // MessageSerializer represents a interface for KNoT messages
@@ -17,10 +18,13 @@ type message struct {
 	Payload interface{}
 }
 
-// DeviceRegisterRequest represents the incoming register device request message
+// DeviceRegisterRequest represents the incoming register device request message. The
+// force flag re-creates the thing when it's already registered.
 type DeviceRegisterRequest struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Metadata entities.Metadata `json:"metadata"`
+	Force    bool              `json:"force,omitempty"`
 }
 
 // DeviceRegisteredResponse represents the outgoing register device response message
@@ -42,6 +46,32 @@ type DeviceUnregisteredResponse struct {
 	Error *string `json:"error"`
 }
 
+// DeviceBulkRegisterRequest represents the incoming bulk register devices request message.
+// The force flag applies to all the devices.
+type DeviceBulkRegisterRequest struct {
+	Devices []DeviceRegisterRequest `json:"devices"`
+	Force   bool                    `json:"force,omitempty"`
+}
+
+// DeviceBulkRegisteredResponse represents the outgoing bulk register devices response
+// message, with the result of every device in the requested order
+type DeviceBulkRegisteredResponse struct {
+	Devices []DeviceRegisteredResponse `json:"devices"`
+	Error   *string                    `json:"error"`
+}
+
+// DeviceBulkUnregisterRequest represents the incoming bulk unregister devices request message
+type DeviceBulkUnregisterRequest struct {
+	Devices []DeviceUnregisterRequest `json:"devices"`
+}
+
+// DeviceBulkUnregisteredResponse represents the outgoing bulk unregister devices response
+// message, with the result of every device in the requested order
+type DeviceBulkUnregisteredResponse struct {
+	Devices []DeviceUnregisteredResponse `json:"devices"`
+	Error   *string                      `json:"error"`
+}
+
 // ConfigUpdateRequest represents the incoming update config request message
 type ConfigUpdateRequest struct {
 	ID     string            `json:"id"`
@@ -56,6 +86,35 @@ type ConfigUpdatedResponse struct {
 	Error   *string           `json:"error"`
 }
 
+// MetadataUpdateRequest represents the incoming update metadata request message
+type MetadataUpdateRequest struct {
+	ID       string            `json:"id"`
+	Metadata entities.Metadata `json:"metadata"`
+}
+
+// MetadataUpdatedResponse represents the outgoing update metadata response message
+type MetadataUpdatedResponse struct {
+	ID       string            `json:"id"`
+	Metadata entities.Metadata `json:"metadata"`
+	Error    *string           `json:"error"`
+}
+
+// DeviceUpdateRequest represents the incoming update device request message. The name
+// and metadata are kept when they aren't provided.
+type DeviceUpdateRequest struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name,omitempty"`
+	Metadata *entities.Metadata `json:"metadata,omitempty"`
+}
+
+// DeviceUpdatedResponse represents the outgoing update device response message
+type DeviceUpdatedResponse struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Metadata entities.Metadata `json:"metadata"`
+	Error    *string           `json:"error"`
+}
+
 // DeviceAuthRequest represents the incoming auth device command
 type DeviceAuthRequest struct {
 	ID    string `json:"id"`
@@ -68,22 +127,83 @@ type DeviceAuthResponse struct {
 	Error *string `json:"error"`
 }
 
-// DeviceListResponse represents the outgoing list devices command response
+// DeviceListRequest represents the incoming list devices command. All the options are
+// optional and every thing is listed when they aren't provided.
+type DeviceListRequest struct {
+	Limit     int      `json:"limit,omitempty"`
+	Offset    int      `json:"offset,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	HasConfig *bool    `json:"hasConfig,omitempty"`
+	Sort      string   `json:"sort,omitempty"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+// DeviceListResponse represents the outgoing list devices command response. The total is
+// the number of things that match the filters, regardless of the pagination.
 type DeviceListResponse struct {
 	Things []*entities.Thing `json:"devices"`
+	Total  int               `json:"total"`
 	Error  *string           `json:"error"`
 }
 
-// DataRequest represents the incoming request data command
+// DataRequest represents the incoming request data command. The command ID is
+// optional and it is generated when not provided.
 type DataRequest struct {
 	ID        string `json:"id"`
+	CommandID string `json:"commandId,omitempty"`
 	SensorIds []int  `json:"sensorIds"`
 }
 
-// DataUpdate represents the incoming update data command
+// DataUpdate represents the incoming update data command. The command ID is
+// optional and it is generated when not provided.
 type DataUpdate struct {
-	ID   string          `json:"id"`
-	Data []entities.Data `json:"data"`
+	ID        string          `json:"id"`
+	CommandID string          `json:"commandId,omitempty"`
+	Data      []entities.Data `json:"data"`
+}
+
+// CommandAck represents the incoming acknowledgement of a command by the thing
+type CommandAck struct {
+	ID        string  `json:"id"`
+	CommandID string  `json:"commandId"`
+	Error     *string `json:"error"`
+}
+
+// CommandStatusRequest represents the incoming command status request
+type CommandStatusRequest struct {
+	ID string `json:"id"`
+}
+
+// CommandListRequest represents the incoming request to list the commands queued to a thing
+type CommandListRequest struct {
+	ID string `json:"id"`
+}
+
+// CommandListResponse represents the outgoing list of commands queued to a thing
+type CommandListResponse struct {
+	ID       string             `json:"id"`
+	Commands []entities.Command `json:"commands"`
+	Error    *string            `json:"error"`
+}
+
+// CommandCancelRequest represents the incoming request to cancel a command queued to a thing
+type CommandCancelRequest struct {
+	ID        string `json:"id"`
+	CommandID string `json:"commandId"`
+}
+
+// CommandCancelResponse represents the outgoing cancel command response
+type CommandCancelResponse struct {
+	ID        string  `json:"id"`
+	CommandID string  `json:"commandId"`
+	Error     *string `json:"error"`
+}
+
+// CommandStatusResponse represents the outgoing command status response
+type CommandStatusResponse struct {
+	Command *entities.Command `json:"command"`
+	Error   *string           `json:"error"`
 }
 
 // DataSent represents the data received from the things
@@ -94,7 +214,13 @@ type DataSent struct {
 
 // CreateSessionRequest represents session creation request
 type CreateSessionRequest struct {
-	Token string `json:"token"`
+	Token  string                     `json:"token"`
+	Filter userEntities.SessionFilter `json:"filter"`
+}
+
+// UpdateSessionRequest represents session update request
+type UpdateSessionRequest struct {
+	Filter userEntities.SessionFilter `json:"filter"`
 }
 
 // CreateSessionResponse represents session creation response
@@ -102,6 +228,16 @@ type CreateSessionResponse struct {
 	ID string `json:"id"`
 }
 
+// SessionExpired represents the session that expired and no longer receives data
+type SessionExpired struct {
+	ID string `json:"id"`
+}
+
+// ListSessionsResponse represents the user's sessions listing response
+type ListSessionsResponse struct {
+	Sessions []string `json:"sessions"`
+}
+
 // NewMessage creates a message
 func NewMessage(msg interface{}) MessageSerializer {
 	return message{Payload: msg}
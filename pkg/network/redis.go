@@ -2,49 +2,192 @@ package network
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	redis "github.com/go-redis/redis/v8"
 )
 
+// Redis health states
+const (
+	RedisAvailable   = "available"
+	RedisUnavailable = "unavailable"
+)
+
+// redisPingTimeout is how long a health check waits for the service to answer
+const redisPingTimeout = 3 * time.Second
+
+// redisUpdateAttempts is how many times a value is updated while other clients change it
+const redisUpdateAttempts = 10
+
+// ErrRedisUnavailable is returned by the Redis operations while the service doesn't answer
+// the health checks, so they fail without waiting for the connection timeouts
+var ErrRedisUnavailable = errors.New("redis is unavailable")
+
+// Redis abstracts the Redis service capabilities. The connection is verified with PING when
+// it starts and periodically, and the operations fail while the service is unavailable.
 type Redis struct {
-	URL    string
-	logger logging.Logger
-	rdb    *redis.Client
-}
+	URL            string
+	healthInterval time.Duration
+	logger         logging.Logger
+	rdb            *redis.Client
 
-var ctx = context.Background()
+	mutex     sync.RWMutex
+	available bool
+	quit      chan struct{}
+	stopOnce  sync.Once
+}
 
 // NewRedis creates a new Redis instances and accepts a URL encoded string to configure the
-// connection with Redis service.
+// connection with Redis service. The service health is checked on every healthInterval.
 // redis://<user>:<pass>@localhost:6379/<db>
-func NewRedis(url string, logger logging.Logger) *Redis {
-	return &Redis{url, logger, nil}
+func NewRedis(url string, healthInterval time.Duration, logger logging.Logger) *Redis {
+	return &Redis{URL: url, healthInterval: healthInterval, logger: logger, quit: make(chan struct{})}
 }
 
 // Start starts a connection with the Redis service by parsing the configuration URL and creating
 // a new client instance, which is added to the struct responsible for abstracting this service
-// capabilities.
+// capabilities. The connection is verified with backoff and started reports whenever the service
+// becomes available or unavailable. An invalid URL is reported as unavailable and isn't retried.
 func (r *Redis) Start(started chan bool) {
 	opt, err := redis.ParseURL(r.URL)
 	if err != nil {
-		r.logger.Error(err)
+		r.logger.Errorf("invalid redis URL: %s", err)
+		started <- false
+		return
 	}
 
-	// TODO: handle disconnection/reconnection
+	r.mutex.Lock()
 	r.rdb = redis.NewClient(opt)
-	started <- true
+	r.mutex.Unlock()
+
+	retryPolicy := backoff.WithContext(backoff.NewExponentialBackOff(), r.contextUntilStopped())
+	err = backoff.Retry(r.ping, retryPolicy)
+	if err != nil {
+		r.logger.Errorf("redis connection not established: %s", err)
+	}
+
+	r.setAvailable(err == nil)
+	started <- err == nil
+	go r.monitor(started)
+}
+
+// Stop stops the health checks and closes the client's connections, it can be called
+// more than once
+func (r *Redis) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.quit)
+
+		r.mutex.RLock()
+		defer r.mutex.RUnlock()
+		if r.rdb != nil {
+			r.rdb.Close()
+		}
+	})
+}
+
+// Name returns the upstream service name
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+// State returns whether the service is available according to the health checks
+func (r *Redis) State() string {
+	if r.Available() {
+		return RedisAvailable
+	}
+	return RedisUnavailable
+}
+
+// Available reports whether the service answered the last health check
+func (r *Redis) Available() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.available
+}
+
+// monitor checks the service health and reports when it becomes unavailable or available
+// again. The client reconnects by itself, so the checks only verify it's answering.
+func (r *Redis) monitor(started chan bool) {
+	ticker := time.NewTicker(r.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := r.ping()
+			available := err == nil
+			if available == r.Available() {
+				continue
+			}
+
+			r.setAvailable(available)
+			if available {
+				r.logger.Info("redis connection reestablished")
+			} else {
+				r.logger.Errorf("redis connection lost: %s", err)
+			}
+			started <- available
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *Redis) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
+	defer cancel()
+	return r.rdb.Ping(ctx).Err()
+}
+
+func (r *Redis) setAvailable(available bool) {
+	r.mutex.Lock()
+	r.available = available
+	r.mutex.Unlock()
+}
+
+// contextUntilStopped returns a context canceled when Stop is called
+func (r *Redis) contextUntilStopped() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-r.quit
+		cancel()
+	}()
+	return ctx
 }
 
 // Set stores a key-value pair to the Redis database. The key must be a string and the value can be
 // of any supported type: https://redis.io/topics/data-types.
-func (r *Redis) Set(key string, value interface{}, expiration time.Duration) error {
+func (r *Redis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
 	return r.rdb.Set(ctx, key, value, expiration).Err()
 }
 
+// SetNX stores a key-value pair, like Set, only when the key doesn't exist, reporting if it
+// was stored.
+func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if !r.Available() {
+		return false, ErrRedisUnavailable
+	}
+
+	return r.rdb.SetNX(ctx, key, value, expiration).Result()
+}
+
 // Get retrieves a value from the Redis database according to key, which is returned as a string.
-func (r *Redis) Get(key string) (string, error) {
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	if !r.Available() {
+		return "", ErrRedisUnavailable
+	}
+
 	val, err := r.rdb.Get(ctx, key).Result()
 	if err != nil && err != redis.Nil {
 		return "", err
@@ -52,3 +195,233 @@ func (r *Redis) Get(key string) (string, error) {
 
 	return val, nil
 }
+
+// Update replaces the value stored at key by the one returned by update, which receives the
+// current value, or an empty string when the key doesn't exist, and sets its expiration. The
+// key is removed when the new value is empty, and nothing is changed when update fails. The
+// value is only replaced if the key wasn't changed meanwhile, otherwise update is called again
+// with the new value, so the concurrent updates are applied one after the other.
+func (r *Redis) Update(ctx context.Context, key string, expiration time.Duration, update func(value string) (string, error)) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	txf := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		value, err = update(value)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if value == "" {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, value, expiration)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < redisUpdateAttempts; attempt++ {
+		err := r.rdb.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+
+	return fmt.Errorf("key %s changed by %d concurrent updates", key, redisUpdateAttempts)
+}
+
+// HSet stores a field of the hash stored at key and sets the expiration of the whole hash.
+func (r *Redis) HSet(ctx context.Context, key, field string, value interface{}, expiration time.Duration) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, value)
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+
+	return err
+}
+
+// hSetIfEqualScript stores the hash field and sets the hash expiration only when the guard
+// key holds the expected value. A missing guard key holds 0, like INCR considers it.
+var hSetIfEqualScript = redis.NewScript(`
+if (redis.call("GET", KEYS[1]) or "0") ~= ARGV[1] then
+	return 0
+end
+redis.call("HSET", KEYS[2], ARGV[2], ARGV[3])
+redis.call("PEXPIRE", KEYS[2], ARGV[4])
+return 1
+`)
+
+// HSetIfEqual stores a field of the hash stored at key, like HSet, only when guardKey holds
+// guardValue. Both are checked and set atomically.
+func (r *Redis) HSetIfEqual(ctx context.Context, key, field string, value interface{}, expiration time.Duration, guardKey, guardValue string) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	args := []interface{}{guardValue, field, value, expiration.Milliseconds()}
+	return hSetIfEqualScript.Run(ctx, r.rdb, []string{guardKey, key}, args...).Err()
+}
+
+// HGet retrieves a field of the hash stored at key, which is returned as a string. An empty
+// string is returned when the field doesn't exist.
+func (r *Redis) HGet(ctx context.Context, key, field string) (string, error) {
+	if !r.Available() {
+		return "", ErrRedisUnavailable
+	}
+
+	val, err := r.rdb.HGet(ctx, key, field).Result()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	return val, nil
+}
+
+// Del removes a key from the Redis database.
+func (r *Redis) Del(ctx context.Context, key string) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.Del(ctx, key).Err()
+}
+
+// Incr increments the integer stored at key and sets its expiration.
+func (r *Redis) Incr(ctx context.Context, key string, expiration time.Duration) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+
+	return err
+}
+
+// Expire sets the expiration of key. Keys that don't exist are ignored.
+func (r *Redis) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.Expire(ctx, key, expiration).Err()
+}
+
+// SAdd adds members to the set stored at key.
+func (r *Redis) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.SAdd(ctx, key, members...).Err()
+}
+
+// SMembers retrieves all the members of the set stored at key. An empty slice is returned
+// when the set doesn't exist.
+func (r *Redis) SMembers(ctx context.Context, key string) ([]string, error) {
+	if !r.Available() {
+		return nil, ErrRedisUnavailable
+	}
+
+	return r.rdb.SMembers(ctx, key).Result()
+}
+
+// SRem removes members from the set stored at key.
+func (r *Redis) SRem(ctx context.Context, key string, members ...interface{}) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.SRem(ctx, key, members...).Err()
+}
+
+// ZAdd adds a member with the score to the sorted set stored at key, or updates its score
+// when it already exists.
+func (r *Redis) ZAdd(ctx context.Context, key string, score float64, member interface{}) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.ZAdd(ctx, key, &redis.Z{Score: score, Member: member}).Err()
+}
+
+// ZAddXX updates the score of a member of the sorted set stored at key. Members that
+// don't exist aren't added.
+func (r *Redis) ZAddXX(ctx context.Context, key string, score float64, member interface{}) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.ZAddXX(ctx, key, &redis.Z{Score: score, Member: member}).Err()
+}
+
+// zAddIfMaxScript updates the score of the member only when its current score is up to max
+var zAddIfMaxScript = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[3])
+if not score or tonumber(score) > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[3])
+return 1
+`)
+
+// ZAddIfMax updates the score of a member of the sorted set stored at key only when its
+// current score is up to max, reporting if it was updated. Both are checked and set
+// atomically, so only one of the concurrent callers updates it.
+func (r *Redis) ZAddIfMax(ctx context.Context, key string, max, score float64, member interface{}) (bool, error) {
+	if !r.Available() {
+		return false, ErrRedisUnavailable
+	}
+
+	updated, err := zAddIfMaxScript.Run(ctx, r.rdb, []string{key}, max, score, member).Int()
+	return updated == 1, err
+}
+
+// ZRangeByScore retrieves the members of the sorted set stored at key whose scores are
+// up to max, ordered by their scores.
+func (r *Redis) ZRangeByScore(ctx context.Context, key string, max float64) ([]string, error) {
+	if !r.Available() {
+		return nil, ErrRedisUnavailable
+	}
+
+	return r.rdb.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}).Result()
+}
+
+// ZRem removes members from the sorted set stored at key.
+func (r *Redis) ZRem(ctx context.Context, key string, members ...interface{}) error {
+	if !r.Available() {
+		return ErrRedisUnavailable
+	}
+
+	return r.rdb.ZRem(ctx, key, members...).Err()
+}
+
+// ZClaim removes a member from the sorted set stored at key, reporting if it was removed.
+// Only one of the concurrent callers removes it, so it claims the member.
+func (r *Redis) ZClaim(ctx context.Context, key string, member interface{}) (bool, error) {
+	if !r.Available() {
+		return false, ErrRedisUnavailable
+	}
+
+	removed, err := r.rdb.ZRem(ctx, key, member).Result()
+	return removed == 1, err
+}
@@ -0,0 +1,129 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedisServer answers PONG to every command received while it's listening
+type fakeRedisServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    []net.Conn
+}
+
+func startFakeRedisServer(t *testing.T, addr string) *fakeRedisServer {
+	listener, err := net.Listen("tcp", addr)
+	assert.NoError(t, err)
+
+	server := &fakeRedisServer{listener: listener}
+	go server.accept()
+	return server
+}
+
+func (s *fakeRedisServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		go s.serve(conn)
+	}
+}
+
+// serve reads the commands, which are arrays of bulk strings, and answers them
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		args, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+		for i := 0; i < args*2; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+		}
+
+		if _, err := conn.Write([]byte("+PONG\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+// stop stops listening and closes the connections, as if the service was down
+func (s *fakeRedisServer) stop() {
+	s.listener.Close()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func receiveState(t *testing.T, started chan bool) bool {
+	select {
+	case state := <-started:
+		return state
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for redis state")
+		return false
+	}
+}
+
+func TestRedisInvalidURL(t *testing.T) {
+	r := NewRedis("invalid://localhost", time.Second, &mocks.FakeLogger{})
+	started := make(chan bool, 1)
+	r.Start(started)
+
+	assert.False(t, <-started)
+	assert.False(t, r.Available())
+	assert.Equal(t, RedisUnavailable, r.State())
+}
+
+func TestRedisStopTwice(t *testing.T) {
+	r := NewRedis("redis://127.0.0.1:0/0", time.Second, &mocks.FakeLogger{})
+
+	assert.NotPanics(t, func() {
+		r.Stop()
+		r.Stop()
+	})
+}
+
+func TestRedisHealthChecks(t *testing.T) {
+	server := startFakeRedisServer(t, "127.0.0.1:0")
+	addr := server.listener.Addr().String()
+	r := NewRedis("redis://"+addr+"/0", 50*time.Millisecond, &mocks.FakeLogger{})
+	started := make(chan bool, 1)
+	go r.Start(started)
+	defer r.Stop()
+
+	assert.True(t, receiveState(t, started))
+	assert.Equal(t, RedisAvailable, r.State())
+
+	server.stop()
+	assert.False(t, receiveState(t, started))
+	_, err := r.Get(context.Background(), "key")
+	assert.Equal(t, ErrRedisUnavailable, err)
+	assert.Equal(t, ErrRedisUnavailable, r.Set(context.Background(), "key", "value", time.Minute))
+
+	server = startFakeRedisServer(t, addr)
+	defer server.stop()
+	assert.True(t, receiveState(t, started))
+	assert.True(t, r.Available())
+}
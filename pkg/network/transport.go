@@ -0,0 +1,34 @@
+package network
+
+import "errors"
+
+// ErrUnsupported is returned when the transport doesn't support an operation, e.g. MQTT
+// doesn't report the messages not delivered to any subscriber
+var ErrUnsupported = errors.New("operation not supported by the transport")
+
+// Transport abstracts the messaging broker used to exchange the events described in
+// docs/events.md with the clients. Messages are published to an exchange and routing
+// key and received from a queue bound to them. Request/reply commands are answered by
+// publishing to the `ReplyTo` key received in the request with its correlation ID.
+type Transport interface {
+	// Start connects to the broker and informs through the channel every time the
+	// connection is established (true) or lost (false).
+	Start(started chan bool)
+
+	// Stop closes the connection with the broker
+	Stop()
+
+	// PublishPersistentMessage sends a message and returns an error if the broker
+	// doesn't confirm it was received.
+	PublishPersistentMessage(exchange, exchangeType, key string, msg MessageSerializer, options *MessageOptions) error
+
+	// OnMessage subscribes to the messages routed to the queue through the exchange
+	// and binding key, which are sent to the channel. Subscriptions are restored when
+	// the connection is reestablished.
+	OnMessage(msgChan chan InMsg, queueName, exchangeName, exchangeType, key string) error
+
+	// DeleteExchange deletes an exchange that is no longer used. When ifUnused is true,
+	// the exchange isn't deleted while a queue bound to it has consumers and
+	// ErrExchangeInUse is returned. Deleting an exchange that doesn't exist succeeds.
+	DeleteExchange(name string, ifUnused bool) error
+}
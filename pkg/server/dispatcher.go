@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+)
+
+// DispatcherStats represents the message processing metrics, which allow to verify if
+// the workers are keeping up with the messages received.
+// `Queued` is the number of messages waiting for a worker.
+// `Blocked` is the number of times a message had to wait for a full worker queue.
+// `BlockedTime` is the total time spent waiting for full worker queues.
+type DispatcherStats struct {
+	Workers     int           `json:"workers"`
+	QueueSize   int           `json:"queueSize"`
+	Queued      int           `json:"queued"`
+	InFlight    int64         `json:"inFlight"`
+	Processed   uint64        `json:"processed"`
+	Failed      uint64        `json:"failed"`
+	Blocked     uint64        `json:"blocked"`
+	BlockedTime time.Duration `json:"blockedTime"`
+}
+
+// dispatcher distributes the messages among a pool of workers. Messages of the same
+// thing are always sent to the same worker, so they are handled in the order they
+// were received, while messages of different things are handled in parallel. The bulk
+// messages refer to many things, which may belong to different workers, so they aren't
+// ordered with the other messages of their things.
+type dispatcher struct {
+	queues      []chan network.InMsg
+	handle      func(network.InMsg) error
+	next        uint64
+	inFlight    int64
+	processed   uint64
+	failed      uint64
+	blocked     uint64
+	blockedTime int64
+}
+
+// thingMessage represents the property shared by the messages related to a thing
+type thingMessage struct {
+	ID string `json:"id"`
+}
+
+func newDispatcher(workers, queueSize int, handle func(network.InMsg) error) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+
+	queues := make([]chan network.InMsg, workers)
+	for i := range queues {
+		queues[i] = make(chan network.InMsg, queueSize)
+	}
+
+	return &dispatcher{queues: queues, handle: handle}
+}
+
+// start starts the workers, which run until their queues are closed
+func (d *dispatcher) start() {
+	for _, queue := range d.queues {
+		go d.work(queue)
+	}
+}
+
+// stop closes the workers queues, the messages already queued are still handled
+func (d *dispatcher) stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+}
+
+// dispatch sends the message to its worker queue and blocks while the queue is full,
+// which holds the delivery of new messages by the broker until the workers catch up.
+func (d *dispatcher) dispatch(msg network.InMsg) {
+	queue := d.queues[d.workerIndex(msg)]
+	select {
+	case queue <- msg:
+		return
+	default:
+	}
+
+	start := time.Now()
+	queue <- msg
+	atomic.AddUint64(&d.blocked, 1)
+	atomic.AddInt64(&d.blockedTime, int64(time.Since(start)))
+}
+
+func (d *dispatcher) work(queue chan network.InMsg) {
+	for msg := range queue {
+		atomic.AddInt64(&d.inFlight, 1)
+		err := d.handle(msg)
+		atomic.AddInt64(&d.inFlight, -1)
+		atomic.AddUint64(&d.processed, 1)
+		if err != nil {
+			atomic.AddUint64(&d.failed, 1)
+		}
+	}
+}
+
+// workerIndex selects the worker by hashing the thing's ID. Messages not related to
+// a specific thing, such as the list devices command and the bulk commands, which have
+// no top-level ID, are distributed in turns.
+func (d *dispatcher) workerIndex(msg network.InMsg) int {
+	var tm thingMessage
+	err := json.Unmarshal(msg.Body, &tm)
+	if err != nil || tm.ID == "" {
+		return int(atomic.AddUint64(&d.next, 1) % uint64(len(d.queues)))
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(tm.ID))
+	return int(hash.Sum32() % uint32(len(d.queues)))
+}
+
+func (d *dispatcher) stats() DispatcherStats {
+	queued := 0
+	for _, queue := range d.queues {
+		queued += len(queue)
+	}
+
+	return DispatcherStats{
+		Workers:     len(d.queues),
+		QueueSize:   cap(d.queues[0]),
+		Queued:      queued,
+		InFlight:    atomic.LoadInt64(&d.inFlight),
+		Processed:   atomic.LoadUint64(&d.processed),
+		Failed:      atomic.LoadUint64(&d.failed),
+		Blocked:     atomic.LoadUint64(&d.blocked),
+		BlockedTime: time.Duration(atomic.LoadInt64(&d.blockedTime)),
+	}
+}
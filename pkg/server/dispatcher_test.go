@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func newThingMsg(thingID string, seq int) network.InMsg {
+	body := fmt.Sprintf(`{"id": "%s", "seq": %d}`, thingID, seq)
+	return network.InMsg{Body: []byte(body)}
+}
+
+func TestDispatcherKeepsThingOrder(t *testing.T) {
+	things := []string{"fc3fcf912d0c290a", "8a6f2fe9da74485f", "3aa21010cda96fe9", "fbe64efa6c7f717e"}
+	msgsPerThing := 50
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	received := map[string][]int{}
+	wg.Add(len(things) * msgsPerThing)
+
+	d := newDispatcher(3, 2, func(msg network.InMsg) error {
+		defer wg.Done()
+		var tm struct {
+			ID  string `json:"id"`
+			Seq int    `json:"seq"`
+		}
+		assert.NoError(t, json.Unmarshal(msg.Body, &tm))
+
+		mutex.Lock()
+		received[tm.ID] = append(received[tm.ID], tm.Seq)
+		mutex.Unlock()
+		return nil
+	})
+	d.start()
+	defer d.stop()
+
+	for seq := 0; seq < msgsPerThing; seq++ {
+		for _, id := range things {
+			d.dispatch(newThingMsg(id, seq))
+		}
+	}
+	wg.Wait()
+
+	for _, id := range things {
+		assert.Len(t, received[id], msgsPerThing)
+		for i, seq := range received[id] {
+			assert.Equal(t, i, seq, "message of thing %s out of order", id)
+		}
+	}
+
+	stats := d.stats()
+	assert.Equal(t, uint64(len(things)*msgsPerThing), stats.Processed)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestDispatcherHandlesThingsInParallel(t *testing.T) {
+	release := make(chan bool)
+	handled := make(chan string, 2)
+
+	d := newDispatcher(2, 1, func(msg network.InMsg) error {
+		var tm thingMessage
+		_ = json.Unmarshal(msg.Body, &tm)
+		if tm.ID == "slow" {
+			<-release
+		}
+		handled <- tm.ID
+		return nil
+	})
+	d.start()
+	defer d.stop()
+
+	slow, fast := newThingMsg("slow", 0), newThingMsg("fast", 0)
+	if d.workerIndex(slow) == d.workerIndex(fast) {
+		t.Fatal("things must be assigned to different workers")
+	}
+
+	d.dispatch(slow)
+	d.dispatch(fast)
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, "fast", id)
+	case <-time.After(time.Second):
+		t.Error("message blocked by another thing's slow message")
+	}
+	close(release)
+	assert.Equal(t, "slow", <-handled)
+}
@@ -1,13 +1,24 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/controllers"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/interactors"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/segmentio/ksuid"
 )
 
+// retryInterval is the interval before retrying a failed message for the first time,
+// which grows exponentially
+const retryInterval = 100 * time.Millisecond
+
 // API definition to enable receiving request-reply commands from the clients
 // The operations supported for this type of events are device authentication
 // and list registered devices, as can be seen on the documentation:
@@ -23,23 +34,63 @@ const (
 	bindingKeyListDevices      = "device.list"
 	bindingKeyRegisterDevice   = "device.register"
 	bindingKeyUnregisterDevice = "device.unregister"
+	bindingKeyRegisterBulk     = "device.register.bulk"
+	bindingKeyUnregisterBulk   = "device.unregister.bulk"
 	bindingKeyRequestData      = "data.request"
 	bindingKeyUpdateData       = "data.update"
 	bindingKeySchemaSent       = "device.schema.sent"
 	bindingKeyConfigSent       = "device.config.sent"
+	bindingKeyUpdateMetadata   = "device.metadata.update"
+	bindingKeyUpdateDevice     = "device.update"
+	bindingKeyAckCommand       = "command.ack"
+	bindingKeyCommandStatus    = "command.status"
+	bindingKeyListCommands     = "command.list"
+	bindingKeyCancelCommand    = "command.cancel"
 	bindingKeyEmpty            = ""
 )
 
 // MsgHandler handle messages received from a service
 type MsgHandler struct {
 	logger          logging.Logger
-	amqp            *network.Amqp
+	transport       network.Transport
 	thingController *controllers.ThingController
+	dispatcher      *dispatcher
+	maxRetries      int
+	statsInterval   time.Duration
+	handleTimeout   time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+	quit            chan bool
+	stopOnce        sync.Once
 }
 
-// NewMsgHandler creates a new MsgHandler instance with the necessary dependencies
-func NewMsgHandler(logger logging.Logger, amqp *network.Amqp, thingController *controllers.ThingController) *MsgHandler {
-	return &MsgHandler{logger, amqp, thingController}
+// NewMsgHandler creates a new MsgHandler instance with the necessary dependencies.
+// The messages are handled by a pool of workers, each one with a queue of queueSize
+// messages. A message that fails is retried by its worker up to maxRetries times before
+// being dead-lettered. The processing stats are logged every statsInterval. Each attempt
+// must be handled within handleTimeout, and the messages in flight are canceled when the
+// handler stops.
+func NewMsgHandler(
+	logger logging.Logger,
+	transport network.Transport,
+	thingController *controllers.ThingController,
+	workers, queueSize, maxRetries int,
+	statsInterval, handleTimeout time.Duration,
+) *MsgHandler {
+	ctx, cancel := context.WithCancel(context.Background())
+	mc := &MsgHandler{
+		logger:          logger,
+		transport:       transport,
+		thingController: thingController,
+		maxRetries:      maxRetries,
+		statsInterval:   statsInterval,
+		handleTimeout:   handleTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+		quit:            make(chan bool),
+	}
+	mc.dispatcher = newDispatcher(workers, queueSize, mc.handleMsg)
+	return mc
 }
 
 // Start starts to listen messages
@@ -53,14 +104,45 @@ func (mc *MsgHandler) Start(started chan bool) {
 		return
 	}
 
+	mc.dispatcher.start()
 	go mc.onMsgReceived(msgChan)
+	if mc.statsInterval > 0 {
+		go mc.logStats()
+	}
 
 	started <- true
 }
 
-// Stop stops to listen for messages
+// Stop stops to listen for messages, it can be called more than once
 func (mc *MsgHandler) Stop() {
-	mc.logger.Debug("message handler stopped")
+	mc.stopOnce.Do(func() {
+		close(mc.quit)
+		mc.cancel()
+		mc.logger.Debug("message handler stopped")
+	})
+}
+
+// Stats returns the current message processing stats
+func (mc *MsgHandler) Stats() DispatcherStats {
+	return mc.dispatcher.stats()
+}
+
+func (mc *MsgHandler) logStats() {
+	ticker := time.NewTicker(mc.statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s := mc.Stats()
+			mc.logger.Infof(
+				"messages processed: %d, failed: %d, in flight: %d, queued: %d, blocked: %d (%s)",
+				s.Processed, s.Failed, s.InFlight, s.Queued, s.Blocked, s.BlockedTime,
+			)
+		case <-mc.quit:
+			return
+		}
+	}
 }
 
 func (mc *MsgHandler) subscribeToMessages(msgChan chan network.InMsg) error {
@@ -69,20 +151,28 @@ func (mc *MsgHandler) subscribeToMessages(msgChan chan network.InMsg) error {
 		if err != nil {
 			return
 		}
-		err = mc.amqp.OnMessage(msgChan, queue, exchange, kind, key)
+		err = mc.transport.OnMessage(msgChan, queue, exchange, kind, key)
 	}
 
 	// Subscribe to general direct commands
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyRegisterDevice)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyUnregisterDevice)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyRegisterBulk)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyUnregisterBulk)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyRequestData)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyUpdateData)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeySchemaSent)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyConfigSent)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyUpdateMetadata)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyUpdateDevice)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyAckCommand)
 
 	// Subscribe to request-reply messages received from any client
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyAuthDevice)
 	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyListDevices)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyCommandStatus)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyListCommands)
+	subscribe(msgChan, queueNameCommands, exchangeDevices, exchangeDevicesType, bindingKeyCancelCommand)
 
 	// Subscribe to broadcasted data events
 	subscribe(msgChan, queueNameEvents, exchangeDataSent, exchangeDataSentType, bindingKeyEmpty)
@@ -92,64 +182,205 @@ func (mc *MsgHandler) subscribeToMessages(msgChan chan network.InMsg) error {
 
 func (mc *MsgHandler) onMsgReceived(msgChan chan network.InMsg) {
 	for {
-		var err error
-		msg := <-msgChan
-		mc.logger.Infof("exchange: %s, routing key: %s", msg.Exchange, msg.RoutingKey)
-		mc.logger.Infof("message received: %s", string(msg.Body))
-
-		token, ok := msg.Headers["Authorization"].(string)
-		if !ok {
-			mc.logger.Error(errors.New("authorization token not provided"))
-			continue
+		select {
+		case msg := <-msgChan:
+			mc.logger.Infof("exchange: %s, routing key: %s", msg.Exchange, msg.RoutingKey)
+			mc.logger.Infof("message received: %s", string(msg.Body))
+			mc.dispatcher.dispatch(msg)
+		case <-mc.quit:
+			mc.dispatcher.stop()
+			return
 		}
+	}
+}
 
-		if msg.RoutingKey == bindingKeyAuthDevice || msg.RoutingKey == bindingKeyListDevices {
-			// handling request-reply command messages, which requires specific validations such as if reply_to was correctly received
-			err = mc.handleRequestReplyCommands(msg, token)
-		} else if msg.Exchange == exchangeDataSent {
-			// handling broadcasted data events
-			err = mc.handleBroadcastedData(msg, token)
-		} else {
-			// handling general direct commands
-			err = mc.handleClientMessages(msg, token)
+// handleMsg runs in the dispatcher workers and acknowledges the message after handling it
+func (mc *MsgHandler) handleMsg(msg network.InMsg) error {
+	var err error
+
+	token, ok := msg.Headers["Authorization"].(string)
+	if !ok {
+		err = errors.New("authorization token not provided")
+		mc.logger.Error(err)
+		mc.reject(msg)
+		return err
+	}
+
+	err = mc.handleWithRetries(msg, token)
+	if err != nil {
+		mc.logger.Error(err)
+		mc.handleFailure(msg, err)
+		return err
+	}
+
+	mc.ack(msg)
+	return nil
+}
+
+// handleWithRetries retries the message in the worker while it fails transiently, so the
+// later messages of the same thing wait for it and are still handled in order. Only the
+// final outcome is sent to the clients, the failures of the attempts that are retried aren't.
+func (mc *MsgHandler) handleWithRetries(msg network.InMsg, token string) error {
+	retryPolicy := backoff.NewExponentialBackOff()
+	retryPolicy.InitialInterval = retryInterval
+	for attempt := 0; ; attempt++ {
+		err := mc.handle(msg, token, attempt < mc.maxRetries)
+		if err == nil || !isTransient(err) || attempt == mc.maxRetries {
+			return err
 		}
 
-		if err != nil {
-			mc.logger.Error(err)
-			continue
+		wait := retryPolicy.NextBackOff()
+		mc.logger.Infof("retrying message %s in %s: %s", msg.RoutingKey, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-mc.ctx.Done():
+			return err
 		}
 	}
 }
 
-func (mc *MsgHandler) handleClientMessages(msg network.InMsg, token string) error {
+func (mc *MsgHandler) handle(msg network.InMsg, token string, retry bool) error {
+	ctx, cancel := mc.newMsgContext(msg)
+	defer cancel()
+	if retry {
+		ctx = interactors.WithRetry(ctx)
+	}
+
+	if isRequestReplyCommand(msg.RoutingKey) {
+		// handling request-reply command messages, which requires specific validations such as if reply_to was correctly received
+		return mc.handleRequestReplyCommands(ctx, msg, token)
+	} else if msg.Exchange == exchangeDataSent {
+		// handling broadcasted data events
+		return mc.handleBroadcastedData(ctx, msg, token)
+	}
+
+	// handling general direct commands
+	return mc.handleClientMessages(ctx, msg, token)
+}
+
+// handleFailure settles a message that failed to be handled. The malformed messages and
+// the ones that kept failing after being retried are dead-lettered. The invalid requests,
+// whose error was already sent to the clients, are acknowledged, so they aren't handled and
+// answered again. The messages interrupted by the handler stopping are sent back to the
+// broker, to be handled when the service is started again.
+func (mc *MsgHandler) handleFailure(msg network.InMsg, err error) {
+	switch {
+	case interactors.IsInvalidRequest(err):
+		mc.ack(msg)
+	case isTransient(err) && mc.ctx.Err() != nil:
+		mc.nack(msg)
+	default:
+		mc.reject(msg)
+	}
+}
+
+// newMsgContext creates the context of the message handling, which carries the message's
+// correlation ID, or a new one when the message doesn't have it, to be sent upstream.
+func (mc *MsgHandler) newMsgContext(msg network.InMsg) (context.Context, context.CancelFunc) {
+	correlationID := msg.CorrelationID
+	if correlationID == "" {
+		correlationID = ksuid.New().String()
+	}
+	ctx := network.WithCorrelationID(mc.ctx, correlationID)
+
+	if mc.handleTimeout > 0 {
+		return context.WithTimeout(ctx, mc.handleTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ack acknowledges a message only after it was successfully handled, so messages in
+// flight aren't lost if the service stops before finishing them.
+func (mc *MsgHandler) ack(msg network.InMsg) {
+	err := msg.Ack()
+	if err != nil {
+		mc.logger.Errorf("error acknowledging message: %s", err)
+	}
+}
 
+func (mc *MsgHandler) nack(msg network.InMsg) {
+	err := msg.Nack()
+	if err != nil {
+		mc.logger.Errorf("error sending message to be retried: %s", err)
+	}
+}
+
+func (mc *MsgHandler) reject(msg network.InMsg) {
+	err := msg.Reject()
+	if err != nil {
+		mc.logger.Errorf("error rejecting message: %s", err)
+	}
+}
+
+// handleClientMessages handles the direct commands. The register, unregister, bulk, update,
+// config and metadata commands are also replied to the requestor when the reply-to is received.
+func (mc *MsgHandler) handleClientMessages(ctx context.Context, msg network.InMsg, token string) error {
 	switch msg.RoutingKey {
 	case bindingKeyRegisterDevice:
-		return mc.thingController.Register(msg.Body, token)
+		return mc.thingController.Register(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
 	case bindingKeyUnregisterDevice:
-		return mc.thingController.Unregister(msg.Body, token)
+		return mc.thingController.Unregister(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyRegisterBulk:
+		return mc.thingController.RegisterBulk(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyUnregisterBulk:
+		return mc.thingController.UnregisterBulk(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
 	case bindingKeyConfigSent:
-		return mc.thingController.UpdateConfig(msg.Body, token)
+		return mc.thingController.UpdateConfig(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyUpdateMetadata:
+		return mc.thingController.UpdateMetadata(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyUpdateDevice:
+		return mc.thingController.UpdateDevice(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
 	case bindingKeyRequestData:
-		return mc.thingController.RequestData(msg.Body, token)
+		return mc.thingController.RequestData(ctx, msg.Body, token)
 	case bindingKeyUpdateData:
-		return mc.thingController.UpdateData(msg.Body, token)
+		return mc.thingController.UpdateData(ctx, msg.Body, token)
+	case bindingKeyAckCommand:
+		return mc.thingController.AckCommand(ctx, msg.Body, token)
 	}
 
 	return nil
 }
 
-func (mc *MsgHandler) handleRequestReplyCommands(msg network.InMsg, token string) error {
+func (mc *MsgHandler) handleRequestReplyCommands(ctx context.Context, msg network.InMsg, token string) error {
 	switch msg.RoutingKey {
 	case bindingKeyAuthDevice:
-		return mc.thingController.AuthDevice(msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+		return mc.thingController.AuthDevice(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
 	case bindingKeyListDevices:
-		return mc.thingController.ListDevices(token, msg.ReplyTo, msg.CorrelationID)
+		return mc.thingController.ListDevices(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyCommandStatus:
+		return mc.thingController.CommandStatus(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyListCommands:
+		return mc.thingController.ListCommands(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
+	case bindingKeyCancelCommand:
+		return mc.thingController.CancelCommand(ctx, msg.Body, token, msg.ReplyTo, msg.CorrelationID)
 	}
 
 	return nil
 }
 
-func (mc *MsgHandler) handleBroadcastedData(msg network.InMsg, token string) error {
-	return mc.thingController.PublishData(msg.Body, token)
+// isMalformed verifies if the error comes from decoding the message body
+func isMalformed(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// isTransient verifies if the error comes from the broker or the upstream services, so
+// handling the message again may succeed
+func isTransient(err error) bool {
+	return !isMalformed(err) && !interactors.IsInvalidRequest(err)
+}
+
+func isRequestReplyCommand(routingKey string) bool {
+	switch routingKey {
+	case bindingKeyAuthDevice, bindingKeyListDevices, bindingKeyCommandStatus, bindingKeyListCommands, bindingKeyCancelCommand:
+		return true
+	}
+
+	return false
+}
+
+func (mc *MsgHandler) handleBroadcastedData(ctx context.Context, msg network.InMsg, token string) error {
+	return mc.thingController.PublishData(ctx, msg.Body, token)
 }
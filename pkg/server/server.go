@@ -4,33 +4,58 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "github.com/CESARBR/knot-babeltower/docs" // This blank import is needed in order to documentation be provided by the server
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	thingControllers "github.com/CESARBR/knot-babeltower/pkg/thing/controllers"
 	"github.com/CESARBR/knot-babeltower/pkg/user/controllers"
 
 	"github.com/gorilla/mux"
+	"github.com/segmentio/ksuid"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	port           int
-	logger         logging.Logger
-	userController *controllers.UserController
-	srv            *http.Server
+	port            int
+	logger          logging.Logger
+	userController  *controllers.UserController
+	thingController *thingControllers.ThingController
+	upstreams       []Upstream
+	srv             *http.Server
+	ctx             context.Context
+	cancel          context.CancelFunc
 }
 
-// Health represents the service's health status
+// Upstream represents an upstream service whose circuit breaker or health state is reported
+// in the health status
+type Upstream interface {
+	Name() string
+	State() string
+}
+
+// Health represents the service's health status. The status is degraded when the circuit
+// breaker of an upstream service is open or it's unavailable.
 type Health struct {
-	Status string `json:"status"`
+	Status    string            `json:"status"`
+	Upstreams map[string]string `json:"upstreams,omitempty"`
 }
 
-// NewServer creates a new server instance
-func NewServer(port int, logger logging.Logger, userController *controllers.UserController) Server {
-	return Server{port, logger, userController, nil}
+// NewServer creates a new server instance. The requests in flight are canceled when the
+// server stops.
+func NewServer(
+	port int,
+	logger logging.Logger,
+	userController *controllers.UserController,
+	thingController *thingControllers.ThingController,
+	upstreams []Upstream,
+) Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return Server{port, logger, userController, thingController, upstreams, nil, ctx, cancel}
 }
 
 // Start starts the http server
@@ -38,7 +63,11 @@ func (s *Server) Start(started chan bool) {
 	routers := s.createRouters()
 	s.logger.Infof("listening on %d", s.port)
 	started <- true
-	s.srv = &http.Server{Addr: fmt.Sprintf(":%d", s.port), Handler: s.logRequest(routers)}
+	s.srv = &http.Server{
+		Addr:        fmt.Sprintf(":%d", s.port),
+		Handler:     s.logRequest(routers),
+		BaseContext: func(net.Listener) context.Context { return s.ctx },
+	}
 	err := s.srv.ListenAndServe()
 	if err != nil {
 		s.logger.Error(err)
@@ -48,6 +77,7 @@ func (s *Server) Start(started chan bool) {
 
 // Stop stops the server
 func (s *Server) Stop() {
+	s.cancel()
 	err := s.srv.Shutdown(context.TODO())
 	if err != nil {
 		s.logger.Error(err)
@@ -69,6 +99,11 @@ func (s *Server) createRouters() *mux.Router {
 	r.HandleFunc("/users", s.userController.Create).Methods("POST")
 	r.HandleFunc("/tokens", s.userController.CreateToken).Methods("POST")
 	r.HandleFunc("/sessions", s.userController.CreateSession).Methods("POST")
+	r.HandleFunc("/sessions", s.userController.ListSessions).Methods("GET")
+	r.HandleFunc("/sessions/{id}", s.userController.RevokeSession).Methods("DELETE")
+	r.HandleFunc("/sessions/{id}", s.userController.UpdateSession).Methods("PATCH")
+	r.HandleFunc("/sessions/{id}/keepalive", s.userController.KeepAliveSession).Methods("POST")
+	r.HandleFunc("/things/{id}", s.thingController.UpdateThing).Methods("PATCH")
 	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	)).Methods("GET")
@@ -83,16 +118,37 @@ func (s *Server) createRouters() *mux.Router {
 func (s *Server) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	response, _ := json.Marshal(&Health{Status: "online"})
+	response, _ := json.Marshal(s.health())
 	_, err := w.Write(response)
 	if err != nil {
 		s.logger.Errorf("error sending response, %s\n", err)
 	}
 }
 
+func (s *Server) health() *Health {
+	health := &Health{Status: "online", Upstreams: map[string]string{}}
+	for _, upstream := range s.upstreams {
+		state := upstream.State()
+		if state == network.BreakerOpen || state == network.RedisUnavailable {
+			health.Status = "degraded"
+		}
+		health.Upstreams[upstream.Name()] = state
+	}
+
+	return health
+}
+
+// logRequest logs the request and adds its correlation ID to the request's context. The
+// ID is received in the X-Correlation-ID header or generated when it isn't.
 func (s *Server) logRequest(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s.logger.Infof("%s %s %s\n", r.RemoteAddr, r.Method, r.URL)
-		handler.ServeHTTP(w, r)
+		correlationID := r.Header.Get(network.CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = ksuid.New().String()
+		}
+		w.Header().Set(network.CorrelationIDHeader, correlationID)
+
+		s.logger.Infof("%s %s %s %s\n", r.RemoteAddr, r.Method, r.URL, correlationID)
+		handler.ServeHTTP(w, r.WithContext(network.WithCorrelationID(r.Context(), correlationID)))
 	})
 }
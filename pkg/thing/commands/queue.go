@@ -0,0 +1,256 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// Replacement policies applied when a command is queued for a thing that already has
+// commands queued.
+const (
+	// PolicyKeepAll keeps every command until it is delivered or expires
+	PolicyKeepAll = "all"
+	// PolicyLatest keeps only the latest command for each sensor, e.g. only the last
+	// value is written to an actuator that received many updates while offline.
+	PolicyLatest = "latest"
+)
+
+// Queue keeps the commands to things that are offline until they come back. The
+// commands are delivered in the order they were queued, and the ones that expire
+// before that are published as timed out.
+type Queue interface {
+	// Enqueue adds a command to the thing's queue, applying the replacement policy
+	Enqueue(ctx context.Context, command entities.Command) error
+
+	// Flush removes and returns the commands queued to the thing
+	Flush(ctx context.Context, thingID string) ([]entities.Command, error)
+
+	// List returns the commands queued to the thing
+	List(ctx context.Context, thingID string) ([]entities.Command, error)
+
+	// Get returns a queued command
+	Get(ctx context.Context, commandID string) (*entities.Command, error)
+
+	// Cancel removes a command from the thing's queue
+	Cancel(ctx context.Context, thingID, commandID string) error
+}
+
+// queueConfig holds the settings shared by the queue backends
+type queueConfig struct {
+	ttl    time.Duration
+	size   int
+	policy string
+}
+
+type queue struct {
+	queueConfig
+	logger    logging.Logger
+	publisher amqp.Publisher
+	mutex     sync.Mutex
+	things    map[string][]entities.Command
+	timers    map[string]*time.Timer
+}
+
+// NewQueue creates a new Queue instance that keeps the commands in memory, so it's only
+// suitable for a single babeltower instance. The queued commands expire after ttl and
+// each thing can have at most size commands queued.
+func NewQueue(logger logging.Logger, publisher amqp.Publisher, ttl time.Duration, size int, policy string) Queue {
+	return &queue{
+		queueConfig: queueConfig{ttl, size, policy},
+		logger:      logger,
+		publisher:   publisher,
+		things:      map[string][]entities.Command{},
+		timers:      map[string]*time.Timer{},
+	}
+}
+
+// Enqueue adds a command to the thing's queue, applying the replacement policy
+func (q *queue) Enqueue(ctx context.Context, command entities.Command) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	queued, replaced, err := q.add(q.things[command.ThingID], command)
+	if err != nil {
+		return err
+	}
+	for _, r := range replaced {
+		q.logger.Infof("queued command %s replaced by %s", r.ID, command.ID)
+		q.timers[r.ID].Stop()
+		delete(q.timers, r.ID)
+	}
+
+	command = queued[len(queued)-1]
+	q.timers[command.ID] = time.AfterFunc(time.Until(*command.ExpiresAt), func() { q.expire(command.ThingID, command.ID) })
+	q.things[command.ThingID] = queued
+
+	return nil
+}
+
+// Flush removes and returns the commands queued to the thing
+func (q *queue) Flush(ctx context.Context, thingID string) ([]entities.Command, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	commands := append([]entities.Command{}, q.things[thingID]...)
+	delete(q.things, thingID)
+	for _, command := range commands {
+		q.timers[command.ID].Stop()
+		delete(q.timers, command.ID)
+	}
+
+	return commands, nil
+}
+
+// List returns the commands queued to the thing
+func (q *queue) List(ctx context.Context, thingID string) ([]entities.Command, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return append([]entities.Command{}, q.things[thingID]...), nil
+}
+
+// Get returns a queued command
+func (q *queue) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, queued := range q.things {
+		for _, command := range queued {
+			if command.ID == commandID {
+				return &command, nil
+			}
+		}
+	}
+
+	return nil, entities.ErrCommandNotFound
+}
+
+// Cancel removes a command from the thing's queue
+func (q *queue) Cancel(ctx context.Context, thingID, commandID string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	queued, _, found := remove(q.things[thingID], commandID)
+	if !found {
+		return entities.ErrCommandNotFound
+	}
+
+	q.update(thingID, queued)
+	q.timers[commandID].Stop()
+	delete(q.timers, commandID)
+	return nil
+}
+
+func (q *queue) expire(thingID, commandID string) {
+	q.mutex.Lock()
+	queued, command, found := remove(q.things[thingID], commandID)
+	if found {
+		q.update(thingID, queued)
+		delete(q.timers, commandID)
+	}
+	q.mutex.Unlock()
+	if !found {
+		return
+	}
+
+	command.Status = entities.CommandTimeout
+	command.UpdatedAt = time.Now()
+	err := q.publisher.PublishCommandTimeout(command)
+	if err != nil {
+		q.logger.Errorf("error publishing command %s timeout: %s", command.ID, err)
+	}
+}
+
+// update replaces the thing's queue, which is removed when empty. It must be called with
+// the mutex locked.
+func (q *queue) update(thingID string, queued []entities.Command) {
+	if len(queued) == 0 {
+		delete(q.things, thingID)
+		return
+	}
+	q.things[thingID] = queued
+}
+
+// add returns the thing's queued commands with the command added, and the commands it
+// replaced, according to the replacement policy. The queued commands aren't changed, so
+// the result can be discarded.
+func (c queueConfig) add(queued []entities.Command, command entities.Command) (updated, replaced []entities.Command, err error) {
+	kept := queued
+	if c.policy == PolicyLatest {
+		kept, replaced = replace(queued, command)
+	}
+
+	// the queue is only changed when the command fits, so nothing is lost when it's full
+	if len(kept) >= c.size {
+		return nil, nil, entities.ErrCommandQueueFull
+	}
+
+	// commands queued again keep their original expiration
+	now := time.Now()
+	if command.ExpiresAt == nil {
+		expiresAt := now.Add(c.ttl)
+		command.ExpiresAt = &expiresAt
+	}
+	command.Status = entities.CommandQueued
+	command.UpdatedAt = now
+
+	return append(kept[:len(kept):len(kept)], command), replaced, nil
+}
+
+// replace returns the queued commands without the sensors of the new command in the
+// commands of the same kind, and the commands left without sensors, which are dropped
+// from them. The queued commands aren't changed.
+func replace(queued []entities.Command, command entities.Command) (kept, replaced []entities.Command) {
+	sensors := map[int]bool{}
+	for _, id := range command.SensorIDs {
+		sensors[id] = true
+	}
+
+	for _, qc := range queued {
+		if qc.Kind == command.Kind {
+			qc.SensorIDs, qc.Data = withoutSensors(qc, sensors)
+			if len(qc.SensorIDs) == 0 {
+				replaced = append(replaced, qc)
+				continue
+			}
+		}
+		kept = append(kept, qc)
+	}
+
+	return kept, replaced
+}
+
+// remove returns the queued commands without the command, which is also returned, and
+// if it was found. The queued commands aren't changed.
+func remove(queued []entities.Command, commandID string) ([]entities.Command, entities.Command, bool) {
+	for i, command := range queued {
+		if command.ID == commandID {
+			return append(queued[:i:i], queued[i+1:]...), command, true
+		}
+	}
+
+	return queued, entities.Command{}, false
+}
+
+func withoutSensors(command entities.Command, sensors map[int]bool) ([]int, []entities.Data) {
+	var sensorIDs []int
+	for _, id := range command.SensorIDs {
+		if !sensors[id] {
+			sensorIDs = append(sensorIDs, id)
+		}
+	}
+
+	var data []entities.Data
+	for _, d := range command.Data {
+		if !sensors[d.SensorID] {
+			data = append(data, d)
+		}
+	}
+
+	return sensorIDs, data
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newUpdateCommand(id string, data ...entities.Data) entities.Command {
+	command := entities.Command{ID: id, ThingID: thingID, Kind: entities.CommandUpdateData, Data: data}
+	for _, d := range data {
+		command.SensorIDs = append(command.SensorIDs, d.SensorID)
+	}
+
+	return command
+}
+
+type queueTestCase struct {
+	name        string
+	policy      string
+	commands    []entities.Command
+	expectedIDs []string
+}
+
+var queueCases = []queueTestCase{
+	{
+		"all commands kept",
+		PolicyKeepAll,
+		[]entities.Command{
+			newUpdateCommand("first", entities.Data{SensorID: 0, Value: true}),
+			newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}),
+		},
+		[]string{"first", "second"},
+	},
+	{
+		"latest command of each sensor kept",
+		PolicyLatest,
+		[]entities.Command{
+			newUpdateCommand("first", entities.Data{SensorID: 0, Value: true}, entities.Data{SensorID: 1, Value: true}),
+			newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}),
+			newUpdateCommand("third", entities.Data{SensorID: 1, Value: false}),
+		},
+		[]string{"second", "third"},
+	},
+	{
+		"commands of other kinds not replaced",
+		PolicyLatest,
+		[]entities.Command{
+			{ID: "first", ThingID: thingID, Kind: entities.CommandRequestData, SensorIDs: []int{0}},
+			newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}),
+		},
+		[]string{"first", "second"},
+	},
+}
+
+func TestQueue(t *testing.T) {
+	for _, tc := range queueCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 10, tc.policy)
+			for _, command := range tc.commands {
+				assert.NoError(t, queue.Enqueue(ctx, command))
+			}
+
+			queued, err := queue.List(ctx, thingID)
+			assert.NoError(t, err)
+			var ids []string
+			for _, command := range queued {
+				assert.Equal(t, entities.CommandQueued, command.Status)
+				ids = append(ids, command.ID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+
+			flushed, err := queue.Flush(ctx, thingID)
+			assert.NoError(t, err)
+			assert.Len(t, flushed, len(tc.expectedIDs))
+			queued, err = queue.List(ctx, thingID)
+			assert.NoError(t, err)
+			assert.Empty(t, queued)
+		})
+	}
+}
+
+func TestQueueReplacesSensorsOfQueuedCommand(t *testing.T) {
+	queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 10, PolicyLatest)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true}, entities.Data{SensorID: 1, Value: true})))
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("second", entities.Data{SensorID: 0, Value: false})))
+
+	command, err := queue.Get(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, command.SensorIDs)
+	assert.Equal(t, []entities.Data{{SensorID: 1, Value: true}}, command.Data)
+}
+
+func TestQueueRejectsCommandWhenFull(t *testing.T) {
+	queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 1, PolicyKeepAll)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	err := queue.Enqueue(ctx, newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}))
+	assert.True(t, errors.Is(err, entities.ErrCommandQueueFull))
+}
+
+func TestQueueKeepsCommandsWhenFullWithLatestPolicy(t *testing.T) {
+	queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 1, PolicyLatest)
+	first := newUpdateCommand("first", entities.Data{SensorID: 0, Value: true}, entities.Data{SensorID: 1, Value: true})
+	assert.NoError(t, queue.Enqueue(ctx, first))
+
+	err := queue.Enqueue(ctx, newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}))
+	assert.True(t, errors.Is(err, entities.ErrCommandQueueFull))
+	command, err := queue.Get(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, first.SensorIDs, command.SensorIDs)
+	assert.Equal(t, first.Data, command.Data)
+
+	// the command fits when it replaces every sensor of the queued one
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("third", entities.Data{SensorID: 0, Value: false}, entities.Data{SensorID: 1, Value: false})))
+	_, err = queue.Get(ctx, "first")
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+	_, err = queue.Get(ctx, "third")
+	assert.NoError(t, err)
+}
+
+func TestQueueCancelsCommand(t *testing.T) {
+	queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 10, PolicyKeepAll)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	err := queue.Cancel(ctx, "8a6f2fe9da74485f", "first")
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+	assert.NoError(t, queue.Cancel(ctx, thingID, "first"))
+	_, err = queue.Get(ctx, "first")
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+}
+
+func TestQueueTimesOutExpiredCommand(t *testing.T) {
+	published := make(chan entities.Command, 1)
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.
+		On("PublishCommandTimeout", mock.Anything).
+		Run(func(args mock.Arguments) { published <- args.Get(0).(entities.Command) }).
+		Return(nil).
+		Once()
+	queue := NewQueue(&mocks.FakeLogger{}, fakePublisher, 50*time.Millisecond, 10, PolicyKeepAll)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	select {
+	case command := <-published:
+		assert.Equal(t, "first", command.ID)
+		assert.Equal(t, entities.CommandTimeout, command.Status)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for command timeout")
+	}
+	queued, err := queue.List(ctx, thingID)
+	assert.NoError(t, err)
+	assert.Empty(t, queued)
+}
+
+func TestQueueKeepsExpirationOfRequeuedCommand(t *testing.T) {
+	queue := NewQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, 10, PolicyKeepAll)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+	flushed, err := queue.Flush(ctx, thingID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, queue.Enqueue(ctx, flushed[0]))
+	command, err := queue.Get(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, flushed[0].ExpiresAt, command.ExpiresAt)
+}
@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+const (
+	// commandQueueKeyPrefix prefixes the keys that store the commands queued to each thing
+	commandQueueKeyPrefix = "command-queue:"
+	// queuedCommandKeyPrefix prefixes the keys that map the queued commands to their thing
+	queuedCommandKeyPrefix = "queued-command:"
+	// queuedCommandsKey is the sorted set of the queued command IDs scored by the time they
+	// expire
+	queuedCommandsKey = "commands-queued"
+	// queueKeysTTLFactor multiplies the queue TTL to obtain the TTL of its keys, so the
+	// commands are still found when they expire, and the keys are removed by Redis when the
+	// commands are never delivered nor expired, e.g. when no instance is running.
+	queueKeysTTLFactor = 2
+)
+
+type redisQueue struct {
+	queueConfig
+	logger    logging.Logger
+	publisher amqp.Publisher
+	redis     *network.Redis
+	quit      chan struct{}
+}
+
+// NewRedisQueue creates a Queue that keeps the commands in Redis, so they are shared by
+// every babeltower instance and a command queued by one instance is delivered by the one
+// that finds the thing online. The thing's queue is changed atomically, and a command is
+// removed from it by a single instance, which delivers it or publishes it as timed out.
+// The expired commands are found by the instances checking them periodically with Start.
+func NewRedisQueue(logger logging.Logger, publisher amqp.Publisher, redis *network.Redis, ttl time.Duration, size int, policy string) *redisQueue {
+	return &redisQueue{queueConfig{ttl, size, policy}, logger, publisher, redis, make(chan struct{})}
+}
+
+// Start times out the expired commands on every interval until Stop is called
+func (q *redisQueue) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := q.expire(context.Background())
+			if err != nil {
+				q.logger.Errorf("error timing out queued commands: %s", err)
+			}
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// Stop stops timing out the expired commands
+func (q *redisQueue) Stop() {
+	close(q.quit)
+}
+
+// Enqueue adds a command to the thing's queue, applying the replacement policy. The
+// command is indexed before it's queued, so a queued command is always found.
+func (q *redisQueue) Enqueue(ctx context.Context, command entities.Command) error {
+	if command.ExpiresAt == nil {
+		expiresAt := time.Now().Add(q.ttl)
+		command.ExpiresAt = &expiresAt
+	}
+
+	err := q.redis.Set(ctx, queuedCommandKeyPrefix+command.ID, command.ThingID, q.keysTTL())
+	if err == nil {
+		err = q.redis.ZAdd(ctx, queuedCommandsKey, score(*command.ExpiresAt), command.ID)
+	}
+	if err != nil {
+		q.untrack(ctx, command.ID)
+		return err
+	}
+
+	var replaced []entities.Command
+	err = q.update(ctx, command.ThingID, func(queued []entities.Command) ([]entities.Command, error) {
+		var updated []entities.Command
+		var err error
+		updated, replaced, err = q.add(queued, command)
+		return updated, err
+	})
+	if err != nil {
+		q.untrack(ctx, command.ID)
+		return err
+	}
+
+	for _, r := range replaced {
+		q.logger.Infof("queued command %s replaced by %s", r.ID, command.ID)
+		q.untrack(ctx, r.ID)
+	}
+
+	return nil
+}
+
+// Flush removes and returns the commands queued to the thing
+func (q *redisQueue) Flush(ctx context.Context, thingID string) ([]entities.Command, error) {
+	var commands []entities.Command
+	err := q.update(ctx, thingID, func(queued []entities.Command) ([]entities.Command, error) {
+		commands = queued
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, command := range commands {
+		q.untrack(ctx, command.ID)
+	}
+
+	return append([]entities.Command{}, commands...), nil
+}
+
+// List returns the commands queued to the thing
+func (q *redisQueue) List(ctx context.Context, thingID string) ([]entities.Command, error) {
+	value, err := q.redis.Get(ctx, commandQueueKeyPrefix+thingID)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCommands(value)
+}
+
+// Get returns a queued command
+func (q *redisQueue) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	thingID, err := q.redis.Get(ctx, queuedCommandKeyPrefix+commandID)
+	if err != nil {
+		return nil, err
+	}
+	if thingID == "" {
+		return nil, entities.ErrCommandNotFound
+	}
+
+	queued, err := q.List(ctx, thingID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, command := range queued {
+		if command.ID == commandID {
+			return &command, nil
+		}
+	}
+
+	return nil, entities.ErrCommandNotFound
+}
+
+// Cancel removes a command from the thing's queue
+func (q *redisQueue) Cancel(ctx context.Context, thingID, commandID string) error {
+	_, err := q.take(ctx, thingID, commandID)
+	return err
+}
+
+// expire removes the expired commands from the queues and publishes them as timed out
+func (q *redisQueue) expire(ctx context.Context) error {
+	ids, err := q.redis.ZRangeByScore(ctx, queuedCommandsKey, score(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		thingID, err := q.redis.Get(ctx, queuedCommandKeyPrefix+id)
+		if err != nil {
+			return err
+		}
+
+		command, err := q.take(ctx, thingID, id)
+		if errors.Is(err, entities.ErrCommandNotFound) {
+			// the command was delivered, replaced or canceled by another instance
+			q.untrack(ctx, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		command.Status = entities.CommandTimeout
+		command.UpdatedAt = time.Now()
+		err = q.publisher.PublishCommandTimeout(*command)
+		if err != nil {
+			q.logger.Errorf("error publishing command %s timeout: %s", command.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// take removes a command from the thing's queue and returns it. Only one of the instances
+// taking the command at the same time, e.g. expiring it while it's canceled, takes it.
+func (q *redisQueue) take(ctx context.Context, thingID, commandID string) (*entities.Command, error) {
+	if thingID == "" {
+		return nil, entities.ErrCommandNotFound
+	}
+
+	var command entities.Command
+	err := q.update(ctx, thingID, func(queued []entities.Command) ([]entities.Command, error) {
+		var found bool
+		queued, command, found = remove(queued, commandID)
+		if !found {
+			return nil, entities.ErrCommandNotFound
+		}
+		return queued, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	q.untrack(ctx, commandID)
+	return &command, nil
+}
+
+// update changes the thing's queue atomically with fn, which may be called again when the
+// queue is changed meanwhile by another instance, and the queue isn't changed if it fails
+func (q *redisQueue) update(ctx context.Context, thingID string, fn func(queued []entities.Command) ([]entities.Command, error)) error {
+	return q.redis.Update(ctx, commandQueueKeyPrefix+thingID, q.keysTTL(), func(value string) (string, error) {
+		queued, err := decodeCommands(value)
+		if err != nil {
+			return "", err
+		}
+
+		queued, err = fn(queued)
+		if err != nil {
+			return "", err
+		}
+		if len(queued) == 0 {
+			return "", nil
+		}
+
+		encoded, err := json.Marshal(queued)
+		return string(encoded), err
+	})
+}
+
+// untrack removes a command that is no longer queued from the indexes. The failures are
+// only logged, since the commands left indexed are ignored when they aren't queued.
+func (q *redisQueue) untrack(ctx context.Context, commandID string) {
+	err := q.redis.ZRem(ctx, queuedCommandsKey, commandID)
+	if err == nil {
+		err = q.redis.Del(ctx, queuedCommandKeyPrefix+commandID)
+	}
+	if err != nil {
+		q.logger.Errorf("error removing queued command %s from the indexes: %s", commandID, err)
+	}
+}
+
+func (q *redisQueue) keysTTL() time.Duration {
+	return queueKeysTTLFactor * q.ttl
+}
+
+// decodeCommands decodes the commands stored in a queue, which is empty when not found
+func decodeCommands(value string) ([]entities.Command, error) {
+	if value == "" {
+		return []entities.Command{}, nil
+	}
+
+	var commands []entities.Command
+	err := json.Unmarshal([]byte(value), &commands)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,99 @@
+//go:build integration
+// +build integration
+
+package commands
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRedisQueue(t *testing.T) {
+	r := newTestRedis(t)
+	for _, tc := range queueCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queue := NewRedisQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, time.Minute, 10, tc.policy)
+			for _, command := range tc.commands {
+				assert.NoError(t, queue.Enqueue(ctx, command))
+			}
+
+			queued, err := queue.List(ctx, thingID)
+			assert.NoError(t, err)
+			var ids []string
+			for _, command := range queued {
+				assert.Equal(t, entities.CommandQueued, command.Status)
+				ids = append(ids, command.ID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+
+			flushed, err := queue.Flush(ctx, thingID)
+			assert.NoError(t, err)
+			assert.Len(t, flushed, len(tc.expectedIDs))
+			queued, err = queue.List(ctx, thingID)
+			assert.NoError(t, err)
+			assert.Empty(t, queued)
+			_, err = queue.Get(ctx, tc.expectedIDs[0])
+			assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+		})
+	}
+}
+
+func TestRedisQueueSharedByInstances(t *testing.T) {
+	r := newTestRedis(t)
+	sender := NewRedisQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, time.Minute, 1, PolicyKeepAll)
+	receiver := NewRedisQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, time.Minute, 1, PolicyKeepAll)
+	assert.NoError(t, sender.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	// the queue's size is shared too
+	err := receiver.Enqueue(ctx, newUpdateCommand("second", entities.Data{SensorID: 0, Value: false}))
+	assert.True(t, errors.Is(err, entities.ErrCommandQueueFull))
+	command, err := receiver.Get(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, thingID, command.ThingID)
+
+	flushed, err := receiver.Flush(ctx, thingID)
+	assert.NoError(t, err)
+	assert.Len(t, flushed, 1)
+	assert.True(t, errors.Is(sender.Cancel(ctx, thingID, "first"), entities.ErrCommandNotFound))
+}
+
+func TestRedisQueueCancelsCommand(t *testing.T) {
+	r := newTestRedis(t)
+	queue := NewRedisQueue(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, time.Minute, 10, PolicyKeepAll)
+	assert.NoError(t, queue.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	err := queue.Cancel(ctx, "8a6f2fe9da74485f", "first")
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+	assert.NoError(t, queue.Cancel(ctx, thingID, "first"))
+	_, err = queue.Get(ctx, "first")
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+}
+
+func TestRedisQueueTimesOutExpiredCommandOnce(t *testing.T) {
+	r := newTestRedis(t)
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.
+		On("PublishCommandTimeout", mock.MatchedBy(func(c entities.Command) bool {
+			return c.ID == "first" && c.Status == entities.CommandTimeout
+		})).
+		Return(nil).
+		Once()
+	first := NewRedisQueue(&mocks.FakeLogger{}, fakePublisher, r, 10*time.Millisecond, 10, PolicyKeepAll)
+	second := NewRedisQueue(&mocks.FakeLogger{}, fakePublisher, r, 10*time.Millisecond, 10, PolicyKeepAll)
+	assert.NoError(t, first.Enqueue(ctx, newUpdateCommand("first", entities.Data{SensorID: 0, Value: true})))
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, first.expire(ctx))
+	assert.NoError(t, second.expire(ctx))
+
+	queued, err := first.List(ctx, thingID)
+	assert.NoError(t, err)
+	assert.Empty(t, queued)
+	fakePublisher.AssertExpectations(t)
+}
@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// commandKeyPrefix prefixes the keys that store the tracked commands
+	commandKeyPrefix = "command:"
+	// commandSensorsKeyPrefix prefixes the keys of the sets of sensors a pending command
+	// is still waiting data from
+	commandSensorsKeyPrefix = "command-sensors:"
+	// thingCommandsKeyPrefix prefixes the keys of the sets indexing the pending commands
+	// of each thing
+	thingCommandsKeyPrefix = "thing-commands:"
+	// pendingCommandsKey is the sorted set of the pending command IDs scored by the time
+	// they time out
+	pendingCommandsKey = "commands-pending"
+)
+
+type redisTracker struct {
+	logger    logging.Logger
+	publisher amqp.Publisher
+	redis     *network.Redis
+	timeout   time.Duration
+	retention time.Duration
+	quit      chan struct{}
+}
+
+// NewRedisTracker creates a Tracker that keeps the commands in Redis, so they are shared by
+// every babeltower instance and a command can be sent by one instance and completed by
+// another. A pending command is finished by the single instance that removes it from the
+// pending commands, and the ones that time out are found by the instances checking them
+// periodically with Start. The command's keys expire after timeout plus retention.
+func NewRedisTracker(logger logging.Logger, publisher amqp.Publisher, redis *network.Redis, timeout, retention time.Duration) *redisTracker {
+	return &redisTracker{logger, publisher, redis, timeout, retention, make(chan struct{})}
+}
+
+// Start times out the pending commands on every interval until Stop is called
+func (t *redisTracker) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := t.expire(context.Background())
+			if err != nil {
+				t.logger.Errorf("error timing out commands: %s", err)
+			}
+		case <-t.quit:
+			return
+		}
+	}
+}
+
+// Stop stops timing out the pending commands
+func (t *redisTracker) Stop() {
+	close(t.quit)
+}
+
+// Add starts tracking a command, generating its ID when it isn't provided
+func (t *redisTracker) Add(ctx context.Context, thingID, commandID, kind string, sensorIDs []int) (*entities.Command, error) {
+	if commandID == "" {
+		commandID = ksuid.New().String()
+	}
+
+	now := time.Now()
+	command := entities.Command{
+		ID:        commandID,
+		ThingID:   thingID,
+		Kind:      kind,
+		SensorIDs: sensorIDs,
+		Status:    entities.CommandPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	value, err := json.Marshal(command)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := t.timeout + t.retention
+	added, err := t.redis.SetNX(ctx, commandKeyPrefix+commandID, value, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, entities.ErrCommandExists
+	}
+
+	if len(sensorIDs) > 0 {
+		sensors := make([]interface{}, len(sensorIDs))
+		for i, id := range sensorIDs {
+			sensors[i] = id
+		}
+		err = t.redis.SAdd(ctx, commandSensorsKeyPrefix+commandID, sensors...)
+		if err != nil {
+			return nil, err
+		}
+		err = t.redis.Expire(ctx, commandSensorsKeyPrefix+commandID, ttl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = t.redis.SAdd(ctx, thingCommandsKeyPrefix+thingID, commandID)
+	if err != nil {
+		return nil, err
+	}
+	err = t.redis.Expire(ctx, thingCommandsKeyPrefix+thingID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.redis.ZAdd(ctx, pendingCommandsKey, score(now.Add(t.timeout)), commandID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &command, nil
+}
+
+// Remove stops tracking a command that couldn't be sent to the thing
+func (t *redisTracker) Remove(ctx context.Context, commandID string) error {
+	err := t.redis.ZRem(ctx, pendingCommandsKey, commandID)
+	if err != nil {
+		return err
+	}
+
+	err = t.redis.Del(ctx, commandSensorsKeyPrefix+commandID)
+	if err != nil {
+		return err
+	}
+
+	return t.redis.Del(ctx, commandKeyPrefix+commandID)
+}
+
+// Ack completes a pending command, or fails it if the thing informs an error
+func (t *redisTracker) Ack(ctx context.Context, thingID, commandID string, failure error) error {
+	command, err := t.Get(ctx, commandID)
+	if err != nil {
+		return err
+	}
+	if command.ThingID != thingID {
+		return entities.ErrCommandNotFound
+	}
+	if command.Status != entities.CommandPending {
+		return nil
+	}
+
+	status := entities.CommandCompleted
+	if failure != nil {
+		status = entities.CommandFailed
+		command.Error = failure.Error()
+	}
+
+	finished, err := t.finish(ctx, command, status)
+	if err != nil || !finished {
+		return err
+	}
+
+	return t.publisher.PublishCommandCompleted(*command)
+}
+
+// Match completes the pending commands of the thing that have received data from all
+// their sensors. The commands that are no longer pending are removed from the thing's index.
+func (t *redisTracker) Match(ctx context.Context, thingID string, sensorIDs []int) error {
+	ids, err := t.redis.SMembers(ctx, thingCommandsKeyPrefix+thingID)
+	if err != nil {
+		return err
+	}
+
+	sensors := make([]interface{}, len(sensorIDs))
+	for i, id := range sensorIDs {
+		sensors[i] = id
+	}
+
+	stale := []interface{}{}
+	for _, id := range ids {
+		command, err := t.Get(ctx, id)
+		if errors.Is(err, entities.ErrCommandNotFound) {
+			stale = append(stale, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if command.Status != entities.CommandPending {
+			stale = append(stale, id)
+			continue
+		}
+
+		if len(sensors) > 0 {
+			err = t.redis.SRem(ctx, commandSensorsKeyPrefix+id, sensors...)
+			if err != nil {
+				return err
+			}
+		}
+		remaining, err := t.redis.SMembers(ctx, commandSensorsKeyPrefix+id)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			continue
+		}
+
+		finished, err := t.finish(ctx, command, entities.CommandCompleted)
+		if err != nil {
+			return err
+		}
+		if !finished {
+			continue
+		}
+
+		err = t.publisher.PublishCommandCompleted(*command)
+		if err != nil {
+			t.logger.Errorf("error publishing command %s completed: %s", command.ID, err)
+		}
+	}
+
+	if len(stale) > 0 {
+		return t.redis.SRem(ctx, thingCommandsKeyPrefix+thingID, stale...)
+	}
+
+	return nil
+}
+
+// Get returns a tracked command
+func (t *redisTracker) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	value, err := t.redis.Get(ctx, commandKeyPrefix+commandID)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, entities.ErrCommandNotFound
+	}
+
+	command := &entities.Command{}
+	err = json.Unmarshal([]byte(value), command)
+	if err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// expire times out the pending commands whose timeout has passed
+func (t *redisTracker) expire(ctx context.Context) error {
+	ids, err := t.redis.ZRangeByScore(ctx, pendingCommandsKey, score(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		command, err := t.Get(ctx, id)
+		if errors.Is(err, entities.ErrCommandNotFound) {
+			// the command's keys expired, e.g. when no instance was running
+			err = t.redis.ZRem(ctx, pendingCommandsKey, id)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		finished, err := t.finish(ctx, command, entities.CommandTimeout)
+		if err != nil {
+			return err
+		}
+		if !finished {
+			continue
+		}
+
+		err = t.publisher.PublishCommandTimeout(*command)
+		if err != nil {
+			t.logger.Errorf("error publishing command %s timeout: %s", command.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// finish sets the final status of a pending command, which is kept for the retention
+// time, reporting if it was finished. The command is claimed by removing it from the
+// pending commands, so only one of the instances finishing it at the same time, e.g.
+// timing it out while it's acknowledged, finishes it. A command that fails to be saved
+// is given back to the pending commands, while the indexes that fail to be updated are
+// cleaned up by Match.
+func (t *redisTracker) finish(ctx context.Context, command *entities.Command, status string) (bool, error) {
+	claimed, err := t.redis.ZClaim(ctx, pendingCommandsKey, command.ID)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	command.Status = status
+	command.UpdatedAt = time.Now()
+	value, err := json.Marshal(command)
+	if err == nil {
+		err = t.redis.Set(ctx, commandKeyPrefix+command.ID, value, t.retention)
+	}
+	if err != nil {
+		releaseErr := t.redis.ZAdd(ctx, pendingCommandsKey, score(command.CreatedAt.Add(t.timeout)), command.ID)
+		if releaseErr != nil {
+			t.logger.Errorf("error releasing command %s: %s", command.ID, releaseErr)
+		}
+		return false, err
+	}
+
+	err = t.redis.Del(ctx, commandSensorsKeyPrefix+command.ID)
+	if err == nil {
+		err = t.redis.SRem(ctx, thingCommandsKeyPrefix+command.ThingID, command.ID)
+	}
+	if err != nil {
+		t.logger.Errorf("error removing command %s from the pending indexes: %s", command.ID, err)
+	}
+
+	return true, nil
+}
+
+// score returns the score of the time in the sorted sets, in seconds
+func score(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,107 @@
+//go:build integration
+// +build integration
+
+package commands
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestRedis(t *testing.T) *network.Redis {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		url = "redis://localhost:6379/0"
+	}
+
+	r := network.NewRedis(url, time.Minute, &mocks.FakeLogger{})
+	started := make(chan bool, 1)
+	go r.Start(started)
+	select {
+	case s := <-started:
+		assert.True(t, s)
+	case <-time.After(30 * time.Second):
+		t.Fatal("timeout waiting for redis connection")
+	}
+	t.Cleanup(r.Stop)
+
+	return r
+}
+
+func TestRedisTracker(t *testing.T) {
+	r := newTestRedis(t)
+	for _, tc := range trackerCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakePublisher := &mocks.FakePublisher{}
+			if tc.expectedEvent != "" {
+				fakePublisher.
+					On(tc.expectedEvent, mock.MatchedBy(func(c entities.Command) bool { return c.Status == tc.expectedStatus })).
+					Return(nil).
+					Once()
+			}
+
+			tracker := NewRedisTracker(&mocks.FakeLogger{}, fakePublisher, r, tc.timeout, time.Minute)
+			go tracker.Start(10 * time.Millisecond)
+			defer tracker.Stop()
+
+			command, err := tracker.Add(ctx, thingID, "", entities.CommandRequestData, tc.sensorIDs)
+			assert.NoError(t, err)
+			assert.Equal(t, entities.CommandPending, command.Status)
+			// the commands left pending would be completed by the data of the next cases
+			defer func(id string) { assert.NoError(t, tracker.Remove(ctx, id)) }(command.ID)
+
+			tc.act(tracker, command.ID)
+
+			command, err = tracker.Get(ctx, command.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, command.Status)
+			assert.Equal(t, tc.expectedError, command.Error)
+			fakePublisher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRedisTrackerSharedByInstances(t *testing.T) {
+	r := newTestRedis(t)
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.On("PublishCommandCompleted", mock.Anything).Return(nil).Once()
+	sender := NewRedisTracker(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, time.Minute, time.Minute)
+	receiver := NewRedisTracker(&mocks.FakeLogger{}, fakePublisher, r, time.Minute, time.Minute)
+
+	command, err := sender.Add(ctx, thingID, "", entities.CommandRequestData, []int{0})
+	assert.NoError(t, err)
+	defer func(id string) { assert.NoError(t, sender.Remove(ctx, id)) }(command.ID)
+	_, err = receiver.Add(ctx, thingID, command.ID, entities.CommandRequestData, []int{0})
+	assert.Equal(t, entities.ErrCommandExists, err)
+
+	// the command is completed once, by the instance that received the thing's data
+	assert.NoError(t, receiver.Match(ctx, thingID, []int{0}))
+	assert.NoError(t, sender.Ack(ctx, thingID, command.ID, nil))
+
+	command, err = sender.Get(ctx, command.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.CommandCompleted, command.Status)
+	fakePublisher.AssertExpectations(t)
+}
+
+func TestRedisTrackerRemove(t *testing.T) {
+	r := newTestRedis(t)
+	tracker := NewRedisTracker(&mocks.FakeLogger{}, &mocks.FakePublisher{}, r, 10*time.Millisecond, time.Minute)
+	command, err := tracker.Add(ctx, thingID, "", entities.CommandUpdateData, []int{0})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tracker.Remove(ctx, command.ID))
+	_, err = tracker.Get(ctx, command.ID)
+	assert.Equal(t, entities.ErrCommandNotFound, err)
+
+	// the removed command doesn't time out
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, tracker.expire(ctx))
+}
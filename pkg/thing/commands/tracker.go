@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/segmentio/ksuid"
+)
+
+// Tracker keeps the commands sent to the things as pending until the thing acts on
+// them. A command is completed when the thing sends data from all the command's sensors
+// or acknowledges it, and timed out if that doesn't happen in time. The result is
+// published to the clients and kept to be queried for a while.
+type Tracker interface {
+	// Add starts tracking a command, generating its ID when it isn't provided
+	Add(ctx context.Context, thingID, commandID, kind string, sensorIDs []int) (*entities.Command, error)
+
+	// Remove stops tracking a command that couldn't be sent to the thing
+	Remove(ctx context.Context, commandID string) error
+
+	// Ack completes a pending command, or fails it if the thing informs an error
+	Ack(ctx context.Context, thingID, commandID string, failure error) error
+
+	// Match completes the pending commands of the thing that have received data
+	// from all their sensors
+	Match(ctx context.Context, thingID string, sensorIDs []int) error
+
+	// Get returns a tracked command
+	Get(ctx context.Context, commandID string) (*entities.Command, error)
+}
+
+type trackedCommand struct {
+	command   entities.Command
+	remaining map[int]bool
+	timer     *time.Timer
+}
+
+type tracker struct {
+	logger    logging.Logger
+	publisher amqp.Publisher
+	timeout   time.Duration
+	retention time.Duration
+	mutex     sync.Mutex
+	commands  map[string]*trackedCommand
+}
+
+// NewTracker creates a new Tracker instance that keeps the commands in memory, so it's only
+// suited for a single instance. Pending commands time out after timeout and finished
+// commands are kept for retention.
+func NewTracker(logger logging.Logger, publisher amqp.Publisher, timeout, retention time.Duration) Tracker {
+	return &tracker{
+		logger:    logger,
+		publisher: publisher,
+		timeout:   timeout,
+		retention: retention,
+		commands:  map[string]*trackedCommand{},
+	}
+}
+
+// Add starts tracking a command, generating its ID when it isn't provided
+func (t *tracker) Add(ctx context.Context, thingID, commandID, kind string, sensorIDs []int) (*entities.Command, error) {
+	if commandID == "" {
+		commandID = ksuid.New().String()
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.commands[commandID]; ok {
+		return nil, entities.ErrCommandExists
+	}
+
+	now := time.Now()
+	tc := &trackedCommand{
+		command: entities.Command{
+			ID:        commandID,
+			ThingID:   thingID,
+			Kind:      kind,
+			SensorIDs: sensorIDs,
+			Status:    entities.CommandPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		remaining: map[int]bool{},
+	}
+	for _, id := range sensorIDs {
+		tc.remaining[id] = true
+	}
+	tc.timer = time.AfterFunc(t.timeout, func() { t.expire(commandID) })
+	t.commands[commandID] = tc
+
+	command := tc.command
+	return &command, nil
+}
+
+// Remove stops tracking a command that couldn't be sent to the thing
+func (t *tracker) Remove(ctx context.Context, commandID string) error {
+	t.remove(commandID)
+	return nil
+}
+
+// Ack completes a pending command, or fails it if the thing informs an error
+func (t *tracker) Ack(ctx context.Context, thingID, commandID string, failure error) error {
+	t.mutex.Lock()
+	tc, ok := t.commands[commandID]
+	if !ok || tc.command.ThingID != thingID {
+		t.mutex.Unlock()
+		return entities.ErrCommandNotFound
+	}
+	if tc.command.Status != entities.CommandPending {
+		t.mutex.Unlock()
+		return nil
+	}
+
+	status := entities.CommandCompleted
+	if failure != nil {
+		status = entities.CommandFailed
+		tc.command.Error = failure.Error()
+	}
+	command := t.finish(tc, status)
+	t.mutex.Unlock()
+
+	return t.publisher.PublishCommandCompleted(command)
+}
+
+// Match completes the pending commands of the thing that have received data from all
+// their sensors
+func (t *tracker) Match(ctx context.Context, thingID string, sensorIDs []int) error {
+	var completed []entities.Command
+
+	t.mutex.Lock()
+	for _, tc := range t.commands {
+		if tc.command.ThingID != thingID || tc.command.Status != entities.CommandPending {
+			continue
+		}
+
+		for _, id := range sensorIDs {
+			delete(tc.remaining, id)
+		}
+		if len(tc.remaining) == 0 {
+			completed = append(completed, t.finish(tc, entities.CommandCompleted))
+		}
+	}
+	t.mutex.Unlock()
+
+	for _, command := range completed {
+		err := t.publisher.PublishCommandCompleted(command)
+		if err != nil {
+			t.logger.Errorf("error publishing command %s completed: %s", command.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Get returns a tracked command
+func (t *tracker) Get(ctx context.Context, commandID string) (*entities.Command, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tc, ok := t.commands[commandID]
+	if !ok {
+		return nil, entities.ErrCommandNotFound
+	}
+
+	command := tc.command
+	return &command, nil
+}
+
+func (t *tracker) remove(commandID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tc, ok := t.commands[commandID]
+	if !ok {
+		return
+	}
+
+	tc.timer.Stop()
+	delete(t.commands, commandID)
+}
+
+func (t *tracker) expire(commandID string) {
+	t.mutex.Lock()
+	tc, ok := t.commands[commandID]
+	if !ok || tc.command.Status != entities.CommandPending {
+		t.mutex.Unlock()
+		return
+	}
+	command := t.finish(tc, entities.CommandTimeout)
+	t.mutex.Unlock()
+
+	err := t.publisher.PublishCommandTimeout(command)
+	if err != nil {
+		t.logger.Errorf("error publishing command %s timeout: %s", command.ID, err)
+	}
+}
+
+// finish sets the final status of the command and schedules its removal after the
+// retention time. It must be called with the mutex locked.
+func (t *tracker) finish(tc *trackedCommand, status string) entities.Command {
+	tc.timer.Stop()
+	tc.command.Status = status
+	tc.command.UpdatedAt = time.Now()
+
+	commandID := tc.command.ID
+	tc.timer = time.AfterFunc(t.retention, func() { t.remove(commandID) })
+
+	return tc.command
+}
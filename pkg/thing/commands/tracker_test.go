@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const thingID = "fc3fcf912d0c290a"
+
+var ctx = context.Background()
+
+type trackerTestCase struct {
+	name           string
+	sensorIDs      []int
+	timeout        time.Duration
+	act            func(t Tracker, commandID string)
+	expectedStatus string
+	expectedError  string
+	expectedEvent  string
+}
+
+var trackerCases = []trackerTestCase{
+	{
+		"command pending while not all sensors sent data",
+		[]int{0, 1},
+		time.Minute,
+		func(t Tracker, _ string) { _ = t.Match(ctx, thingID, []int{0}) },
+		entities.CommandPending,
+		"",
+		"",
+	},
+	{
+		"command completed when all sensors sent data",
+		[]int{0, 1},
+		time.Minute,
+		func(t Tracker, _ string) {
+			_ = t.Match(ctx, thingID, []int{0})
+			_ = t.Match(ctx, thingID, []int{1, 2})
+		},
+		entities.CommandCompleted,
+		"",
+		"PublishCommandCompleted",
+	},
+	{
+		"command not completed by data from another thing",
+		[]int{0},
+		time.Minute,
+		func(t Tracker, _ string) { _ = t.Match(ctx, "8a6f2fe9da74485f", []int{0}) },
+		entities.CommandPending,
+		"",
+		"",
+	},
+	{
+		"command completed when acknowledged",
+		[]int{0},
+		time.Minute,
+		func(t Tracker, commandID string) { _ = t.Ack(ctx, thingID, commandID, nil) },
+		entities.CommandCompleted,
+		"",
+		"PublishCommandCompleted",
+	},
+	{
+		"command failed when acknowledged with error",
+		[]int{0},
+		time.Minute,
+		func(t Tracker, commandID string) { _ = t.Ack(ctx, thingID, commandID, errors.New("actuator jammed")) },
+		entities.CommandFailed,
+		"actuator jammed",
+		"PublishCommandCompleted",
+	},
+	{
+		"command timed out without data",
+		[]int{0},
+		100 * time.Millisecond,
+		func(_ Tracker, _ string) { time.Sleep(200 * time.Millisecond) },
+		entities.CommandTimeout,
+		"",
+		"PublishCommandTimeout",
+	},
+}
+
+func TestTracker(t *testing.T) {
+	for _, tc := range trackerCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakePublisher := &mocks.FakePublisher{}
+			if tc.expectedEvent != "" {
+				fakePublisher.
+					On(tc.expectedEvent, mock.MatchedBy(func(c entities.Command) bool { return c.Status == tc.expectedStatus })).
+					Return(nil).
+					Once()
+			}
+
+			tracker := NewTracker(&mocks.FakeLogger{}, fakePublisher, tc.timeout, time.Minute)
+			command, err := tracker.Add(ctx, thingID, "", entities.CommandRequestData, tc.sensorIDs)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, command.ID)
+			assert.Equal(t, entities.CommandPending, command.Status)
+
+			tc.act(tracker, command.ID)
+
+			command, err = tracker.Get(ctx, command.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, command.Status)
+			assert.Equal(t, tc.expectedError, command.Error)
+			fakePublisher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTrackerRejectsDuplicatedCommand(t *testing.T) {
+	tracker := NewTracker(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, time.Minute)
+	_, err := tracker.Add(ctx, thingID, "command-id", entities.CommandUpdateData, []int{0})
+	assert.NoError(t, err)
+
+	_, err = tracker.Add(ctx, thingID, "command-id", entities.CommandUpdateData, []int{0})
+	assert.True(t, errors.Is(err, entities.ErrCommandExists))
+}
+
+func TestTrackerRemovesFinishedCommandAfterRetention(t *testing.T) {
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.On("PublishCommandCompleted", mock.Anything).Return(nil)
+	tracker := NewTracker(&mocks.FakeLogger{}, fakePublisher, time.Minute, 50*time.Millisecond)
+	command, err := tracker.Add(ctx, thingID, "", entities.CommandRequestData, []int{0})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tracker.Ack(ctx, thingID, command.ID, nil))
+	assert.Eventually(t, func() bool {
+		_, err := tracker.Get(ctx, command.ID)
+		return errors.Is(err, entities.ErrCommandNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTrackerAckUnknownCommand(t *testing.T) {
+	tracker := NewTracker(&mocks.FakeLogger{}, &mocks.FakePublisher{}, time.Minute, time.Minute)
+	command, err := tracker.Add(ctx, thingID, "", entities.CommandRequestData, []int{0})
+	assert.NoError(t, err)
+
+	err = tracker.Ack(ctx, "8a6f2fe9da74485f", command.ID, nil)
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+	err = tracker.Ack(ctx, thingID, "unknown-command", nil)
+	assert.True(t, errors.Is(err, entities.ErrCommandNotFound))
+}
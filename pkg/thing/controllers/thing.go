@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/interactors"
 )
 
@@ -23,30 +26,109 @@ func NewThingController(logger logging.Logger, thingInteractor interactors.Inter
 	return &ThingController{logger, thingInteractor, sender, publisher}
 }
 
-// Register handles the register device request and execute its use case
-func (mc *ThingController) Register(body []byte, authorizationHeader string) error {
+// Register handles the register device request and execute its use case. Besides the
+// registered event, the result is replied to the requestor when replyTo is provided.
+func (mc *ThingController) Register(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
 	msg := network.DeviceRegisterRequest{}
 	err := json.Unmarshal(body, &msg)
 	if err != nil {
 		return err
 	}
 
-	return mc.thingInteractor.Register(authorizationHeader, msg.ID, msg.Name)
+	token, err := mc.thingInteractor.Register(ctx, authorizationHeader, msg.ID, msg.Name, msg.Metadata, msg.Force)
+	if replyTo == "" || interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	sendErr := mc.sender.SendRegisteredDevice(msg.ID, msg.Name, token, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
 }
 
-// Unregister handles the unregister device request and execute its use case
-func (mc *ThingController) Unregister(body []byte, authorizationHeader string) error {
+// Unregister handles the unregister device request and execute its use case. Besides
+// the unregistered event, the result is replied to the requestor when replyTo is provided.
+func (mc *ThingController) Unregister(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
 	msg := network.DeviceUnregisterRequest{}
 	err := json.Unmarshal(body, &msg)
 	if err != nil {
 		return err
 	}
 
-	return mc.thingInteractor.Unregister(authorizationHeader, msg.ID)
+	err = mc.thingInteractor.Unregister(ctx, authorizationHeader, msg.ID)
+	if replyTo == "" || interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	sendErr := mc.sender.SendUnregisteredDevice(msg.ID, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
+}
+
+// RegisterBulk handles the bulk register devices request and execute its use case.
+// Besides the registered bulk event, the result is replied to the requestor when replyTo
+// is provided.
+func (mc *ThingController) RegisterBulk(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
+	msg := network.DeviceBulkRegisterRequest{}
+	err := json.Unmarshal(body, &msg)
+	if err != nil {
+		return err
+	}
+
+	things := make([]entities.Thing, len(msg.Devices))
+	for i, d := range msg.Devices {
+		things[i] = entities.Thing{ID: d.ID, Name: d.Name, Metadata: d.Metadata}
+	}
+
+	results, err := mc.thingInteractor.RegisterBulk(ctx, authorizationHeader, things, msg.Force)
+	if replyTo == "" || interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	sendErr := mc.sender.SendBulkRegisteredDevices(results, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
+}
+
+// UnregisterBulk handles the bulk unregister devices request and execute its use case.
+// Besides the unregistered bulk event, the result is replied to the requestor when replyTo
+// is provided.
+func (mc *ThingController) UnregisterBulk(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
+	msg := network.DeviceBulkUnregisterRequest{}
+	err := json.Unmarshal(body, &msg)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(msg.Devices))
+	for i, d := range msg.Devices {
+		ids[i] = d.ID
+	}
+
+	results, err := mc.thingInteractor.UnregisterBulk(ctx, authorizationHeader, ids)
+	if replyTo == "" || interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	sendErr := mc.sender.SendBulkUnregisteredDevices(results, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
 }
 
-// UpdateConfig handles the update config request and execute its use case
-func (mc *ThingController) UpdateConfig(body []byte, authorizationHeader string) error {
+// UpdateConfig handles the update config request and execute its use case. Besides
+// the config updated event, the result is replied to the requestor when replyTo is provided.
+func (mc *ThingController) UpdateConfig(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
 	mc.logger.Info("update config message received")
 	var updateConfigReq network.ConfigUpdateRequest
 	err := json.Unmarshal(body, &updateConfigReq)
@@ -55,12 +137,8 @@ func (mc *ThingController) UpdateConfig(body []byte, authorizationHeader string)
 		return err
 	}
 
-	changed, err := mc.thingInteractor.UpdateConfig(authorizationHeader, updateConfigReq.ID, updateConfigReq.Config)
-	if err != nil {
-		pubErr := mc.publisher.PublishUpdatedConfig(updateConfigReq.ID, updateConfigReq.Config, changed, err)
-		if pubErr != nil {
-			return fmt.Errorf("error publishing response: %v: %w", err, pubErr)
-		}
+	changed, err := mc.thingInteractor.UpdateConfig(ctx, authorizationHeader, updateConfigReq.ID, updateConfigReq.Config)
+	if interactors.WillRetry(ctx, err) {
 		return err
 	}
 
@@ -69,22 +147,109 @@ func (mc *ThingController) UpdateConfig(body []byte, authorizationHeader string)
 		return fmt.Errorf("error publishing response: %v: %w", err, pubErr)
 	}
 
-	return nil
+	if replyTo != "" {
+		sendErr := mc.sender.SendUpdatedConfig(updateConfigReq.ID, updateConfigReq.Config, changed, replyTo, corrID, err)
+		if sendErr != nil {
+			return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+		}
+	}
+
+	return err
+}
+
+// UpdateMetadata handles the update metadata request and execute its use case. Besides
+// the metadata updated event, the result is replied to the requestor when replyTo is provided.
+func (mc *ThingController) UpdateMetadata(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
+	mc.logger.Info("update metadata message received")
+	var updateMetadataReq network.MetadataUpdateRequest
+	err := json.Unmarshal(body, &updateMetadataReq)
+	if err != nil {
+		mc.logger.Error(err)
+		return err
+	}
+
+	err = mc.thingInteractor.UpdateMetadata(ctx, authorizationHeader, updateMetadataReq.ID, updateMetadataReq.Metadata)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	pubErr := mc.publisher.PublishUpdatedMetadata(updateMetadataReq.ID, updateMetadataReq.Metadata, err)
+	if pubErr != nil {
+		return fmt.Errorf("error publishing response: %v: %w", err, pubErr)
+	}
+
+	if replyTo != "" {
+		sendErr := mc.sender.SendUpdatedMetadata(updateMetadataReq.ID, updateMetadataReq.Metadata, replyTo, corrID, err)
+		if sendErr != nil {
+			return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+		}
+	}
+
+	return err
+}
+
+// UpdateDevice handles the update device request and execute its use case. Besides the
+// updated event, the result is replied to the requestor when replyTo is provided.
+func (mc *ThingController) UpdateDevice(ctx context.Context, body []byte, authorizationHeader, replyTo, corrID string) error {
+	mc.logger.Info("update device message received")
+	var updateReq network.DeviceUpdateRequest
+	err := json.Unmarshal(body, &updateReq)
+	if err != nil {
+		mc.logger.Error(err)
+		return err
+	}
+
+	thing, err := mc.thingInteractor.Update(ctx, authorizationHeader, updateReq.ID, updateReq.Name, updateReq.Metadata)
+	if replyTo == "" || interactors.WillRetry(ctx, err) {
+		return err
+	}
+
+	if thing == nil {
+		thing = &entities.Thing{ID: updateReq.ID, Name: updateReq.Name}
+	}
+	sendErr := mc.sender.SendUpdatedDevice(thing.ID, thing.Name, thing.Metadata, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
 }
 
-// ListDevices handles the list devices request and execute its use case
-func (mc *ThingController) ListDevices(authorization, replyTo, corrID string) error {
+// ListDevices handles the list devices request and execute its use case. The request
+// body is optional and every thing is listed when it's empty.
+func (mc *ThingController) ListDevices(ctx context.Context, body []byte, authorization, replyTo, corrID string) error {
 	mc.logger.Info("list devices command received")
-	things, err := mc.thingInteractor.List(authorization)
+	var listReq network.DeviceListRequest
+	if len(body) > 0 {
+		err := json.Unmarshal(body, &listReq)
+		if err != nil {
+			mc.logger.Error(err)
+			return err
+		}
+	}
+
+	options := entities.ListOptions{
+		Limit:     listReq.Limit,
+		Offset:    listReq.Offset,
+		Name:      listReq.Name,
+		Tags:      listReq.Tags,
+		HasConfig: listReq.HasConfig,
+		Sort:      listReq.Sort,
+		Fields:    listReq.Fields,
+	}
+	things, total, err := mc.thingInteractor.List(ctx, authorization, options)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
 	if err != nil {
-		sendErr := mc.sender.SendListResponse(things, replyTo, corrID, err)
+		sendErr := mc.sender.SendListResponse(things, total, replyTo, corrID, err)
 		if sendErr != nil {
 			return fmt.Errorf("error sending response: %v: %w", err, sendErr)
 		}
 		return err
 	}
 
-	sendErr := mc.sender.SendListResponse(things, replyTo, corrID, err)
+	sendErr := mc.sender.SendListResponse(things, total, replyTo, corrID, err)
 	if sendErr != nil {
 		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
 	}
@@ -93,7 +258,7 @@ func (mc *ThingController) ListDevices(authorization, replyTo, corrID string) er
 }
 
 // AuthDevice handles the auth device request and execute its use case
-func (mc *ThingController) AuthDevice(body []byte, authorization, replyTo, corrID string) error {
+func (mc *ThingController) AuthDevice(ctx context.Context, body []byte, authorization, replyTo, corrID string) error {
 	var authThingReq network.DeviceAuthRequest
 	err := json.Unmarshal(body, &authThingReq)
 	if err != nil {
@@ -102,7 +267,10 @@ func (mc *ThingController) AuthDevice(body []byte, authorization, replyTo, corrI
 	}
 
 	mc.logger.Info("auth device command received")
-	err = mc.thingInteractor.Auth(authorization, authThingReq.ID)
+	err = mc.thingInteractor.Auth(ctx, authorization, authThingReq.ID)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
 	if err != nil {
 		sendErr := mc.sender.SendAuthResponse(authThingReq.ID, replyTo, corrID, err)
 		if sendErr != nil {
@@ -120,7 +288,7 @@ func (mc *ThingController) AuthDevice(body []byte, authorization, replyTo, corrI
 }
 
 // RequestData handles the request data request and execute its use case
-func (mc *ThingController) RequestData(body []byte, authorization string) error {
+func (mc *ThingController) RequestData(ctx context.Context, body []byte, authorization string) error {
 	var requestDataReq network.DataRequest
 	err := json.Unmarshal(body, &requestDataReq)
 	if err != nil {
@@ -130,7 +298,7 @@ func (mc *ThingController) RequestData(body []byte, authorization string) error
 
 	mc.logger.Info("request data command received")
 	mc.logger.Debug(authorization, requestDataReq)
-	err = mc.thingInteractor.RequestData(authorization, requestDataReq.ID, requestDataReq.SensorIds)
+	err = mc.thingInteractor.RequestData(ctx, authorization, requestDataReq.ID, requestDataReq.CommandID, requestDataReq.SensorIds)
 	if err != nil {
 		return err
 	}
@@ -139,23 +307,105 @@ func (mc *ThingController) RequestData(body []byte, authorization string) error
 }
 
 // UpdateData handles the update data request and execute its use case
-func (mc *ThingController) UpdateData(body []byte, authorization string) error {
+func (mc *ThingController) UpdateData(ctx context.Context, body []byte, authorization string) error {
 	msg := network.DataUpdate{}
 	err := json.Unmarshal(body, &msg)
 	if err != nil {
 		return fmt.Errorf("message body parsing error: %w", err)
 	}
 
-	return mc.thingInteractor.UpdateData(authorization, msg.ID, msg.Data)
+	return mc.thingInteractor.UpdateData(ctx, authorization, msg.ID, msg.CommandID, msg.Data)
 }
 
 // PublishData handles the publish data request and execute its use case
-func (mc *ThingController) PublishData(body []byte, authorization string) error {
+func (mc *ThingController) PublishData(ctx context.Context, body []byte, authorization string) error {
 	msg := network.DataSent{}
 	err := json.Unmarshal(body, &msg)
 	if err != nil {
 		return fmt.Errorf("message body parsing error: %w", err)
 	}
 
-	return mc.thingInteractor.PublishData(authorization, msg.ID, msg.Data)
+	return mc.thingInteractor.PublishData(ctx, authorization, msg.ID, msg.Data)
+}
+
+// AckCommand handles the command acknowledgement sent by the thing
+func (mc *ThingController) AckCommand(ctx context.Context, body []byte, authorization string) error {
+	msg := network.CommandAck{}
+	err := json.Unmarshal(body, &msg)
+	if err != nil {
+		return fmt.Errorf("message body parsing error: %w", err)
+	}
+
+	var failure error
+	if msg.Error != nil {
+		failure = errors.New(*msg.Error)
+	}
+
+	return mc.thingInteractor.AckCommand(ctx, authorization, msg.ID, msg.CommandID, failure)
+}
+
+// CommandStatus handles the command status request and execute its use case
+func (mc *ThingController) CommandStatus(ctx context.Context, body []byte, authorization, replyTo, corrID string) error {
+	var commandStatusReq network.CommandStatusRequest
+	err := json.Unmarshal(body, &commandStatusReq)
+	if err != nil {
+		mc.logger.Error(err)
+		return err
+	}
+
+	mc.logger.Info("command status request received")
+	command, err := mc.thingInteractor.CommandStatus(ctx, authorization, commandStatusReq.ID)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
+	sendErr := mc.sender.SendCommandStatus(command, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
+}
+
+// ListCommands handles the request to list the commands queued to a thing
+func (mc *ThingController) ListCommands(ctx context.Context, body []byte, authorization, replyTo, corrID string) error {
+	var commandListReq network.CommandListRequest
+	err := json.Unmarshal(body, &commandListReq)
+	if err != nil {
+		mc.logger.Error(err)
+		return err
+	}
+
+	mc.logger.Info("list commands request received")
+	commands, err := mc.thingInteractor.ListCommands(ctx, authorization, commandListReq.ID)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
+	sendErr := mc.sender.SendCommandList(commandListReq.ID, commands, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
+}
+
+// CancelCommand handles the request to cancel a command queued to a thing
+func (mc *ThingController) CancelCommand(ctx context.Context, body []byte, authorization, replyTo, corrID string) error {
+	var commandCancelReq network.CommandCancelRequest
+	err := json.Unmarshal(body, &commandCancelReq)
+	if err != nil {
+		mc.logger.Error(err)
+		return err
+	}
+
+	mc.logger.Info("cancel command request received")
+	err = mc.thingInteractor.CancelCommand(ctx, authorization, commandCancelReq.ID, commandCancelReq.CommandID)
+	if interactors.WillRetry(ctx, err) {
+		return err
+	}
+	sendErr := mc.sender.SendCommandCancel(commandCancelReq.ID, commandCancelReq.CommandID, replyTo, corrID, err)
+	if sendErr != nil {
+		return fmt.Errorf("error sending response: %v: %w", err, sendErr)
+	}
+
+	return err
 }
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/interactors"
+	"github.com/gorilla/mux"
+)
+
+// UpdateThingRequest represents the received parameters for UpdateThing operation. The
+// name and metadata are kept when they aren't provided.
+type UpdateThingRequest struct {
+	Name     string             `json:"name,omitempty"`
+	Metadata *entities.Metadata `json:"metadata,omitempty"`
+}
+
+// DetailedErrorResponse represents the response to be sent to the request
+type DetailedErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// UpdateThing godoc
+// @Summary Update a thing's name and metadata
+// @Produce json
+// @Accept  json
+// @Param Authorization header string true "User or application token"
+// @Param id path string true "Thing's ID"
+// @Param thing body UpdateThingRequest true "Thing's name and metadata"
+// @Success 200 {object} entities.Thing "Updated thing"
+// @Failure 400 {object} DetailedErrorResponse "Invalid name or metadata"
+// @Failure 401 {object} DetailedErrorResponse "Invalid credentials"
+// @Failure 404 {object} DetailedErrorResponse "Thing not found"
+// @Failure 422 {object} DetailedErrorResponse "Invalid request format"
+// @Failure 500 {string} string "Internal server error"
+// @Router /things/{id} [patch]
+// UpdateThing handles the server request to update a thing, which is also published
+// through the device.updated event
+func (mc *ThingController) UpdateThing(w http.ResponseWriter, r *http.Request) {
+	var req UpdateThingRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		mc.logger.Error("failed to parse request body")
+		mc.writeResponse(w, http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	thing, err := mc.thingInteractor.Update(r.Context(), r.Header.Get("Authorization"), id, req.Name, req.Metadata)
+	if err != nil {
+		mc.logger.Errorf("failed to update thing %s: %s", id, err)
+		der := &DetailedErrorResponse{err.Error()}
+		mc.writeResponse(w, mapErrorToStatusCode(err), der)
+		return
+	}
+
+	mc.logger.Infof("thing %s updated", id)
+	mc.writeResponse(w, http.StatusOK, thing)
+}
+
+func (mc *ThingController) writeResponse(w http.ResponseWriter, statusCode int, msg interface{}) {
+	if msg != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(statusCode)
+
+	if msg == nil {
+		return
+	}
+
+	js, err := json.Marshal(msg)
+	if err != nil {
+		mc.logger.Errorf("unable to marshal json: %s", err)
+		return
+	}
+
+	_, err = w.Write(js)
+	if err != nil {
+		mc.logger.Errorf("unable to write to connection HTTP: %s", err)
+	}
+}
+
+func mapErrorToStatusCode(err error) int {
+	switch {
+	case errors.Is(err, interactors.ErrUpdateNotProvided),
+		errors.Is(err, interactors.ErrNameInvalid),
+		errors.Is(err, interactors.ErrTagInvalid),
+		errors.Is(err, interactors.ErrLocationInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, interactors.ErrAuthNotProvided),
+		errors.Is(err, entities.ErrThingUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, entities.ErrThingNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
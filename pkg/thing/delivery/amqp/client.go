@@ -1,6 +1,9 @@
 package amqp
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
@@ -13,53 +16,88 @@ const (
 	exchangeDataPublishedType = "fanout"
 	registerOutKey            = "device.registered"
 	unregisterOutKey          = "device.unregistered"
+	bulkRegisterOutKey        = "device.registered.bulk"
+	bulkUnregisterOutKey      = "device.unregistered.bulk"
 	configOutKey              = "device.config.updated"
+	metadataOutKey            = "device.metadata.updated"
+	updateOutKey              = "device.updated"
 	updateDataKey             = "data.update"
 	requestDataKey            = "data.request"
+	commandCompletedKey       = "command.completed"
+	commandTimeoutKey         = "command.timeout"
+	sessionExpiredKey         = "session.expired"
 	dataExpirationTime        = "86400000" // 1 day in milliseconds
 )
 
-// Publisher provides methods to send events to the clients
+// Publisher provides methods to send events to the clients. A nil error means the
+// broker has confirmed the message was received.
 type Publisher interface {
 	PublishRegisteredDevice(thingID, name, token string, err error) error
 	PublishUnregisteredDevice(thingID, token string, err error) error
+	PublishBulkRegisteredDevices(results []entities.BulkResult, err error) error
+	PublishBulkUnregisteredDevices(results []entities.BulkResult, token string, err error) error
 	PublishUpdatedConfig(thingID string, config []entities.Config, changed bool, err error) error
-	PublishUpdateData(thingID string, data []entities.Data) error
-	PublishRequestData(thingID string, sensorIds []int) error
+	PublishUpdatedMetadata(thingID string, metadata entities.Metadata, err error) error
+	PublishUpdatedDevice(thingID, name string, metadata entities.Metadata, err error) error
+	PublishUpdateData(thingID, commandID string, data []entities.Data) error
+	PublishRequestData(thingID, commandID string, sensorIds []int) error
+
+	// Publish the result of the commands sent to the things
+	PublishCommandCompleted(command entities.Command) error
+	PublishCommandTimeout(command entities.Command) error
 
 	// Publish data in broadcast mode to all clients within the cluster
 	PublishBroadcastData(thingID, token string, data []entities.Data) error
 
 	// Publish data to specific users according to their session IDs
 	PublishSessionData(thingID, token, sessionID string, data []entities.Data) error
+
+	// Publish the sessions that expired and delete the exchanges where their data was
+	// published
+	PublishSessionExpired(sessionID string) error
+	DeleteSessionExchange(sessionID string, ifUnused bool) error
 }
 
-// Sender represents the operations to send commands response
+// Sender represents the operations to send commands response. A nil error means the
+// broker has confirmed the response was received.
 type Sender interface {
 	SendAuthResponse(thingID, replyTo, corrID string, err error) error
-	SendListResponse(things []*entities.Thing, replyTo, corrID string, err error) error
+	SendListResponse(things []*entities.Thing, total int, replyTo, corrID string, err error) error
+
+	// Reply to the requestor of the commands that also have their result broadcasted
+	SendRegisteredDevice(thingID, name, token, replyTo, corrID string, err error) error
+	SendUnregisteredDevice(thingID, replyTo, corrID string, err error) error
+	SendBulkRegisteredDevices(results []entities.BulkResult, replyTo, corrID string, err error) error
+	SendBulkUnregisteredDevices(results []entities.BulkResult, replyTo, corrID string, err error) error
+	SendUpdatedConfig(thingID string, config []entities.Config, changed bool, replyTo, corrID string, err error) error
+	SendUpdatedMetadata(thingID string, metadata entities.Metadata, replyTo, corrID string, err error) error
+	SendUpdatedDevice(thingID, name string, metadata entities.Metadata, replyTo, corrID string, err error) error
+
+	SendCommandStatus(command *entities.Command, replyTo, corrID string, err error) error
+	SendCommandList(thingID string, commands []entities.Command, replyTo, corrID string, err error) error
+	SendCommandCancel(thingID, commandID, replyTo, corrID string, err error) error
 }
 
 // msgClientPublisher handle messages received from a service
 type msgClientPublisher struct {
-	logger logging.Logger
-	amqp   *network.Amqp
+	logger    logging.Logger
+	transport network.Transport
 }
 
 // commandSender handle messages received from a service
 type commandSender struct {
-	logger logging.Logger
-	amqp   *network.Amqp
+	logger    logging.Logger
+	transport network.Transport
 }
 
 // NewMsgClientPublisher constructs the msgClientPublisher
-func NewMsgClientPublisher(logger logging.Logger, amqp *network.Amqp) Publisher {
-	return &msgClientPublisher{logger, amqp}
+func NewMsgClientPublisher(logger logging.Logger, transport network.Transport) Publisher {
+	return &msgClientPublisher{logger, transport}
 }
 
 // NewCommandSender creates a new commandSender instance
-func NewCommandSender(logger logging.Logger, amqp *network.Amqp) Sender {
-	return &commandSender{logger, amqp}
+func NewCommandSender(logger logging.Logger, transport network.Transport) Sender {
+	return &commandSender{logger, transport}
 }
 
 // PublishRegisteredDevice publishes the registered device's credentials to the device registration queue
@@ -68,7 +106,7 @@ func (mp *msgClientPublisher) PublishRegisteredDevice(thingID, name, token strin
 	errMsg := getErrMsg(err)
 	msg := network.NewMessage(network.DeviceRegisteredResponse{ID: thingID, Name: name, Token: token, Error: errMsg})
 
-	return mp.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, registerOutKey, msg, nil)
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, registerOutKey, msg, nil)
 }
 
 // PublishUnregisteredDevice publishes the unregistered device's id and error message to the device unregistered queue
@@ -78,7 +116,24 @@ func (mp *msgClientPublisher) PublishUnregisteredDevice(thingID, token string, e
 	msg := network.NewMessage(network.DeviceUnregisteredResponse{ID: thingID, Error: errMsg})
 	options := &network.MessageOptions{Authorization: token}
 
-	return mp.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, unregisterOutKey, msg, options)
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, unregisterOutKey, msg, options)
+}
+
+// PublishBulkRegisteredDevices publishes the result of every device of a bulk registration
+func (mp *msgClientPublisher) PublishBulkRegisteredDevices(results []entities.BulkResult, err error) error {
+	mp.logger.Debug("sending bulk registered response")
+	msg := network.NewMessage(getBulkRegisteredResponse(results, err))
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, bulkRegisterOutKey, msg, nil)
+}
+
+// PublishBulkUnregisteredDevices publishes the result of every device of a bulk unregistration
+func (mp *msgClientPublisher) PublishBulkUnregisteredDevices(results []entities.BulkResult, token string, err error) error {
+	mp.logger.Debug("sending bulk unregistered response")
+	msg := network.NewMessage(getBulkUnregisteredResponse(results, err))
+	options := &network.MessageOptions{Authorization: token}
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, bulkUnregisterOutKey, msg, options)
 }
 
 // PublishUpdatedConfig sends the updated config response
@@ -87,25 +142,92 @@ func (mp *msgClientPublisher) PublishUpdatedConfig(thingID string, config []enti
 	errMsg := getErrMsg(err)
 	msg := network.NewMessage(network.ConfigUpdatedResponse{ID: thingID, Config: config, Changed: changed, Error: errMsg})
 
-	return mp.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, configOutKey, msg, nil)
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, configOutKey, msg, nil)
 }
 
-// PublishRequestData sends request data command
-func (mp *msgClientPublisher) PublishRequestData(thingID string, sensorIds []int) error {
+// PublishUpdatedMetadata sends the updated metadata response
+func (mp *msgClientPublisher) PublishUpdatedMetadata(thingID string, metadata entities.Metadata, err error) error {
+	mp.logger.Debug("sending update metadata response")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.MetadataUpdatedResponse{ID: thingID, Metadata: metadata, Error: errMsg})
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, metadataOutKey, msg, nil)
+}
+
+// PublishUpdatedDevice publishes the updated device's name and metadata
+func (mp *msgClientPublisher) PublishUpdatedDevice(thingID, name string, metadata entities.Metadata, err error) error {
+	mp.logger.Debug("sending updated device response")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.DeviceUpdatedResponse{ID: thingID, Name: name, Metadata: metadata, Error: errMsg})
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, updateOutKey, msg, nil)
+}
+
+// PublishRequestData sends request data command. The command is published as mandatory,
+// so entities.ErrThingOffline is returned if there is no connector listening to the
+// thing's commands.
+func (mp *msgClientPublisher) PublishRequestData(thingID, commandID string, sensorIds []int) error {
 	mp.logger.Debug("sending request data request")
-	msg := network.NewMessage(network.DataRequest{ID: thingID, SensorIds: sensorIds})
+	msg := network.NewMessage(network.DataRequest{ID: thingID, CommandID: commandID, SensorIds: sensorIds})
 	routingKey := "device." + thingID + "." + requestDataKey
 
-	return mp.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, routingKey, msg, nil)
+	err := mp.publishCommand(routingKey, msg)
+	if err != nil {
+		if errors.Is(err, network.ErrPublishUnroutable) {
+			return fmt.Errorf("request data command not delivered: %v: %w", err, entities.ErrThingOffline)
+		}
+		return fmt.Errorf("request data command not delivered: %w", err)
+	}
+
+	return nil
 }
 
-// PublishUpdateData send update data command
-func (mp *msgClientPublisher) PublishUpdateData(thingID string, data []entities.Data) error {
+// PublishUpdateData send update data command. The command is published as mandatory,
+// so entities.ErrThingOffline is returned if there is no connector listening to the
+// thing's commands.
+func (mp *msgClientPublisher) PublishUpdateData(thingID, commandID string, data []entities.Data) error {
 	mp.logger.Debug("sending update data request")
-	msg := network.NewMessage(network.DataUpdate{ID: thingID, Data: data})
+	msg := network.NewMessage(network.DataUpdate{ID: thingID, CommandID: commandID, Data: data})
 	routingKey := "device." + thingID + "." + updateDataKey
 
-	return mp.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, routingKey, msg, nil)
+	err := mp.publishCommand(routingKey, msg)
+	if err != nil {
+		if errors.Is(err, network.ErrPublishUnroutable) {
+			return fmt.Errorf("update data command not delivered: %v: %w", err, entities.ErrThingOffline)
+		}
+		return fmt.Errorf("update data command not delivered: %w", err)
+	}
+
+	return nil
+}
+
+// publishCommand publishes the command to the thing as mandatory. The transports that
+// don't support mandatory messages, e.g. MQTT, can't report an offline thing, so the
+// command is published to them as a regular message.
+func (mp *msgClientPublisher) publishCommand(routingKey string, msg network.MessageSerializer) error {
+	options := &network.MessageOptions{Mandatory: true}
+	err := mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, routingKey, msg, options)
+	if errors.Is(err, network.ErrUnsupported) {
+		return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, routingKey, msg, nil)
+	}
+
+	return err
+}
+
+// PublishCommandCompleted publishes the command that was completed or failed by the thing
+func (mp *msgClientPublisher) PublishCommandCompleted(command entities.Command) error {
+	mp.logger.Debug("sending command completed event")
+	msg := network.NewMessage(command)
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, commandCompletedKey, msg, nil)
+}
+
+// PublishCommandTimeout publishes the command that the thing didn't act on in time
+func (mp *msgClientPublisher) PublishCommandTimeout(command entities.Command) error {
+	mp.logger.Debug("sending command timeout event")
+	msg := network.NewMessage(command)
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, commandTimeoutKey, msg, nil)
 }
 
 // SendAuthResponse sends the auth thing status response
@@ -115,17 +237,115 @@ func (cs *commandSender) SendAuthResponse(thingID string, replyTo, corrID string
 	msg := network.NewMessage(network.DeviceAuthResponse{ID: thingID, Error: errMsg})
 	options := &network.MessageOptions{CorrelationID: corrID}
 
-	return cs.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
 }
 
 // SendListResponse sends the list devices command response
-func (cs *commandSender) SendListResponse(things []*entities.Thing, replyTo, corrID string, err error) error {
+func (cs *commandSender) SendListResponse(things []*entities.Thing, total int, replyTo, corrID string, err error) error {
 	cs.logger.Debug("sending list devices response")
 	errMsg := getErrMsg(err)
-	msg := network.NewMessage(network.DeviceListResponse{Things: things, Error: errMsg})
+	msg := network.NewMessage(network.DeviceListResponse{Things: things, Total: total, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendRegisteredDevice sends the register device response to the requestor
+func (cs *commandSender) SendRegisteredDevice(thingID, name, token, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending register device reply")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.DeviceRegisteredResponse{ID: thingID, Name: name, Token: token, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendUnregisteredDevice sends the unregister device response to the requestor
+func (cs *commandSender) SendUnregisteredDevice(thingID, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending unregister device reply")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.DeviceUnregisteredResponse{ID: thingID, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendBulkRegisteredDevices sends the result of every device of a bulk registration to the requestor
+func (cs *commandSender) SendBulkRegisteredDevices(results []entities.BulkResult, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending bulk register devices reply")
+	msg := network.NewMessage(getBulkRegisteredResponse(results, err))
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendBulkUnregisteredDevices sends the result of every device of a bulk unregistration to the requestor
+func (cs *commandSender) SendBulkUnregisteredDevices(results []entities.BulkResult, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending bulk unregister devices reply")
+	msg := network.NewMessage(getBulkUnregisteredResponse(results, err))
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendUpdatedConfig sends the update config response to the requestor
+func (cs *commandSender) SendUpdatedConfig(thingID string, config []entities.Config, changed bool, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending update config reply")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.ConfigUpdatedResponse{ID: thingID, Config: config, Changed: changed, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendUpdatedMetadata sends the update metadata response to the requestor
+func (cs *commandSender) SendUpdatedMetadata(thingID string, metadata entities.Metadata, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending update metadata reply")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.MetadataUpdatedResponse{ID: thingID, Metadata: metadata, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendUpdatedDevice sends the update device response to the requestor
+func (cs *commandSender) SendUpdatedDevice(thingID, name string, metadata entities.Metadata, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending update device reply")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.DeviceUpdatedResponse{ID: thingID, Name: name, Metadata: metadata, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendCommandStatus sends the command status response
+func (cs *commandSender) SendCommandStatus(command *entities.Command, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending command status response")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.CommandStatusResponse{Command: command, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendCommandList sends the list of commands queued to the thing
+func (cs *commandSender) SendCommandList(thingID string, commands []entities.Command, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending command list response")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.CommandListResponse{ID: thingID, Commands: commands, Error: errMsg})
+	options := &network.MessageOptions{CorrelationID: corrID}
+
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+}
+
+// SendCommandCancel sends the cancel command response
+func (cs *commandSender) SendCommandCancel(thingID, commandID, replyTo, corrID string, err error) error {
+	cs.logger.Debug("sending command cancel response")
+	errMsg := getErrMsg(err)
+	msg := network.NewMessage(network.CommandCancelResponse{ID: thingID, CommandID: commandID, Error: errMsg})
 	options := &network.MessageOptions{CorrelationID: corrID}
 
-	return cs.amqp.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
+	return cs.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, replyTo, msg, options)
 }
 
 // PublishBroadcastData publishes thing's data to all consumers
@@ -134,7 +354,7 @@ func (mp *msgClientPublisher) PublishBroadcastData(thingID, token string, data [
 	msg := network.NewMessage(network.DataSent{ID: thingID, Data: data})
 	options := &network.MessageOptions{Authorization: token, Expiration: dataExpirationTime}
 
-	return mp.amqp.PublishPersistentMessage(exchangeDataPublished, exchangeDataPublishedType, "", msg, options)
+	return mp.transport.PublishPersistentMessage(exchangeDataPublished, exchangeDataPublishedType, "", msg, options)
 }
 
 // PublishSessionData publishes thing's data to its owner based on a session ID
@@ -142,9 +362,58 @@ func (mp *msgClientPublisher) PublishSessionData(thingID, token, sessionID strin
 	mp.logger.Debug("publishing session data")
 	msg := network.NewMessage(network.DataSent{ID: thingID, Data: data})
 	options := &network.MessageOptions{Expiration: dataExpirationTime}
-	topic := "data." + sessionID + ".published"
 
-	return mp.amqp.PublishPersistentMessage(topic, exchangeDataPublishedType, "", msg, options)
+	return mp.transport.PublishPersistentMessage(sessionExchange(sessionID), exchangeDataPublishedType, "", msg, options)
+}
+
+// PublishSessionExpired publishes the session that expired and no longer receives data
+func (mp *msgClientPublisher) PublishSessionExpired(sessionID string) error {
+	mp.logger.Debug("publishing expired session")
+	msg := network.NewMessage(network.SessionExpired{ID: sessionID})
+
+	return mp.transport.PublishPersistentMessage(exchangeDevice, exchangeDeviceType, sessionExpiredKey, msg, nil)
+}
+
+// DeleteSessionExchange deletes the exchange where the session's data is published. When
+// ifUnused is true and a queue bound to the exchange has consumers, it isn't deleted and
+// entities.ErrSessionConsumed is returned, or entities.ErrSessionConsumersUnknown if the
+// transport can't verify it.
+func (mp *msgClientPublisher) DeleteSessionExchange(sessionID string, ifUnused bool) error {
+	err := mp.transport.DeleteExchange(sessionExchange(sessionID), ifUnused)
+	if err != nil {
+		if errors.Is(err, network.ErrExchangeInUse) {
+			return fmt.Errorf("session's exchange not deleted: %v: %w", err, entities.ErrSessionConsumed)
+		}
+		if errors.Is(err, network.ErrUnsupported) {
+			return fmt.Errorf("session's exchange not deleted: %v: %w", err, entities.ErrSessionConsumersUnknown)
+		}
+		return fmt.Errorf("session's exchange not deleted: %w", err)
+	}
+
+	return nil
+}
+
+// sessionExchange returns the exchange where the session's data is published
+func sessionExchange(sessionID string) string {
+	return "data." + sessionID + ".published"
+}
+
+func getBulkRegisteredResponse(results []entities.BulkResult, err error) network.DeviceBulkRegisteredResponse {
+	devices := make([]network.DeviceRegisteredResponse, len(results))
+	for i, r := range results {
+		devices[i] = network.DeviceRegisteredResponse{ID: r.ID, Name: r.Name, Token: r.Token, Error: getErrMsg(r.Err)}
+	}
+
+	return network.DeviceBulkRegisteredResponse{Devices: devices, Error: getErrMsg(err)}
+}
+
+func getBulkUnregisteredResponse(results []entities.BulkResult, err error) network.DeviceBulkUnregisteredResponse {
+	devices := make([]network.DeviceUnregisteredResponse, len(results))
+	for i, r := range results {
+		devices[i] = network.DeviceUnregisteredResponse{ID: r.ID, Error: getErrMsg(r.Err)}
+	}
+
+	return network.DeviceBulkUnregisteredResponse{Devices: devices, Error: getErrMsg(err)}
 }
 
 func getErrMsg(err error) *string {
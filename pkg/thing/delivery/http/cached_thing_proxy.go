@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+type cachedThingProxy struct {
+	logger logging.Logger
+	proxy  ThingProxy
+	cache  cache.ThingCache
+}
+
+// NewCachedThingProxy creates a ThingProxy that caches the things obtained through proxy,
+// avoiding to fetch every thing from the things service on each Get. The cached things are
+// invalidated when they are created, removed or updated, and a thing invalidated while it's
+// fetched isn't cached.
+// Cache failures are logged and the things service is used instead.
+func NewCachedThingProxy(logger logging.Logger, proxy ThingProxy, thingCache cache.ThingCache) ThingProxy {
+	return &cachedThingProxy{logger, proxy, thingCache}
+}
+
+// Create registers a new thing and invalidates its cached metadata.
+func (p *cachedThingProxy) Create(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	token, err := p.proxy.Create(ctx, id, name, authorization, metadata)
+	p.invalidate(id)
+	return token, err
+}
+
+// Recreate replaces a registered thing and invalidates its cached metadata.
+func (p *cachedThingProxy) Recreate(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	token, err := p.proxy.Recreate(ctx, id, name, authorization, metadata)
+	p.invalidate(id)
+	return token, err
+}
+
+// UpdateConfig updates the thing's config and invalidates its cached metadata.
+func (p *cachedThingProxy) UpdateConfig(ctx context.Context, authorization, ID string, configList []entities.Config) error {
+	err := p.proxy.UpdateConfig(ctx, authorization, ID, configList)
+	p.invalidate(ID)
+	return err
+}
+
+// UpdateMetadata updates the thing's metadata and invalidates its cached metadata.
+func (p *cachedThingProxy) UpdateMetadata(ctx context.Context, authorization, ID string, metadata entities.Metadata) error {
+	err := p.proxy.UpdateMetadata(ctx, authorization, ID, metadata)
+	p.invalidate(ID)
+	return err
+}
+
+// Update updates the thing's name and metadata and invalidates its cached metadata.
+func (p *cachedThingProxy) Update(ctx context.Context, authorization, ID, name string, metadata entities.Metadata) error {
+	err := p.proxy.Update(ctx, authorization, ID, name, metadata)
+	p.invalidate(ID)
+	return err
+}
+
+// List returns the registered things, which aren't cached.
+func (p *cachedThingProxy) List(ctx context.Context, authorization string) ([]*entities.Thing, error) {
+	return p.proxy.List(ctx, authorization)
+}
+
+// Get retrieves the thing from the cache, falling back to the things service when it
+// isn't cached.
+func (p *cachedThingProxy) Get(ctx context.Context, authorization, ID string) (*entities.Thing, error) {
+	thing, err := p.cache.Get(ctx, authorization, ID)
+	if err != nil {
+		p.logger.Errorf("error getting thing %s from cache: %s", ID, err)
+	}
+	if thing != nil {
+		return thing, nil
+	}
+
+	// the version is read before fetching, so the thing isn't cached if it's changed meanwhile
+	version, versionErr := p.cache.Version(ctx, ID)
+	if versionErr != nil {
+		p.logger.Errorf("error getting thing %s version from cache: %s", ID, versionErr)
+	}
+
+	thing, err = p.proxy.Get(ctx, authorization, ID)
+	if err != nil {
+		return nil, err
+	}
+	if versionErr != nil {
+		return thing, nil
+	}
+
+	err = p.cache.Save(ctx, authorization, thing, version)
+	if err != nil {
+		p.logger.Errorf("error caching thing %s: %s", ID, err)
+	}
+
+	return thing, nil
+}
+
+// Remove removes the thing and invalidates its cached metadata.
+func (p *cachedThingProxy) Remove(ctx context.Context, authorization, ID string) error {
+	err := p.proxy.Remove(ctx, authorization, ID)
+	p.invalidate(ID)
+	return err
+}
+
+// invalidate removes the cached thing even if the request was canceled, since the thing
+// could have been changed anyway
+func (p *cachedThingProxy) invalidate(id string) {
+	err := p.cache.Delete(context.Background(), id)
+	if err != nil {
+		p.logger.Errorf("error invalidating cached thing %s: %s", id, err)
+	}
+}
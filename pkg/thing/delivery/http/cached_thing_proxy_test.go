@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	authorization = "authorization-token"
+	thingID       = "fc3fcf912d0c290a"
+)
+
+var thingExample = &entities.Thing{ID: thingID, Token: "thing-token", Name: "thing"}
+
+type cachedThingProxyTestCase struct {
+	name          string
+	act           func(p ThingProxy)
+	expectedFetch int
+}
+
+var cachedThingProxyCases = []cachedThingProxyTestCase{
+	{
+		"thing fetched once while cached",
+		func(_ ThingProxy) {},
+		1,
+	},
+	{
+		"thing fetched again after registered",
+		func(p ThingProxy) {
+			_, _ = p.Create(context.Background(), thingID, "thing", authorization, entities.Metadata{})
+		},
+		2,
+	},
+	{
+		"thing fetched again after config updated",
+		func(p ThingProxy) { _ = p.UpdateConfig(context.Background(), authorization, thingID, nil) },
+		2,
+	},
+	{
+		"thing fetched again after metadata updated",
+		func(p ThingProxy) {
+			_ = p.UpdateMetadata(context.Background(), authorization, thingID, entities.Metadata{})
+		},
+		2,
+	},
+	{
+		"thing fetched again after updated",
+		func(p ThingProxy) {
+			_ = p.Update(context.Background(), authorization, thingID, "renamed", entities.Metadata{})
+		},
+		2,
+	},
+	{
+		"thing fetched again after unregistered",
+		func(p ThingProxy) { _ = p.Remove(context.Background(), authorization, thingID) },
+		2,
+	},
+}
+
+func TestCachedThingProxy(t *testing.T) {
+	for _, tc := range cachedThingProxyCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeProxy := &mocks.FakeThingProxy{}
+			fakeProxy.On("Get", authorization, thingID).Return(thingExample, nil)
+			fakeProxy.On("Create", thingID, "thing", authorization, entities.Metadata{}).Return("thing-token", nil)
+			fakeProxy.On("UpdateConfig", authorization, thingID, []entities.Config(nil)).Return(nil)
+			fakeProxy.On("UpdateMetadata", authorization, thingID, entities.Metadata{}).Return(nil)
+			fakeProxy.On("Update", authorization, thingID, "renamed", entities.Metadata{}).Return(nil)
+			fakeProxy.On("Remove", authorization, thingID).Return(nil)
+			proxy := NewCachedThingProxy(&mocks.FakeLogger{}, fakeProxy, cache.NewMemoryThingCache(time.Minute))
+
+			thing, err := proxy.Get(context.Background(), authorization, thingID)
+			assert.NoError(t, err)
+			assert.Equal(t, thingExample, thing)
+
+			tc.act(proxy)
+
+			thing, err = proxy.Get(context.Background(), authorization, thingID)
+			assert.NoError(t, err)
+			assert.Equal(t, thingExample, thing)
+			fakeProxy.AssertNumberOfCalls(t, "Get", tc.expectedFetch)
+		})
+	}
+}
+
+func TestCachedThingProxyDoesntCacheErrors(t *testing.T) {
+	fakeProxy := &mocks.FakeThingProxy{}
+	fakeProxy.On("Get", authorization, thingID).Return((*entities.Thing)(nil), entities.ErrThingNotFound)
+	proxy := NewCachedThingProxy(&mocks.FakeLogger{}, fakeProxy, cache.NewMemoryThingCache(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		_, err := proxy.Get(context.Background(), authorization, thingID)
+		assert.True(t, errors.Is(err, entities.ErrThingNotFound))
+	}
+	fakeProxy.AssertNumberOfCalls(t, "Get", 2)
+}
+
+func TestCachedThingProxyDoesntCacheThingChangedWhileFetched(t *testing.T) {
+	fakeProxy := &mocks.FakeThingProxy{}
+	fakeProxy.On("Remove", authorization, thingID).Return(nil)
+	proxy := NewCachedThingProxy(&mocks.FakeLogger{}, fakeProxy, cache.NewMemoryThingCache(time.Minute))
+	fakeProxy.On("Get", authorization, thingID).
+		Run(func(_ mock.Arguments) { _ = proxy.Remove(context.Background(), authorization, thingID) }).
+		Return(thingExample, nil).
+		Once()
+	fakeProxy.On("Get", authorization, thingID).Return(thingExample, nil)
+
+	for i := 0; i < 2; i++ {
+		thing, err := proxy.Get(context.Background(), authorization, thingID)
+		assert.NoError(t, err)
+		assert.Equal(t, thingExample, thing)
+	}
+	fakeProxy.AssertNumberOfCalls(t, "Get", 2)
+}
@@ -0,0 +1,293 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/jwt"
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// tokenSize is the number of random bytes of the tokens issued to the things
+	tokenSize = 16
+	// openTimeout is the max time to wait for the lock of the database file
+	openTimeout = 5 * time.Second
+)
+
+// thingsBucket is the root bucket, which has a nested bucket of things for each owner
+var thingsBucket = []byte("things")
+
+type embeddedThingProxy struct {
+	logger   logging.Logger
+	db       *bolt.DB
+	verifier *jwt.Verifier
+}
+
+// NewEmbeddedThingProxy creates a ThingProxy that stores the things in a local database
+// file, at path, instead of the Mainflux Things service. The things are scoped by the email
+// of their owner, obtained from the authorization token whose signature is checked by the
+// verifier, and receive a token issued by the proxy when they are created.
+func NewEmbeddedThingProxy(logger logging.Logger, path string, verifier *jwt.Verifier) (*embeddedThingProxy, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error opening things database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(thingsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating things bucket: %w", err)
+	}
+
+	logger.Debug("things stored in " + path)
+	return &embeddedThingProxy{logger, db, verifier}, nil
+}
+
+// Close closes the database file
+func (p *embeddedThingProxy) Close() error {
+	return p.db.Close()
+}
+
+// Create stores a new thing owned by the user and returns the token issued to it.
+func (p *embeddedThingProxy) Create(_ context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.issueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = p.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(thingsBucket).CreateBucketIfNotExists(owner)
+		if err != nil {
+			return err
+		}
+
+		if b.Get([]byte(id)) != nil {
+			return entities.ErrThingExists
+		}
+
+		return p.put(b, &entities.Thing{ID: id, Token: token, Name: name, Metadata: metadata})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Recreate replaces the user's thing by a new one, with the new name and metadata and a new
+// token, in a single transaction.
+func (p *embeddedThingProxy) Recreate(_ context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.issueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		_, err := p.get(b, id)
+		if err != nil {
+			return err
+		}
+
+		return p.put(b, &entities.Thing{ID: id, Token: token, Name: name, Metadata: metadata})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// UpdateConfig replaces the config of the user's thing.
+func (p *embeddedThingProxy) UpdateConfig(_ context.Context, authorization, ID string, configList []entities.Config) error {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return err
+	}
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		thing, err := p.get(b, ID)
+		if err != nil {
+			return err
+		}
+
+		thing.Config = configList
+		return p.put(b, thing)
+	})
+}
+
+// UpdateMetadata replaces the metadata of the user's thing.
+func (p *embeddedThingProxy) UpdateMetadata(_ context.Context, authorization, ID string, metadata entities.Metadata) error {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return err
+	}
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		thing, err := p.get(b, ID)
+		if err != nil {
+			return err
+		}
+
+		thing.Metadata = metadata
+		return p.put(b, thing)
+	})
+}
+
+// Update replaces the name and metadata of the user's thing.
+func (p *embeddedThingProxy) Update(_ context.Context, authorization, ID, name string, metadata entities.Metadata) error {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return err
+	}
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		thing, err := p.get(b, ID)
+		if err != nil {
+			return err
+		}
+
+		thing.Name = name
+		thing.Metadata = metadata
+		return p.put(b, thing)
+	})
+}
+
+// List returns the things owned by the user.
+func (p *embeddedThingProxy) List(_ context.Context, authorization string) ([]*entities.Thing, error) {
+	things := []*entities.Thing{}
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return things, err
+	}
+
+	err = p.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, value []byte) error {
+			thing := &entities.Thing{}
+			err := json.Unmarshal(value, thing)
+			if err != nil {
+				return err
+			}
+
+			thing.Token = "" // the things' tokens aren't listed
+			things = append(things, thing)
+			return nil
+		})
+	})
+
+	return things, err
+}
+
+// Get retrieves the user's thing, including its token.
+func (p *embeddedThingProxy) Get(_ context.Context, authorization, ID string) (*entities.Thing, error) {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	var thing *entities.Thing
+	err = p.db.View(func(tx *bolt.Tx) error {
+		thing, err = p.get(tx.Bucket(thingsBucket).Bucket(owner), ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return thing, nil
+}
+
+// Remove removes the user's thing.
+func (p *embeddedThingProxy) Remove(_ context.Context, authorization, ID string) error {
+	owner, err := p.getOwner(authorization)
+	if err != nil {
+		return err
+	}
+
+	return p.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(thingsBucket).Bucket(owner)
+		_, err := p.get(b, ID)
+		if err != nil {
+			return err
+		}
+
+		return b.Delete([]byte(ID))
+	})
+}
+
+// getOwner returns the email of the user that owns the things accessed with the token. There
+// is no service validating the token, so its signature must be verified.
+func (p *embeddedThingProxy) getOwner(authorization string) ([]byte, error) {
+	email, err := p.verifier.GetEmail(authorization)
+	if err != nil || email == "" {
+		return nil, entities.ErrThingUnauthorized
+	}
+
+	return []byte(email), nil
+}
+
+func (p *embeddedThingProxy) issueToken() (string, error) {
+	token := make([]byte, tokenSize)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", fmt.Errorf("error issuing thing token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func (p *embeddedThingProxy) get(b *bolt.Bucket, id string) (*entities.Thing, error) {
+	if b == nil {
+		return nil, entities.ErrThingNotFound
+	}
+
+	value := b.Get([]byte(id))
+	if value == nil {
+		return nil, entities.ErrThingNotFound
+	}
+
+	thing := &entities.Thing{}
+	err := json.Unmarshal(value, thing)
+	if err != nil {
+		return nil, err
+	}
+
+	return thing, nil
+}
+
+func (p *embeddedThingProxy) put(b *bolt.Bucket, thing *entities.Thing) error {
+	value, err := json.Marshal(thing)
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(thing.ID), value)
+}
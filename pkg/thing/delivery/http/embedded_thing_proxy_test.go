@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/jwt"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUserToken(t *testing.T, email string) string {
+	return newSignedUserToken(t, email, "secret")
+}
+
+func newSignedUserToken(t *testing.T, email, secret string) string {
+	claims := jwt.TokenClaims{Iss: "babeltower", Sub: email}
+	token, err := jwtgo.NewWithClaims(jwtgo.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return token
+}
+
+func newEmbeddedThingProxy(t *testing.T, path string) *embeddedThingProxy {
+	proxy, err := NewEmbeddedThingProxy(&mocks.FakeLogger{}, path, jwt.NewVerifier("secret"))
+	assert.NoError(t, err)
+	return proxy
+}
+
+func TestEmbeddedThingProxy(t *testing.T) {
+	ctx := context.Background()
+	owner := newUserToken(t, "owner@knot.org")
+	other := newUserToken(t, "other@knot.org")
+	config := []entities.Config{{SensorID: 1}}
+	path := filepath.Join(t.TempDir(), "things.db")
+	proxy := newEmbeddedThingProxy(t, path)
+
+	metadata := entities.Metadata{Description: "door lock", Tags: []string{"door"}}
+	token, err := proxy.Create(ctx, thingID, "thing", owner, metadata)
+	assert.NoError(t, err)
+	assert.Len(t, token, 2*tokenSize)
+
+	_, err = proxy.Create(ctx, thingID, "thing", owner, entities.Metadata{})
+	assert.Equal(t, entities.ErrThingExists, err)
+
+	err = proxy.UpdateConfig(ctx, owner, thingID, config)
+	assert.NoError(t, err)
+
+	thing, err := proxy.Get(ctx, owner, thingID)
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.Thing{ID: thingID, Token: token, Name: "thing", Config: config, Metadata: metadata}, thing)
+
+	metadata.FirmwareVersion = "1.0.0"
+	err = proxy.UpdateMetadata(ctx, owner, thingID, metadata)
+	assert.NoError(t, err)
+	err = proxy.Update(ctx, owner, thingID, "renamed", metadata)
+	assert.NoError(t, err)
+
+	things, err := proxy.List(ctx, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, []*entities.Thing{{ID: thingID, Name: "renamed", Config: config, Metadata: metadata}}, things)
+
+	recreated, err := proxy.Recreate(ctx, thingID, "thing", owner, entities.Metadata{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, recreated)
+	thing, err = proxy.Get(ctx, owner, thingID)
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.Thing{ID: thingID, Token: recreated, Name: "thing"}, thing)
+
+	// things are scoped by owner
+	_, err = proxy.Get(ctx, other, thingID)
+	assert.Equal(t, entities.ErrThingNotFound, err)
+	things, err = proxy.List(ctx, other)
+	assert.NoError(t, err)
+	assert.Empty(t, things)
+	assert.Equal(t, entities.ErrThingNotFound, proxy.Remove(ctx, other, thingID))
+
+	_, err = proxy.List(ctx, "invalid-token")
+	assert.Equal(t, entities.ErrThingUnauthorized, err)
+
+	// tokens signed with another secret are forged
+	forged := newSignedUserToken(t, "owner@knot.org", "forged")
+	_, err = proxy.Get(ctx, forged, thingID)
+	assert.Equal(t, entities.ErrThingUnauthorized, err)
+
+	// things are persisted in the database file
+	assert.NoError(t, proxy.Close())
+	proxy = newEmbeddedThingProxy(t, path)
+	defer proxy.Close()
+
+	_, err = proxy.Get(ctx, owner, thingID)
+	assert.NoError(t, err)
+	assert.NoError(t, proxy.Remove(ctx, owner, thingID))
+	_, err = proxy.Get(ctx, owner, thingID)
+	assert.Equal(t, entities.ErrThingNotFound, err)
+}
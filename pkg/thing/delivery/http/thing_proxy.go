@@ -2,16 +2,26 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 	"github.com/google/go-querystring/query"
 )
 
+const (
+	// pageLimit is the max number of things that can be returned in a page
+	pageLimit = 100
+	// concurrentPages is the max number of pages fetched at the same time
+	concurrentPages = 8
+)
+
 type errorConflict struct{ error }
 
 func (err errorConflict) Error() string {
@@ -24,16 +34,21 @@ func (err errorConflict) Error() string {
 // updating of thing's configuration by using the Mainflux metadata capabilities.
 // https://github.com/mainflux/mainflux/blob/0.12.1/things/openapi.yml
 type ThingProxy interface {
-	Create(id, name, authorization string) (string, error)
-	UpdateConfig(authorization, ID string, configList []entities.Config) error
-	List(authorization string) (things []*entities.Thing, err error)
-	Get(authorization, ID string) (*entities.Thing, error)
-	Remove(authorization, ID string) error
+	Create(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error)
+	Recreate(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error)
+	UpdateConfig(ctx context.Context, authorization, ID string, configList []entities.Config) error
+	UpdateMetadata(ctx context.Context, authorization, ID string, metadata entities.Metadata) error
+	Update(ctx context.Context, authorization, ID, name string, metadata entities.Metadata) error
+	List(ctx context.Context, authorization string) (things []*entities.Thing, err error)
+	Get(ctx context.Context, authorization, ID string) (*entities.Thing, error)
+	Remove(ctx context.Context, authorization, ID string) error
 }
 
 type thingProxy struct {
-	url    string
-	logger logging.Logger
+	url             string
+	logger          logging.Logger
+	client          *network.HTTPClient
+	concurrentPages int
 }
 
 type pageFetchSchema struct {
@@ -53,12 +68,16 @@ type thingMetadata struct {
 	Thing knotThing `json:"knot"`
 }
 
+// knotThing is the KNoT representation of the thing in the Mainflux metadata, the optional
+// metadata fields are stored alongside the ID and config.
 type knotThing struct {
 	ID     string            `json:"id"`
 	Config []entities.Config `json:"config,omitempty"`
+	entities.Metadata
 }
 
 type requestInfo struct {
+	ctx           context.Context
 	method        string
 	url           string
 	authorization string
@@ -68,29 +87,31 @@ type requestInfo struct {
 }
 
 type requestOptions struct {
-	Limit  int `url:"limit"`
-	Offset int `url:"offset"`
+	Limit    int    `url:"limit"`
+	Offset   int    `url:"offset"`
+	Metadata string `url:"metadata,omitempty"`
 }
 
 // NewThingProxy creates a new things instance and returns a pointer to the ThingsProxy interface
-// implementation.
-func NewThingProxy(logger logging.Logger, hostname, protocol string, port uint16) *thingProxy {
+// implementation. The requests are sent through the client.
+func NewThingProxy(logger logging.Logger, client *network.HTTPClient, hostname, protocol string, port uint16) *thingProxy {
 	url := fmt.Sprintf("%s://%s:%d", protocol, hostname, port)
 	logger.Debug("things proxy configured to " + url)
-	return &thingProxy{url, logger}
+	return &thingProxy{url, logger, client, concurrentPages}
 }
 
 // Create registers a new thing in the Mainflux platform. It receives the thing's properties and
 // map them to the Mainflux internal representation. As a result, the operation returns the things
 // ID.
-func (p thingProxy) Create(id, name, authorization string) (string, error) {
-	t := p.getThingSchema(id, name, nil)
+func (p thingProxy) Create(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	t := p.getThingSchema(&entities.Thing{ID: id, Name: name, Metadata: metadata})
 	body, err := json.Marshal(t)
 	if err != nil {
 		return "", err
 	}
 
 	requestInfo := &requestInfo{
+		ctx,
 		"POST",
 		p.url + "/things",
 		authorization,
@@ -111,73 +132,113 @@ func (p thingProxy) Create(id, name, authorization string) (string, error) {
 	}
 
 	location := resp.Header.Get("Location")
-	return location[len("/things/"):], nil // get substring after "/things/"
+	if !strings.HasPrefix(location, "/things/") {
+		return "", fmt.Errorf("unexpected location of the created thing: %q", location)
+	}
+
+	return strings.TrimPrefix(location, "/things/"), nil
+}
+
+// Recreate replaces a registered thing by a new one, which receives a new token. The new
+// thing is created before the old one is removed, so the thing isn't lost when it fails to
+// be created, and it's removed again when the old one can't be removed.
+func (p thingProxy) Recreate(ctx context.Context, id, name, authorization string, metadata entities.Metadata) (string, error) {
+	old, err := p.Get(ctx, authorization, id)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.Create(ctx, id, name, authorization, metadata)
+	if err != nil {
+		return "", fmt.Errorf("error creating the new thing: %w", err)
+	}
+
+	err = p.remove(ctx, authorization, old.Token)
+	if err != nil {
+		removeErr := p.remove(ctx, authorization, token)
+		if removeErr != nil {
+			p.logger.Errorf("error removing the new thing %s left duplicated: %s", id, removeErr)
+		}
+		return "", fmt.Errorf("error removing the old thing: %w", err)
+	}
+
+	return token, nil
 }
 
 // UpdateConfig updates the internal thing's representation with the config in the format supported
 // by the KNoT protocol. KNoT Thing config has two data structures: (1) schema and (2) event.
 // (1) represents the sensor semantic models (temperature, voltage, etc).
 // (2) represents the sensor data publishing configuration (interval, custom behavior when data changes, etc).
-func (p thingProxy) UpdateConfig(authorization, ID string, configList []entities.Config) error {
-	t, err := p.Get(authorization, ID)
+func (p thingProxy) UpdateConfig(ctx context.Context, authorization, ID string, configList []entities.Config) error {
+	t, err := p.Get(ctx, authorization, ID)
 	if err != nil {
 		return err
 	}
 
-	rt := p.getThingSchema(t.ID, t.Name, t.Config)
-	rt.Metadata.Thing.Config = configList
-	parsedBody, err := json.Marshal(rt)
+	t.Config = configList
+	return p.update(ctx, authorization, t)
+}
+
+// UpdateMetadata replaces the optional metadata that describes the thing, keeping its config.
+func (p thingProxy) UpdateMetadata(ctx context.Context, authorization, ID string, metadata entities.Metadata) error {
+	t, err := p.Get(ctx, authorization, ID)
 	if err != nil {
 		return err
 	}
 
-	requestInfo := &requestInfo{
-		"PUT",
-		p.url + "/things/" + t.Token,
-		authorization,
-		"application/json",
-		parsedBody,
-		nil,
-	}
+	t.Metadata = metadata
+	return p.update(ctx, authorization, t)
+}
 
-	resp, err := p.sendRequest(requestInfo)
+// Update replaces the thing's name and the optional metadata that describes it, keeping
+// its config.
+func (p thingProxy) Update(ctx context.Context, authorization, ID, name string, metadata entities.Metadata) error {
+	t, err := p.Get(ctx, authorization, ID)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	return p.mapErrorFromStatusCode(resp.StatusCode)
+	t.Name = name
+	t.Metadata = metadata
+	return p.update(ctx, authorization, t)
 }
 
 // List returns the registered things according to the KNoT Cloud representation.
 // The Mainflux Things API blocks requests for a large number of things. Thus,
 // this method paginates over them a returns a single slice of things.
-func (p thingProxy) List(authorization string) ([]*entities.Thing, error) {
+func (p thingProxy) List(ctx context.Context, authorization string) ([]*entities.Thing, error) {
 	things := []*entities.Thing{}
-	pagThings, err := p.getPaginatedThings(authorization)
+	pagThings, err := p.getPaginatedThings(ctx, authorization)
 	if err != nil {
 		return things, err
 	}
 
 	for _, t := range pagThings {
-		things = append(things, &entities.Thing{ID: t.Metadata.Thing.ID, Name: t.Name, Config: t.Metadata.Thing.Config})
+		thing := p.getThing(t)
+		thing.Token = "" // the things' tokens aren't listed
+		things = append(things, thing)
 	}
 
 	return things, err
 }
 
-// Get retrieves an invidual thing from the Mainflux service. It uses the KNoT Thing's ID as filter.
-func (p thingProxy) Get(authorization, ID string) (*entities.Thing, error) {
-	things, err := p.getPaginatedThings(authorization)
+// Get retrieves an invidual thing from the Mainflux service. It uses the KNoT Thing's ID as
+// metadata filter, so only the thing is fetched.
+func (p thingProxy) Get(ctx context.Context, authorization, ID string) (*entities.Thing, error) {
+	metadata, err := json.Marshal(thingMetadata{Thing: knotThing{ID: ID}})
 	if err != nil {
 		return nil, err
 	}
 
-	for i := range things {
-		t := things[i]
+	page, err := p.fetchPage(ctx, authorization, &requestOptions{Limit: pageLimit, Metadata: string(metadata)})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range page.Things {
+		t := page.Things[i]
 		if t.Metadata.Thing.ID == ID {
-			nt := &entities.Thing{ID: ID, Token: t.ID, Name: t.Name, Config: t.Metadata.Thing.Config}
-			return nt, nil
+			return p.getThing(t), nil
 		}
 	}
 
@@ -185,15 +246,21 @@ func (p thingProxy) Get(authorization, ID string) (*entities.Thing, error) {
 }
 
 // Remove removes an individual thing from the Mainflux service. It uses the KNoT Thing's ID as filter.
-func (p thingProxy) Remove(authorization, ID string) error {
-	t, err := p.Get(authorization, ID)
+func (p thingProxy) Remove(ctx context.Context, authorization, ID string) error {
+	t, err := p.Get(ctx, authorization, ID)
 	if err != nil {
 		return err
 	}
 
+	return p.remove(ctx, authorization, t.Token)
+}
+
+// remove removes the thing identified by its token, which is its ID in the Mainflux service
+func (p thingProxy) remove(ctx context.Context, authorization, token string) error {
 	requestInfo := &requestInfo{
+		ctx,
 		"DELETE",
-		p.url + "/things/" + t.Token,
+		p.url + "/things/" + token,
 		authorization,
 		"application/json",
 		nil,
@@ -209,57 +276,124 @@ func (p thingProxy) Remove(authorization, ID string) error {
 	return p.mapErrorFromStatusCode(resp.StatusCode)
 }
 
-func (p thingProxy) getThingSchema(id, name string, configList []entities.Config) thingSchema {
+// update replaces the thing's representation in the Mainflux service
+func (p thingProxy) update(ctx context.Context, authorization string, t *entities.Thing) error {
+	parsedBody, err := json.Marshal(p.getThingSchema(t))
+	if err != nil {
+		return err
+	}
+
+	requestInfo := &requestInfo{
+		ctx,
+		"PUT",
+		p.url + "/things/" + t.Token,
+		authorization,
+		"application/json",
+		parsedBody,
+		nil,
+	}
+
+	resp, err := p.sendRequest(requestInfo)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return p.mapErrorFromStatusCode(resp.StatusCode)
+}
+
+func (p thingProxy) getThingSchema(t *entities.Thing) thingSchema {
 	return thingSchema{
-		Name: name,
+		Name: t.Name,
 		Metadata: thingMetadata{
 			Thing: knotThing{
-				ID:     id,
-				Config: configList,
+				ID:       t.ID,
+				Config:   t.Config,
+				Metadata: t.Metadata,
 			},
 		},
 	}
 }
 
-func (p thingProxy) getPaginatedThings(authorization string) ([]*thingSchema, error) {
+func (p thingProxy) getThing(t *thingSchema) *entities.Thing {
+	return &entities.Thing{
+		ID:       t.Metadata.Thing.ID,
+		Token:    t.ID,
+		Name:     t.Name,
+		Config:   t.Metadata.Thing.Config,
+		Metadata: t.Metadata.Thing.Metadata,
+	}
+}
+
+// getPaginatedThings fetches the first page to know the total of things and then fetches
+// the remaining pages concurrently.
+func (p thingProxy) getPaginatedThings(ctx context.Context, authorization string) ([]*thingSchema, error) {
+	first, err := p.fetchPage(ctx, authorization, &requestOptions{Limit: pageLimit, Offset: 0})
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := 0
+	if first.Total > pageLimit {
+		remaining = (first.Total - 1) / pageLimit
+	}
+
+	pages := make([]*pageFetchSchema, remaining)
+	errs := make([]error, remaining)
+	sem := make(chan struct{}, p.concurrentPages)
+	var wg sync.WaitGroup
+	for i := range pages {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			options := &requestOptions{Limit: pageLimit, Offset: (i + 1) * pageLimit}
+			pages[i], errs[i] = p.fetchPage(ctx, authorization, options)
+		}(i)
+	}
+	wg.Wait()
+
+	things := first.Things
+	for i, page := range pages {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		things = append(things, page.Things...)
+	}
+
+	return things, nil
+}
+
+func (p thingProxy) fetchPage(ctx context.Context, authorization string, options *requestOptions) (*pageFetchSchema, error) {
 	requestInfo := &requestInfo{
+		ctx,
 		"GET",
 		p.url + "/things",
 		authorization,
 		"application/json",
 		nil,
-		&requestOptions{Limit: 100, Offset: 0}, // 100 is the max number of things that can be returned
+		options,
 	}
 
-	var things []*thingSchema
-	keepGoing := true
-	for keepGoing {
-		resp, err := p.sendRequest(requestInfo)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		err = p.mapErrorFromStatusCode(resp.StatusCode)
-		if err != nil {
-			return nil, err
-		}
-
-		page := &pageFetchSchema{}
-		err = json.NewDecoder(resp.Body).Decode(&page)
-		if err != nil {
-			return nil, err
-		}
+	resp, err := p.sendRequest(requestInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		things = append(things, page.Things...)
-		requestInfo.options.Offset += requestInfo.options.Limit
+	err = p.mapErrorFromStatusCode(resp.StatusCode)
+	if err != nil {
+		return nil, err
+	}
 
-		if page.Total == len(things) {
-			keepGoing = false
-		}
+	page := &pageFetchSchema{}
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	if err != nil {
+		return nil, err
 	}
 
-	return things, nil
+	return page, nil
 }
 
 func (p thingProxy) sendRequest(info *requestInfo) (*http.Response, error) {
@@ -269,11 +403,7 @@ func (p thingProxy) sendRequest(info *requestInfo) (*http.Response, error) {
 	}
 	queryString := "?" + values.Encode()
 
-	/**
-	 * Add Timeout in http.Client to avoid blocking the request.
-	 */
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest(info.method, info.url+queryString, bytes.NewBuffer(info.data))
+	req, err := http.NewRequestWithContext(info.ctx, info.method, info.url+queryString, bytes.NewBuffer(info.data))
 	if err != nil {
 		return nil, err
 	}
@@ -281,7 +411,7 @@ func (p thingProxy) sendRequest(info *requestInfo) (*http.Response, error) {
 	req.Header.Set("Authorization", info.authorization)
 	req.Header.Set("Content-Type", info.contentType)
 
-	return client.Do(req)
+	return p.client.Do(req)
 }
 
 func (p thingProxy) mapErrorFromStatusCode(code int) error {
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeThingsServer creates a things service with n things that takes latency to answer
+// each request. It supports the pagination and the metadata filter of the things API, and
+// the updating of the things.
+func newFakeThingsServer(t testing.TB, n int, latency time.Duration) *httptest.Server {
+	things := make([]*thingSchema, n)
+	for i := range things {
+		id := fmt.Sprintf("%016x", i)
+		things[i] = &thingSchema{ID: "token-" + id, Name: "thing", Metadata: thingMetadata{Thing: knotThing{ID: id}}}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+		if r.Method == http.MethodPut {
+			updated := &thingSchema{}
+			_ = json.NewDecoder(r.Body).Decode(updated)
+			for i, thing := range things {
+				if "/things/"+thing.ID == r.URL.Path {
+					updated.ID = thing.ID
+					things[i] = updated
+				}
+			}
+			return
+		}
+
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+
+		filtered := things
+		if metadata := query.Get("metadata"); metadata != "" {
+			filter := thingMetadata{}
+			if err := json.Unmarshal([]byte(metadata), &filter); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			filtered = nil
+			for _, thing := range things {
+				if thing.Metadata.Thing.ID == filter.Thing.ID {
+					filtered = append(filtered, thing)
+				}
+			}
+		}
+
+		page := pageFetchSchema{Total: len(filtered), Offset: offset, Limit: limit, Things: []*thingSchema{}}
+		if offset < len(filtered) {
+			end := offset + limit
+			if end > len(filtered) {
+				end = len(filtered)
+			}
+			page.Things = filtered[offset:end]
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestThingProxy(server *httptest.Server, concurrentPages int) *thingProxy {
+	client := network.NewHTTPClient("things", time.Second, 0, network.NewCircuitBreaker("things", 0, 0, &mocks.FakeLogger{}), &mocks.FakeLogger{})
+	return &thingProxy{server.URL, &mocks.FakeLogger{}, client, concurrentPages}
+}
+
+func TestThingProxyGet(t *testing.T) {
+	proxy := newTestThingProxy(newFakeThingsServer(t, 250, 0), concurrentPages)
+
+	thing, err := proxy.Get(context.Background(), authorization, fmt.Sprintf("%016x", 230))
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.Thing{ID: fmt.Sprintf("%016x", 230), Token: fmt.Sprintf("token-%016x", 230), Name: "thing"}, thing)
+
+	_, err = proxy.Get(context.Background(), authorization, thingID)
+	assert.True(t, errors.Is(err, entities.ErrThingNotFound))
+}
+
+func TestThingProxyUpdate(t *testing.T) {
+	proxy := newTestThingProxy(newFakeThingsServer(t, 10, 0), concurrentPages)
+	id := fmt.Sprintf("%016x", 5)
+	config := []entities.Config{{SensorID: 1}}
+	metadata := entities.Metadata{
+		Description: "door lock",
+		Tags:        []string{"door"},
+		Location:    &entities.Location{Latitude: -8.05, Longitude: -34.9},
+	}
+
+	err := proxy.UpdateMetadata(context.Background(), authorization, id, metadata)
+	assert.NoError(t, err)
+	err = proxy.UpdateConfig(context.Background(), authorization, id, config)
+	assert.NoError(t, err)
+
+	thing, err := proxy.Get(context.Background(), authorization, id)
+	assert.NoError(t, err)
+	assert.Equal(t, config, thing.Config)
+	assert.Equal(t, metadata, thing.Metadata)
+
+	err = proxy.Update(context.Background(), authorization, id, "renamed", entities.Metadata{})
+	assert.NoError(t, err)
+
+	thing, err = proxy.Get(context.Background(), authorization, id)
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.Thing{ID: id, Token: "token-" + id, Name: "renamed", Config: config}, thing)
+}
+
+func TestThingProxyList(t *testing.T) {
+	for _, n := range []int{0, 100, 250} {
+		t.Run(strconv.Itoa(n)+" things", func(t *testing.T) {
+			proxy := newTestThingProxy(newFakeThingsServer(t, n, 0), concurrentPages)
+
+			things, err := proxy.List(context.Background(), authorization)
+			assert.NoError(t, err)
+			assert.Len(t, things, n)
+			for i, thing := range things {
+				assert.Equal(t, fmt.Sprintf("%016x", i), thing.ID)
+			}
+		})
+	}
+}
+
+// newFakeRecreateServer creates a things service with the thing registered with the old
+// token, which fails to remove the things whose token is in failedRemovals, and records
+// the requests it receives
+func newFakeRecreateServer(t *testing.T, failedRemovals ...string) (*httptest.Server, *[]string) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Location", "/things/new-token")
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			for _, token := range failedRemovals {
+				if r.URL.Path == "/things/"+token {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			thing := &thingSchema{ID: "old-token", Name: "thing", Metadata: thingMetadata{Thing: knotThing{ID: thingID}}}
+			_ = json.NewEncoder(w).Encode(pageFetchSchema{Total: 1, Limit: pageLimit, Things: []*thingSchema{thing}})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestThingProxyRecreate(t *testing.T) {
+	server, requests := newFakeRecreateServer(t)
+	proxy := newTestThingProxy(server, concurrentPages)
+
+	token, err := proxy.Recreate(context.Background(), thingID, "thing", authorization, entities.Metadata{})
+	assert.NoError(t, err)
+	assert.Equal(t, "new-token", token)
+	// the new thing is created before the old one is removed
+	assert.Equal(t, []string{"GET /things", "POST /things", "DELETE /things/old-token"}, *requests)
+}
+
+func TestThingProxyRecreateRemovesNewThingWhenOldIsKept(t *testing.T) {
+	server, requests := newFakeRecreateServer(t, "old-token")
+	proxy := newTestThingProxy(server, concurrentPages)
+
+	_, err := proxy.Recreate(context.Background(), thingID, "thing", authorization, entities.Metadata{})
+	assert.True(t, errors.Is(err, entities.ErrThingUnauthorized))
+	assert.Equal(t, []string{"GET /things", "POST /things", "DELETE /things/old-token", "DELETE /things/new-token"}, *requests)
+}
+
+func TestThingProxyServiceFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	proxy := newTestThingProxy(server, concurrentPages)
+
+	_, err := proxy.Create(context.Background(), thingID, "thing", authorization, entities.Metadata{})
+	assert.True(t, errors.Is(err, network.ErrServiceFailure))
+	_, err = proxy.Get(context.Background(), authorization, thingID)
+	assert.True(t, errors.Is(err, network.ErrServiceFailure))
+	_, err = proxy.List(context.Background(), authorization)
+	assert.True(t, errors.Is(err, network.ErrServiceFailure))
+}
+
+func TestThingProxyCreateWithoutLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	proxy := newTestThingProxy(server, concurrentPages)
+
+	_, err := proxy.Create(context.Background(), thingID, "thing", authorization, entities.Metadata{})
+	assert.Error(t, err)
+}
+
+const (
+	benchmarkThings  = 10000
+	benchmarkLatency = time.Millisecond
+)
+
+func BenchmarkThingProxyGet(b *testing.B) {
+	proxy := newTestThingProxy(newFakeThingsServer(b, benchmarkThings, benchmarkLatency), concurrentPages)
+	id := fmt.Sprintf("%016x", benchmarkThings-1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := proxy.Get(context.Background(), authorization, id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkThingProxyGetByScan fetches every page to find the thing, as Get used to do
+func BenchmarkThingProxyGetByScan(b *testing.B) {
+	proxy := newTestThingProxy(newFakeThingsServer(b, benchmarkThings, benchmarkLatency), 1)
+	id := fmt.Sprintf("%016x", benchmarkThings-1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		things, err := proxy.List(context.Background(), authorization)
+		if err != nil || things[len(things)-1].ID != id {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkThingProxyList(b *testing.B) {
+	for _, pages := range []int{1, concurrentPages} {
+		b.Run(strconv.Itoa(pages)+" concurrent pages", func(b *testing.B) {
+			proxy := newTestThingProxy(newFakeThingsServer(b, benchmarkThings, benchmarkLatency), pages)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := proxy.List(context.Background(), authorization); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+// Command kinds, named after the events that send them to the things
+const (
+	CommandRequestData = "data.request"
+	CommandUpdateData  = "data.update"
+)
+
+// Command status
+const (
+	CommandPending   = "pending"
+	CommandCompleted = "completed"
+	CommandFailed    = "failed"
+	CommandTimeout   = "timeout"
+	CommandQueued    = "queued"
+)
+
+// Command represents a command sent to a thing, which is kept pending until the thing
+// sends data from all the command's sensors or acknowledges it. Commands to things that
+// are offline are queued, with their data, until they expire.
+type Command struct {
+	ID        string     `json:"id"`
+	ThingID   string     `json:"thingId"`
+	Kind      string     `json:"kind"`
+	SensorIDs []int      `json:"sensorIds"`
+	Data      []Data     `json:"data,omitempty"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
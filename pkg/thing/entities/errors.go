@@ -11,4 +11,23 @@ var (
 
 	// ErrThingExists is returned when trying to register an existing thing
 	ErrThingExists = errors.New("thing is already registered")
+
+	// ErrCommandNotFound is returned when the command isn't tracked or has expired
+	ErrCommandNotFound = errors.New("command not found")
+
+	// ErrCommandExists is returned when a command ID is already in use
+	ErrCommandExists = errors.New("command ID is already in use")
+
+	// ErrThingOffline is returned when there is no connector to deliver a command to the thing
+	ErrThingOffline = errors.New("thing is offline")
+
+	// ErrCommandQueueFull is returned when the thing's queue of commands is full
+	ErrCommandQueueFull = errors.New("thing's command queue is full")
+
+	// ErrSessionConsumed is returned when a session's data is still being consumed
+	ErrSessionConsumed = errors.New("session's data is being consumed")
+
+	// ErrSessionConsumersUnknown is returned when the transport can't verify if a session's
+	// data is being consumed
+	ErrSessionConsumersUnknown = errors.New("session's data consumers aren't known")
 )
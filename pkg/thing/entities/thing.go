@@ -2,8 +2,62 @@ package entities
 
 // Thing represents the thing domain entity
 type Thing struct {
-	ID     string   `json:"id"`
-	Token  string   `json:"token,omitempty"`
-	Name   string   `json:"name,omitempty"`
-	Config []Config `json:"config,omitempty"`
+	ID       string   `json:"id"`
+	Token    string   `json:"token,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Config   []Config `json:"config,omitempty"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Metadata represents the optional information that describes a thing
+type Metadata struct {
+	Description     string    `json:"description,omitempty"`
+	Tags            []string  `json:"tags,omitempty"`
+	Location        *Location `json:"location,omitempty"`
+	FirmwareVersion string    `json:"firmwareVersion,omitempty"`
+	HardwareVersion string    `json:"hardwareVersion,omitempty"`
+}
+
+// Location represents the geographic coordinates of a thing, in decimal degrees
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Fields that can be projected when listing things. The thing's ID is always listed.
+const (
+	FieldID       = "id"
+	FieldName     = "name"
+	FieldConfig   = "config"
+	FieldMetadata = "metadata"
+)
+
+// Orders in which the things can be listed. The descending orders are prefixed with "-".
+const (
+	SortByID       = "id"
+	SortByIDDesc   = "-id"
+	SortByName     = "name"
+	SortByNameDesc = "-name"
+)
+
+// ListOptions represents how the things are filtered, sorted, paginated and projected
+// when listed. The zero value lists every thing, in the order they are fetched and with
+// all their fields.
+type ListOptions struct {
+	Limit     int
+	Offset    int
+	Name      string
+	Tags      []string
+	HasConfig *bool
+	Sort      string
+	Fields    []string
+}
+
+// BulkResult represents the result of a bulk operation over one of its things. The token
+// is only set when the thing is registered.
+type BulkResult struct {
+	ID    string
+	Name  string
+	Token string
+	Err   error
 }
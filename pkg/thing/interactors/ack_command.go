@@ -0,0 +1,32 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+)
+
+// AckCommand runs the use case to acknowledge a command received by the thing. The
+// command is completed, or failed when the thing informs an error.
+func (i *ThingInteractor) AckCommand(ctx context.Context, authorization, thingID, commandID string, failure error) error {
+	if authorization == "" {
+		return ErrAuthNotProvided
+	}
+	if thingID == "" {
+		return ErrIDNotProvided
+	}
+	if commandID == "" {
+		return ErrCommandIDNotProvided
+	}
+
+	_, err := i.thingProxy.Get(ctx, authorization, thingID)
+	if err != nil {
+		return fmt.Errorf("can't receive thing metadata: %w", err)
+	}
+
+	err = i.tracker.Ack(ctx, thingID, commandID, failure)
+	if err != nil {
+		return fmt.Errorf("error acknowledging command: %w", err)
+	}
+
+	return nil
+}
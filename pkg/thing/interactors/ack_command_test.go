@@ -0,0 +1,125 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type AckCommandTestCase struct {
+	name               string
+	authParam          string
+	idParam            string
+	commandIDParam     string
+	failureParam       error
+	expectedErr        error
+	fakeThingProxy     *mocks.FakeThingProxy
+	fakeCommandTracker *mocks.FakeCommandTracker
+	ackErr             error
+}
+
+var ackCommandCases = []AckCommandTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"fc3fcf912d0c290a",
+		"command-id",
+		nil,
+		ErrAuthNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"thing's id not provided",
+		"authorization-token",
+		"",
+		"command-id",
+		nil,
+		ErrIDNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command's id not provided",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"",
+		nil,
+		ErrCommandIDNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"thing not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		nil,
+		entities.ErrThingNotFound,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		nil,
+		entities.ErrCommandNotFound,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandTracker{},
+		entities.ErrCommandNotFound,
+	},
+	{
+		"command acknowledged with failure",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		errors.New("actuator jammed"),
+		nil,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command acknowledged",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		nil,
+		nil,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+}
+
+func TestAckCommand(t *testing.T) {
+	for _, tc := range ackCommandCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("Get", tc.authParam, tc.idParam).
+				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+			tc.fakeCommandTracker.
+				On("Ack", tc.idParam, tc.commandIDParam, tc.failureParam).
+				Return(tc.ackErr).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, tc.fakeCommandTracker, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.AckCommand(context.Background(), tc.authParam, tc.idParam, tc.commandIDParam, tc.failureParam)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			tc.fakeThingProxy.AssertExpectations(t)
+			tc.fakeCommandTracker.AssertExpectations(t)
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package interactors
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Auth is responsible to implement the thing's authentication use case
-func (i *ThingInteractor) Auth(authorization, id string) error {
+func (i *ThingInteractor) Auth(ctx context.Context, authorization, id string) error {
 	if authorization == "" {
 		return ErrAuthNotProvided
 	}
@@ -11,10 +14,12 @@ func (i *ThingInteractor) Auth(authorization, id string) error {
 		return ErrIDNotProvided
 	}
 
-	_, err := i.thingProxy.Get(authorization, id)
+	_, err := i.thingProxy.Get(ctx, authorization, id)
 	if err != nil {
 		return fmt.Errorf("can't receive thing metadata: %w", err)
 	}
 
+	// the thing is authenticated when its connector is online
+	i.deliverQueuedCommands(ctx, id)
 	return nil
 }
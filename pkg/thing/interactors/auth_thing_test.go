@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"testing"
 
 	"github.com/CESARBR/knot-babeltower/pkg/mocks"
@@ -72,8 +73,14 @@ func TestAuthThing(t *testing.T) {
 				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
 				Maybe()
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			err := thingInteractor.Auth(tc.authParam, tc.idParam)
+			fakeCommandQueue := &mocks.FakeCommandQueue{}
+			fakeCommandQueue.
+				On("Flush", tc.idParam).
+				Return([]entities.Command{}, nil).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(tc.fakeLogger, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, fakeCommandQueue, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.Auth(context.Background(), tc.authParam, tc.idParam)
 
 			if tc.authParam == "" {
 				msg := tc.expectedErr.Error()
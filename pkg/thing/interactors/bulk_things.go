@@ -0,0 +1,129 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+const (
+	// maxBulkSize is the max number of things in a bulk operation
+	maxBulkSize = 1000
+	// concurrentBulkCalls is the max number of things' service calls made at the same time
+	// by a bulk operation
+	concurrentBulkCalls = 8
+)
+
+// RegisterBulk runs the use case to create several things at once. Each thing is validated
+// and registered independently, so the result of every thing is returned, in the received
+// order, and sent to the clients through a single registered bulk event. The existing things
+// are handled as in Register.
+func (i *ThingInteractor) RegisterBulk(ctx context.Context, authorization string, things []entities.Thing, force bool) ([]entities.BulkResult, error) {
+	err := validateBulk(authorization, len(things))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]entities.BulkResult, len(things))
+	seen := make(map[string]bool, len(things))
+	pending := []int{}
+	for idx, t := range things {
+		results[idx] = entities.BulkResult{ID: t.ID, Name: t.Name}
+		switch {
+		case t.ID == "":
+			results[idx].Err = ErrIDNotProvided
+		case t.Name == "":
+			results[idx].Err = ErrNameNotProvided
+		case seen[t.ID]:
+			results[idx].Err = ErrBulkIDDuplicated
+		default:
+			seen[t.ID] = true
+			pending = append(pending, idx)
+		}
+	}
+
+	runBulk(pending, func(idx int) {
+		t := things[idx]
+		results[idx].Token, results[idx].Err = i.registerThing(ctx, authorization, t.ID, t.Name, t.Metadata, force)
+	})
+
+	sendErr := i.publisher.PublishBulkRegisteredDevices(results, nil)
+	if sendErr != nil {
+		return results, fmt.Errorf("error sending response to client: %w", sendErr)
+	}
+
+	return results, nil
+}
+
+// UnregisterBulk runs the use case to remove several things at once. The result of every
+// thing is returned, in the received order, and sent to the clients through a single
+// unregistered bulk event.
+func (i *ThingInteractor) UnregisterBulk(ctx context.Context, authorization string, ids []string) ([]entities.BulkResult, error) {
+	err := validateBulk(authorization, len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]entities.BulkResult, len(ids))
+	seen := make(map[string]bool, len(ids))
+	pending := []int{}
+	for idx, id := range ids {
+		results[idx] = entities.BulkResult{ID: id}
+		switch {
+		case id == "":
+			results[idx].Err = ErrIDNotProvided
+		case seen[id]:
+			results[idx].Err = ErrBulkIDDuplicated
+		default:
+			seen[id] = true
+			results[idx].Err = i.verifyThingID(id)
+			if results[idx].Err == nil {
+				pending = append(pending, idx)
+			}
+		}
+	}
+
+	runBulk(pending, func(idx int) {
+		results[idx].Err = i.thingProxy.Remove(ctx, authorization, ids[idx])
+	})
+
+	sendErr := i.publisher.PublishBulkUnregisteredDevices(results, authorization, nil)
+	if sendErr != nil {
+		return results, fmt.Errorf("error sending response to client: %w", sendErr)
+	}
+
+	return results, nil
+}
+
+func validateBulk(authorization string, size int) error {
+	if authorization == "" {
+		return ErrAuthNotProvided
+	}
+	if size == 0 {
+		return ErrBulkNotProvided
+	}
+	if size > maxBulkSize {
+		return ErrBulkTooLarge
+	}
+
+	return nil
+}
+
+// runBulk runs the operation over the indexes, with at most concurrentBulkCalls at the same
+// time, and waits for all of them to finish
+func runBulk(indexes []int, operation func(idx int)) {
+	sem := make(chan struct{}, concurrentBulkCalls)
+	var wg sync.WaitGroup
+	for _, idx := range indexes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			operation(idx)
+		}(idx)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,179 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type BulkThingsTestCase struct {
+	name            string
+	authParam       string
+	thingsParam     []entities.Thing
+	publishErr      error
+	expectedErr     error
+	expectedResults []entities.BulkResult
+}
+
+var errCreateThing = errors.New("thing's service unavailable")
+
+var registerBulkCases = []BulkThingsTestCase{
+	{
+		"authorization token not provided",
+		"",
+		[]entities.Thing{{ID: "0a", Name: "thing"}},
+		nil,
+		ErrAuthNotProvided,
+		nil,
+	},
+	{
+		"things not provided",
+		"authorization-token",
+		[]entities.Thing{},
+		nil,
+		ErrBulkNotProvided,
+		nil,
+	},
+	{
+		"too many things provided",
+		"authorization-token",
+		make([]entities.Thing, maxBulkSize+1),
+		nil,
+		ErrBulkTooLarge,
+		nil,
+	},
+	{
+		"result of every thing returned in the received order",
+		"authorization-token",
+		[]entities.Thing{
+			{ID: "0a", Name: "created"},
+			{ID: "0b", Name: "existent"},
+			{ID: "zz", Name: "invalid"},
+			{ID: "0a", Name: "repeated"},
+			{ID: "", Name: "without id"},
+			{ID: "0c", Name: ""},
+			{ID: "0d", Name: "failed"},
+		},
+		nil,
+		nil,
+		[]entities.BulkResult{
+			{ID: "0a", Name: "created", Token: "token-0a"},
+			{ID: "0b", Name: "existent", Err: entities.ErrThingExists},
+			{ID: "zz", Name: "invalid", Err: ErrIDNotHex},
+			{ID: "0a", Name: "repeated", Err: ErrBulkIDDuplicated},
+			{ID: "", Name: "without id", Err: ErrIDNotProvided},
+			{ID: "0c", Name: "", Err: ErrNameNotProvided},
+			{ID: "0d", Name: "failed", Err: errCreateThing},
+		},
+	},
+	{
+		"failed to send the bulk registered response",
+		"authorization-token",
+		[]entities.Thing{{ID: "0a", Name: "created"}},
+		errors.New("failed to send response"),
+		errors.New("error sending response to client: failed to send response"),
+		[]entities.BulkResult{{ID: "0a", Name: "created", Token: "token-0a"}},
+	},
+}
+
+var unregisterBulkCases = []BulkThingsTestCase{
+	{
+		"authorization token not provided",
+		"",
+		[]entities.Thing{{ID: "0a"}},
+		nil,
+		ErrAuthNotProvided,
+		nil,
+	},
+	{
+		"things not provided",
+		"authorization-token",
+		nil,
+		nil,
+		ErrBulkNotProvided,
+		nil,
+	},
+	{
+		"result of every thing returned in the received order",
+		"authorization-token",
+		[]entities.Thing{{ID: "0a"}, {ID: "0b"}, {ID: "zz"}, {ID: "0a"}, {ID: ""}},
+		nil,
+		nil,
+		[]entities.BulkResult{
+			{ID: "0a"},
+			{ID: "0b", Err: entities.ErrThingNotFound},
+			{ID: "zz", Err: ErrIDNotHex},
+			{ID: "0a", Err: ErrBulkIDDuplicated},
+			{ID: "", Err: ErrIDNotProvided},
+		},
+	},
+}
+
+// newBulkThingProxy creates a thing's proxy where the 0b thing is the only registered one
+// and the creation of the 0d thing fails
+func newBulkThingProxy(authorization string) *mocks.FakeThingProxy {
+	fakeThingProxy := &mocks.FakeThingProxy{}
+	fakeThingProxy.On("Get", authorization, "0b").Return(&entities.Thing{ID: "0b"}, nil).Maybe()
+	fakeThingProxy.On("Get", authorization, mock.Anything).Return((*entities.Thing)(nil), entities.ErrThingNotFound).Maybe()
+	fakeThingProxy.On("Create", "0a", "created", authorization, entities.Metadata{}).Return("token-0a", nil).Maybe()
+	fakeThingProxy.On("Create", "0d", "failed", authorization, entities.Metadata{}).Return("", errCreateThing).Maybe()
+	fakeThingProxy.On("Remove", authorization, "0a").Return(nil).Maybe()
+	fakeThingProxy.On("Remove", authorization, "0b").Return(entities.ErrThingNotFound).Maybe()
+	return fakeThingProxy
+}
+
+func TestRegisterBulk(t *testing.T) {
+	for _, tc := range registerBulkCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakePublisher := &mocks.FakePublisher{}
+			if tc.expectedResults != nil {
+				fakePublisher.On("PublishBulkRegisteredDevices", tc.expectedResults, nil).
+					Return(tc.publishErr).Once()
+			}
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, fakePublisher, newBulkThingProxy(tc.authParam), &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			results, err := thingInteractor.RegisterBulk(context.Background(), tc.authParam, tc.thingsParam, false)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedResults, results)
+
+			fakePublisher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUnregisterBulk(t *testing.T) {
+	for _, tc := range unregisterBulkCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := make([]string, len(tc.thingsParam))
+			for i, thing := range tc.thingsParam {
+				ids[i] = thing.ID
+			}
+
+			fakePublisher := &mocks.FakePublisher{}
+			if tc.expectedResults != nil {
+				fakePublisher.On("PublishBulkUnregisteredDevices", tc.expectedResults, nil).
+					Return(tc.publishErr).Once()
+			}
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, fakePublisher, newBulkThingProxy(tc.authParam), &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			results, err := thingInteractor.UnregisterBulk(context.Background(), tc.authParam, ids)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedResults, results)
+
+			fakePublisher.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+)
+
+// CancelCommand runs the use case to cancel a command queued to an offline thing
+func (i *ThingInteractor) CancelCommand(ctx context.Context, authorization, thingID, commandID string) error {
+	if authorization == "" {
+		return ErrAuthNotProvided
+	}
+	if thingID == "" {
+		return ErrIDNotProvided
+	}
+	if commandID == "" {
+		return ErrCommandIDNotProvided
+	}
+
+	_, err := i.thingProxy.Get(ctx, authorization, thingID)
+	if err != nil {
+		return fmt.Errorf("can't receive thing metadata: %w", err)
+	}
+
+	err = i.queue.Cancel(ctx, thingID, commandID)
+	if err != nil {
+		return fmt.Errorf("error canceling command: %w", err)
+	}
+
+	i.logger.Infof("queued command %s canceled", commandID)
+	return nil
+}
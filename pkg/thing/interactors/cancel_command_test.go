@@ -0,0 +1,107 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type CancelCommandTestCase struct {
+	name             string
+	authParam        string
+	idParam          string
+	commandIDParam   string
+	expectedErr      error
+	fakeThingProxy   *mocks.FakeThingProxy
+	fakeCommandQueue *mocks.FakeCommandQueue
+	cancelErr        error
+}
+
+var cancelCommandCases = []CancelCommandTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"fc3fcf912d0c290a",
+		"command-id",
+		ErrAuthNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandQueue{},
+		nil,
+	},
+	{
+		"thing's id not provided",
+		"authorization-token",
+		"",
+		"command-id",
+		ErrIDNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandQueue{},
+		nil,
+	},
+	{
+		"command's id not provided",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"",
+		ErrCommandIDNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandQueue{},
+		nil,
+	},
+	{
+		"thing not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		entities.ErrThingNotFound,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+		&mocks.FakeCommandQueue{},
+		nil,
+	},
+	{
+		"command not queued",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		entities.ErrCommandNotFound,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandQueue{},
+		entities.ErrCommandNotFound,
+	},
+	{
+		"command canceled",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"command-id",
+		nil,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandQueue{},
+		nil,
+	},
+}
+
+func TestCancelCommand(t *testing.T) {
+	for _, tc := range cancelCommandCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("Get", tc.authParam, tc.idParam).
+				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+			tc.fakeCommandQueue.
+				On("Cancel", tc.idParam, tc.commandIDParam).
+				Return(tc.cancelErr).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, tc.fakeCommandQueue, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.CancelCommand(context.Background(), tc.authParam, tc.idParam, tc.commandIDParam)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			tc.fakeThingProxy.AssertExpectations(t)
+			tc.fakeCommandQueue.AssertExpectations(t)
+		})
+	}
+}
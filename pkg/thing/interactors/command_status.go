@@ -0,0 +1,35 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// CommandStatus runs the use case to get a command sent to a thing of the user
+func (i *ThingInteractor) CommandStatus(ctx context.Context, authorization, commandID string) (*entities.Command, error) {
+	if authorization == "" {
+		return nil, ErrAuthNotProvided
+	}
+	if commandID == "" {
+		return nil, ErrCommandIDNotProvided
+	}
+
+	command, err := i.tracker.Get(ctx, commandID)
+	if errors.Is(err, entities.ErrCommandNotFound) {
+		command, err = i.queue.Get(ctx, commandID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// only the thing's owner can see its commands
+	_, err = i.thingProxy.Get(ctx, authorization, command.ThingID)
+	if err != nil {
+		return nil, fmt.Errorf("can't receive thing metadata: %w", err)
+	}
+
+	return command, nil
+}
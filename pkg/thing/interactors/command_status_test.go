@@ -0,0 +1,112 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type CommandStatusTestCase struct {
+	name               string
+	authParam          string
+	commandIDParam     string
+	expectedCommand    *entities.Command
+	expectedErr        error
+	fakeThingProxy     *mocks.FakeThingProxy
+	fakeCommandTracker *mocks.FakeCommandTracker
+	getErr             error
+}
+
+var pendingCommand = &entities.Command{
+	ID:        "command-id",
+	ThingID:   "fc3fcf912d0c290a",
+	Kind:      entities.CommandRequestData,
+	SensorIDs: []int{0},
+	Status:    entities.CommandPending,
+}
+
+var commandStatusCases = []CommandStatusTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"command-id",
+		nil,
+		ErrAuthNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command's id not provided",
+		"authorization-token",
+		"",
+		nil,
+		ErrCommandIDNotProvided,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command not found",
+		"authorization-token",
+		"command-id",
+		nil,
+		entities.ErrCommandNotFound,
+		&mocks.FakeThingProxy{},
+		&mocks.FakeCommandTracker{},
+		entities.ErrCommandNotFound,
+	},
+	{
+		"command's thing isn't from the user",
+		"authorization-token",
+		"command-id",
+		nil,
+		entities.ErrThingNotFound,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+	{
+		"command status obtained",
+		"authorization-token",
+		"command-id",
+		pendingCommand,
+		nil,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		&mocks.FakeCommandTracker{},
+		nil,
+	},
+}
+
+func TestCommandStatus(t *testing.T) {
+	for _, tc := range commandStatusCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("Get", tc.authParam, pendingCommand.ThingID).
+				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+			tc.fakeCommandTracker.
+				On("Get", tc.commandIDParam).
+				Return(pendingCommand, tc.getErr).
+				Maybe()
+
+			fakeCommandQueue := &mocks.FakeCommandQueue{}
+			fakeCommandQueue.
+				On("Get", tc.commandIDParam).
+				Return((*entities.Command)(nil), entities.ErrCommandNotFound).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, tc.fakeCommandTracker, fakeCommandQueue, SessionPolicyFail, RegistrationPolicyFail)
+			command, err := thingInteractor.CommandStatus(context.Background(), tc.authParam, tc.commandIDParam)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+			assert.Equal(t, tc.expectedCommand, command)
+
+			tc.fakeThingProxy.AssertExpectations(t)
+			tc.fakeCommandTracker.AssertExpectations(t)
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package interactors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/jwt"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
 
 var (
 	// ErrAuthNotProvided is returned when authorization token is not provided
@@ -12,6 +18,12 @@ var (
 	// ErrNameNotProvided is returned when thing's name is not provided
 	ErrNameNotProvided = errors.New("thing's name not provided")
 
+	// ErrNameInvalid is returned when thing's name is blank
+	ErrNameInvalid = errors.New("thing's name can't be blank")
+
+	// ErrUpdateNotProvided is returned when neither the thing's name nor metadata are provided
+	ErrUpdateNotProvided = errors.New("thing's name or metadata not provided")
+
 	// ErrSchemaNotProvided is returned when thing's schema is not provided
 	ErrSchemaNotProvided = errors.New("thing's schema not provided")
 
@@ -21,12 +33,42 @@ var (
 	// ErrSensorsNotProvided is returned when thing's sensors are not provided
 	ErrSensorsNotProvided = errors.New("thing's sensors not provided")
 
+	// ErrCommandIDNotProvided is returned when the command's id is not provided
+	ErrCommandIDNotProvided = errors.New("command's id not provided")
+
 	// ErrIDLength is returned when the thing's id have more than 16 ascii characters
 	ErrIDLength = errors.New("id length exceeds 16 characters")
 
 	// ErrIDNotHex is returned when the thing's id is not formatted in hexadecimal base
 	ErrIDNotHex = errors.New("id is not in hexadecimal format")
 
+	// ErrBulkNotProvided is returned when no thing is provided to a bulk operation
+	ErrBulkNotProvided = errors.New("bulk operation's things not provided")
+
+	// ErrBulkTooLarge is returned when a bulk operation exceeds the maximum number of things
+	ErrBulkTooLarge = fmt.Errorf("bulk operation exceeds %d things", maxBulkSize)
+
+	// ErrBulkIDDuplicated is returned when a thing's id is repeated in a bulk operation
+	ErrBulkIDDuplicated = errors.New("thing's id is repeated in the bulk operation")
+
+	// ErrLimitInvalid is returned when the limit of listed things is negative
+	ErrLimitInvalid = errors.New("list limit can't be negative")
+
+	// ErrOffsetInvalid is returned when the offset of listed things is negative
+	ErrOffsetInvalid = errors.New("list offset can't be negative")
+
+	// ErrSortInvalid is returned when the things are listed in an unknown order
+	ErrSortInvalid = errors.New("list sort must be id, -id, name or -name")
+
+	// ErrFieldInvalid is returned when an unknown field of the listed things is projected
+	ErrFieldInvalid = errors.New("list fields must be id, name, config or metadata")
+
+	// ErrLocationInvalid is returned when the thing's location coordinates are out of range
+	ErrLocationInvalid = errors.New("thing's location is out of range")
+
+	// ErrTagInvalid is returned when some of the thing's tags is empty
+	ErrTagInvalid = errors.New("thing's tags can't be empty")
+
 	// ErrSchemaInvalid is returned when schema has an invalid format
 	ErrSchemaInvalid = errors.New("invalid schema")
 
@@ -51,3 +93,29 @@ var (
 	// ErrConfigEqual is returned when thing's config already has the same config
 	ErrConfigEqual = errors.New("nothing to update in the thing's config")
 )
+
+// invalidRequestErrors are caused by the request itself, so it fails the same way every
+// time it's handled
+var invalidRequestErrors = []error{
+	ErrAuthNotProvided, ErrIDNotProvided, ErrNameNotProvided, ErrNameInvalid,
+	ErrUpdateNotProvided, ErrSchemaNotProvided, ErrDataNotProvided, ErrSensorsNotProvided,
+	ErrCommandIDNotProvided, ErrIDLength, ErrIDNotHex, ErrBulkNotProvided, ErrBulkTooLarge,
+	ErrBulkIDDuplicated, ErrLimitInvalid, ErrOffsetInvalid, ErrSortInvalid, ErrFieldInvalid,
+	ErrLocationInvalid, ErrTagInvalid, ErrSchemaInvalid, ErrSensorInvalid, ErrSchemaUndefined,
+	ErrDataInvalid, ErrConfigNotProvided, ErrConfigInvalid, ErrConfigUndefined, ErrConfigEqual,
+	entities.ErrThingUnauthorized, entities.ErrThingNotFound, entities.ErrThingExists,
+	entities.ErrCommandNotFound, entities.ErrCommandExists, entities.ErrCommandQueueFull,
+	jwt.ErrParseToken, jwt.ErrClaimsAssertion,
+}
+
+// IsInvalidRequest verifies if the error is a validation or domain error caused by the
+// request, which isn't worth retrying, instead of a failure of the services it depends on
+func IsInvalidRequest(err error) bool {
+	for _, invalid := range invalidRequestErrors {
+		if errors.Is(err, invalid) {
+			return true
+		}
+	}
+
+	return false
+}
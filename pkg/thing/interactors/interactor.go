@@ -1,8 +1,11 @@
 package interactors
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/cache"
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/commands"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
@@ -10,14 +13,22 @@ import (
 
 // Interactor is an interface that defines the thing's use cases operations
 type Interactor interface {
-	Register(authorization, id, name string) error
-	Unregister(authorization, id string) error
-	UpdateConfig(authorization, id string, configList []entities.Config) (bool, error)
-	List(authorization string) ([]*entities.Thing, error)
-	RequestData(authorization, thingID string, sensorIds []int) error
-	UpdateData(authorization, thingID string, data []entities.Data) error
-	PublishData(authorization, thingID string, data []entities.Data) error
-	Auth(authorization, id string) error
+	Register(ctx context.Context, authorization, id, name string, metadata entities.Metadata, force bool) (string, error)
+	Unregister(ctx context.Context, authorization, id string) error
+	RegisterBulk(ctx context.Context, authorization string, things []entities.Thing, force bool) ([]entities.BulkResult, error)
+	UnregisterBulk(ctx context.Context, authorization string, ids []string) ([]entities.BulkResult, error)
+	UpdateConfig(ctx context.Context, authorization, id string, configList []entities.Config) (bool, error)
+	UpdateMetadata(ctx context.Context, authorization, id string, metadata entities.Metadata) error
+	Update(ctx context.Context, authorization, id, name string, metadata *entities.Metadata) (*entities.Thing, error)
+	List(ctx context.Context, authorization string, options entities.ListOptions) ([]*entities.Thing, int, error)
+	RequestData(ctx context.Context, authorization, thingID, commandID string, sensorIds []int) error
+	UpdateData(ctx context.Context, authorization, thingID, commandID string, data []entities.Data) error
+	PublishData(ctx context.Context, authorization, thingID string, data []entities.Data) error
+	Auth(ctx context.Context, authorization, id string) error
+	AckCommand(ctx context.Context, authorization, thingID, commandID string, failure error) error
+	CommandStatus(ctx context.Context, authorization, commandID string) (*entities.Command, error)
+	ListCommands(ctx context.Context, authorization, thingID string) ([]entities.Command, error)
+	CancelCommand(ctx context.Context, authorization, thingID, commandID string) error
 }
 
 // ThingInteractor represents the thing interactor capabilities, it's composed
@@ -27,6 +38,15 @@ type ThingInteractor struct {
 	publisher    amqp.Publisher
 	thingProxy   http.ThingProxy
 	sessionStore cache.SessionStore
+	tracker      commands.Tracker
+	queue        commands.Queue
+
+	// sessionPolicy defines how the data is published when the user's session can't be
+	// obtained, e.g. the session store is unavailable
+	sessionPolicy string
+	// registrationPolicy defines how the registration of a thing that already exists is
+	// answered
+	registrationPolicy string
 }
 
 // NewThingInteractor creates a new ThingInteractor instance
@@ -35,6 +55,10 @@ func NewThingInteractor(
 	publisher amqp.Publisher,
 	thingProxy http.ThingProxy,
 	sessionStore cache.SessionStore,
+	tracker commands.Tracker,
+	queue commands.Queue,
+	sessionPolicy string,
+	registrationPolicy string,
 ) *ThingInteractor {
-	return &ThingInteractor{logger, publisher, thingProxy, sessionStore}
+	return &ThingInteractor{logger, publisher, thingProxy, sessionStore, tracker, queue, sessionPolicy, registrationPolicy}
 }
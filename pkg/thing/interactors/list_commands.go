@@ -0,0 +1,30 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// ListCommands runs the use case to list the commands queued to an offline thing
+func (i *ThingInteractor) ListCommands(ctx context.Context, authorization, thingID string) ([]entities.Command, error) {
+	if authorization == "" {
+		return nil, ErrAuthNotProvided
+	}
+	if thingID == "" {
+		return nil, ErrIDNotProvided
+	}
+
+	_, err := i.thingProxy.Get(ctx, authorization, thingID)
+	if err != nil {
+		return nil, fmt.Errorf("can't receive thing metadata: %w", err)
+	}
+
+	commands, err := i.queue.List(ctx, thingID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing queued commands: %w", err)
+	}
+
+	return commands, nil
+}
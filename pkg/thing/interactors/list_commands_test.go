@@ -0,0 +1,107 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type ListCommandsTestCase struct {
+	name             string
+	authParam        string
+	idParam          string
+	expectedCommands []entities.Command
+	expectedErr      error
+	fakeThingProxy   *mocks.FakeThingProxy
+	fakeQueueErr     error
+}
+
+var errQueue = errors.New("queue unavailable")
+
+var queuedCommands = []entities.Command{{
+	ID:        "command-id",
+	ThingID:   "fc3fcf912d0c290a",
+	Kind:      entities.CommandUpdateData,
+	SensorIDs: []int{0},
+	Data:      []entities.Data{{SensorID: 0, Value: true}},
+	Status:    entities.CommandQueued,
+}}
+
+var listCommandsCases = []ListCommandsTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"fc3fcf912d0c290a",
+		nil,
+		ErrAuthNotProvided,
+		&mocks.FakeThingProxy{},
+		nil,
+	},
+	{
+		"thing's id not provided",
+		"authorization-token",
+		"",
+		nil,
+		ErrIDNotProvided,
+		&mocks.FakeThingProxy{},
+		nil,
+	},
+	{
+		"thing not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		nil,
+		entities.ErrThingNotFound,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+		nil,
+	},
+	{
+		"queued commands listed",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		queuedCommands,
+		nil,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		nil,
+	},
+	{
+		"failed to list queued commands",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		nil,
+		errQueue,
+		&mocks.FakeThingProxy{Thing: &entities.Thing{ID: "fc3fcf912d0c290a"}},
+		errQueue,
+	},
+}
+
+func TestListCommands(t *testing.T) {
+	for _, tc := range listCommandsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("Get", tc.authParam, tc.idParam).
+				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+			queued := queuedCommands
+			if tc.fakeQueueErr != nil {
+				queued = nil
+			}
+			fakeCommandQueue := &mocks.FakeCommandQueue{}
+			fakeCommandQueue.
+				On("List", tc.idParam).
+				Return(queued, tc.fakeQueueErr).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, fakeCommandQueue, SessionPolicyFail, RegistrationPolicyFail)
+			commands, err := thingInteractor.ListCommands(context.Background(), tc.authParam, tc.idParam)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+			assert.Equal(t, tc.expectedCommands, commands)
+
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
@@ -1,21 +1,154 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 )
 
-// List fetchs the registered things and return them as an array
-func (i *ThingInteractor) List(authorization string) ([]*entities.Thing, error) {
+// List fetchs the registered things and return the page of them that matches the options,
+// along with the total of things that match the filters.
+func (i *ThingInteractor) List(ctx context.Context, authorization string, options entities.ListOptions) ([]*entities.Thing, int, error) {
 	if authorization == "" {
-		return nil, ErrAuthNotProvided
+		return nil, 0, ErrAuthNotProvided
 	}
 
-	things, err := i.thingProxy.List(authorization)
+	err := validateListOptions(options)
 	if err != nil {
-		return nil, fmt.Errorf("error getting list of things: %w", err)
+		return nil, 0, err
 	}
 
-	return things, nil
+	things, err := i.thingProxy.List(ctx, authorization)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting list of things: %w", err)
+	}
+
+	things = filterThings(things, options)
+	sortThings(things, options.Sort)
+	total := len(things)
+	things = paginateThings(things, options.Limit, options.Offset)
+	if len(options.Fields) > 0 {
+		things = projectThings(things, options.Fields)
+	}
+
+	return things, total, nil
+}
+
+func validateListOptions(options entities.ListOptions) error {
+	if options.Limit < 0 {
+		return ErrLimitInvalid
+	}
+	if options.Offset < 0 {
+		return ErrOffsetInvalid
+	}
+
+	switch options.Sort {
+	case "", entities.SortByID, entities.SortByIDDesc, entities.SortByName, entities.SortByNameDesc:
+	default:
+		return ErrSortInvalid
+	}
+
+	for _, field := range options.Fields {
+		switch field {
+		case entities.FieldID, entities.FieldName, entities.FieldConfig, entities.FieldMetadata:
+		default:
+			return ErrFieldInvalid
+		}
+	}
+
+	return nil
+}
+
+func filterThings(things []*entities.Thing, options entities.ListOptions) []*entities.Thing {
+	name := strings.ToLower(options.Name)
+	filtered := []*entities.Thing{}
+	for _, t := range things {
+		if !strings.Contains(strings.ToLower(t.Name), name) {
+			continue
+		}
+		if options.HasConfig != nil && *options.HasConfig != (len(t.Config) > 0) {
+			continue
+		}
+		if !hasTags(t.Metadata.Tags, options.Tags) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+
+	return filtered
+}
+
+// hasTags verifies if the thing is tagged with all the tags
+func hasTags(thingTags, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range thingTags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortThings sorts the things in place, keeping the fetched order when no sort is provided
+func sortThings(things []*entities.Thing, order string) {
+	var less func(a, b *entities.Thing) bool
+	switch order {
+	case entities.SortByID:
+		less = func(a, b *entities.Thing) bool { return a.ID < b.ID }
+	case entities.SortByIDDesc:
+		less = func(a, b *entities.Thing) bool { return a.ID > b.ID }
+	case entities.SortByName:
+		less = func(a, b *entities.Thing) bool { return a.Name < b.Name || (a.Name == b.Name && a.ID < b.ID) }
+	case entities.SortByNameDesc:
+		less = func(a, b *entities.Thing) bool { return a.Name > b.Name || (a.Name == b.Name && a.ID > b.ID) }
+	default:
+		return
+	}
+
+	sort.SliceStable(things, func(i, j int) bool { return less(things[i], things[j]) })
+}
+
+// paginateThings returns the things after the offset, up to the limit when it's positive
+func paginateThings(things []*entities.Thing, limit, offset int) []*entities.Thing {
+	if offset >= len(things) {
+		return []*entities.Thing{}
+	}
+
+	things = things[offset:]
+	if limit > 0 && limit < len(things) {
+		things = things[:limit]
+	}
+
+	return things
+}
+
+// projectThings returns copies of the things with only the ID and the provided fields
+func projectThings(things []*entities.Thing, fields []string) []*entities.Thing {
+	projected := make([]*entities.Thing, 0, len(things))
+	for _, t := range things {
+		p := &entities.Thing{ID: t.ID}
+		for _, field := range fields {
+			switch field {
+			case entities.FieldName:
+				p.Name = t.Name
+			case entities.FieldConfig:
+				p.Config = t.Config
+			case entities.FieldMetadata:
+				p.Metadata = t.Metadata
+			}
+		}
+		projected = append(projected, p)
+	}
+
+	return projected
 }
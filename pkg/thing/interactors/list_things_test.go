@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -15,10 +16,12 @@ type listThingsTestCase struct {
 
 	// dependencies outputs and operation under test results
 	authorization               string
+	options                     entities.ListOptions
 	expectedProxyResponseError  error
 	expectedProxyResponseThings []*entities.Thing
 	expectedErrorResult         error
 	expectedThingsResult        []*entities.Thing
+	expectedTotalResult         int
 
 	// mocked dependencies
 	fakeLogger     *mocks.FakeLogger
@@ -33,44 +36,208 @@ var things = []*entities.Thing{
 	},
 }
 
+var taggedThings = []*entities.Thing{
+	{
+		ID:       "0a",
+		Name:     "Kitchen temperature",
+		Config:   []entities.Config{{SensorID: 0}},
+		Metadata: entities.Metadata{Tags: []string{"kitchen", "sensor"}},
+	},
+	{
+		ID:       "0b",
+		Name:     "Door lock",
+		Metadata: entities.Metadata{Tags: []string{"entrance"}},
+	},
+	{
+		ID:       "0c",
+		Name:     "Living room temperature",
+		Config:   []entities.Config{{SensorID: 0}},
+		Metadata: entities.Metadata{Tags: []string{"sensor"}},
+	},
+}
+
+var hasConfig = false
+
+var errListThings = errors.New("thing's service unavailable")
+
 var ltCases = []listThingsTestCase{
 	{
 		"authorization token not provided",
 		"",
+		entities.ListOptions{},
 		nil,
 		nil,
 		ErrAuthNotProvided,
 		nil,
+		0,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{},
 	},
 	{
 		"failed to list things from thing's service",
 		"authorization-token",
-		errors.New("thing's service unavailable"),
+		entities.ListOptions{},
+		errListThings,
 		nil,
-		errors.New("thing's service unavailable"),
+		errListThings,
 		nil,
+		0,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{},
 	},
 	{
 		"things successfully received from the thing's service",
 		"authorization-token",
+		entities.ListOptions{},
 		nil,
 		things,
 		nil,
 		things,
+		1,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{},
 	},
 	{
 		"return empty list when there is no thing registered on thing's service",
 		"authorization-token",
+		entities.ListOptions{},
 		nil,
 		[]*entities.Thing{},
 		nil,
 		[]*entities.Thing{},
+		0,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"negative limit provided",
+		"authorization-token",
+		entities.ListOptions{Limit: -1},
+		nil,
+		nil,
+		ErrLimitInvalid,
+		nil,
+		0,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"negative offset provided",
+		"authorization-token",
+		entities.ListOptions{Offset: -1},
+		nil,
+		nil,
+		ErrOffsetInvalid,
+		nil,
+		0,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"unknown sort provided",
+		"authorization-token",
+		entities.ListOptions{Sort: "size"},
+		nil,
+		nil,
+		ErrSortInvalid,
+		nil,
+		0,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"unknown field provided",
+		"authorization-token",
+		entities.ListOptions{Fields: []string{"token"}},
+		nil,
+		nil,
+		ErrFieldInvalid,
+		nil,
+		0,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things filtered by name regardless of the case",
+		"authorization-token",
+		entities.ListOptions{Name: "TEMPERATURE"},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{taggedThings[0], taggedThings[2]},
+		2,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things filtered by all the tags",
+		"authorization-token",
+		entities.ListOptions{Tags: []string{"sensor", "kitchen"}},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{taggedThings[0]},
+		1,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things filtered by not having config",
+		"authorization-token",
+		entities.ListOptions{HasConfig: &hasConfig},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{taggedThings[1]},
+		1,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things sorted by name and paginated",
+		"authorization-token",
+		entities.ListOptions{Sort: entities.SortByName, Limit: 1, Offset: 1},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{taggedThings[0]},
+		3,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things sorted by descending id",
+		"authorization-token",
+		entities.ListOptions{Sort: entities.SortByIDDesc},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{taggedThings[2], taggedThings[1], taggedThings[0]},
+		3,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"no thing returned when the offset exceeds the total",
+		"authorization-token",
+		entities.ListOptions{Offset: 5},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{},
+		3,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"things projected without config and metadata",
+		"authorization-token",
+		entities.ListOptions{Fields: []string{entities.FieldName}, Limit: 1},
+		nil,
+		taggedThings,
+		nil,
+		[]*entities.Thing{{ID: "0a", Name: "Kitchen temperature"}},
+		3,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{},
 	},
@@ -84,22 +251,16 @@ func TestListThings(t *testing.T) {
 				Return(tc.expectedProxyResponseThings, tc.expectedProxyResponseError).
 				Maybe()
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			things, err := thingInteractor.List(tc.authorization)
-			if tc.authorization == "" {
-				assert.EqualError(t, err, ErrAuthNotProvided.Error())
-				return
-			}
-
-			if err != nil && !errors.As(err, &tc.expectedErrorResult) {
-				t.Errorf("failed to list the devices. Error: %s", err)
+			thingInteractor := NewThingInteractor(tc.fakeLogger, nil, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			things, total, err := thingInteractor.List(context.Background(), tc.authorization, tc.options)
+			if tc.expectedErrorResult != nil {
+				assert.True(t, errors.Is(err, tc.expectedErrorResult))
 				return
 			}
 
-			if tc.expectedProxyResponseError == nil {
-				assert.Equal(t, things, tc.expectedThingsResult)
-			}
-
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedThingsResult, things)
+			assert.Equal(t, tc.expectedTotalResult, total)
 			tc.fakeThingProxy.AssertExpectations(t)
 		})
 	}
@@ -1,14 +1,24 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/CESARBR/knot-babeltower/pkg/jwt"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	userEntities "github.com/CESARBR/knot-babeltower/pkg/user/entities"
+)
+
+// Policies of the data published when the user's session can't be obtained
+const (
+	// SessionPolicyFail fails the publication, so the message is retried
+	SessionPolicyFail = "fail"
+	// SessionPolicySkip keeps broadcasting the data, which isn't sent to the user's session
+	SessionPolicySkip = "skip"
 )
 
 // PublishData executes the use case operations to publish data from the things to cloud
-func (i *ThingInteractor) PublishData(authorization, thingID string, data []entities.Data) error {
+func (i *ThingInteractor) PublishData(ctx context.Context, authorization, thingID string, data []entities.Data) error {
 	if authorization == "" {
 		return ErrAuthNotProvided
 	}
@@ -19,41 +29,124 @@ func (i *ThingInteractor) PublishData(authorization, thingID string, data []enti
 		return ErrDataNotProvided
 	}
 
-	err := i.verifyThingData(authorization, thingID, data)
+	thing, err := i.verifyThingData(ctx, authorization, thingID, data)
 	if err != nil {
 		return fmt.Errorf("error validating thing's data: %w", err)
 	}
 
-	err = i.publisher.PublishBroadcastData(thingID, authorization, data)
+	// the sessions are resolved before anything is published, so a failure getting them
+	// doesn't publish the data again when the message is retried
+	sessions, err := i.getSessionsData(ctx, thingID, authorization, thing.Metadata.Tags, data)
 	if err != nil {
-		return fmt.Errorf("error publishing data in broadcast mode: %w", err)
+		return fmt.Errorf("error publishing data to user sessions: %w", err)
 	}
 
-	err = i.publishSessionData(thingID, authorization, data)
+	// the data sent by the thing completes the commands waiting for it and shows
+	// it is online to receive the queued ones
+	err = i.tracker.Match(ctx, thingID, sensorIDs(data))
 	if err != nil {
-		return fmt.Errorf("error publishing data to user sessions: %w", err)
+		return fmt.Errorf("error completing the thing's commands: %w", err)
+	}
+	i.deliverQueuedCommands(ctx, thingID)
+
+	err = i.publisher.PublishBroadcastData(thingID, authorization, data)
+	if err != nil {
+		return fmt.Errorf("error publishing data in broadcast mode: %w", err)
 	}
 
+	i.publishSessionData(thingID, authorization, sessions)
 	return nil
 }
 
-func (i *ThingInteractor) publishSessionData(thingID, authorization string, data []entities.Data) error {
+// sessionData represents the data to be sent to a user session
+type sessionData struct {
+	sessionID string
+	data      []entities.Data
+}
+
+// getSessionsData returns the data to be sent to each user session, which is filtered by
+// the session filter
+func (i *ThingInteractor) getSessionsData(ctx context.Context, thingID, authorization string, tags []string, data []entities.Data) ([]sessionData, error) {
 	email, err := jwt.GetEmail(authorization)
 	if err != nil {
-		return fmt.Errorf("error getting user e-mail from token: %w", err)
+		return nil, fmt.Errorf("error getting user e-mail from token: %w", err)
 	}
 
-	sessionId, err := i.sessionStore.Get(email)
+	sessions, err := i.sessionStore.List(ctx, email)
+	if err != nil && i.sessionPolicy == SessionPolicySkip {
+		i.logger.Warn("data not sent to the user sessions: ", err)
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("error getting user session: %w", err)
+		return nil, fmt.Errorf("error getting user sessions: %w", err)
 	}
 
-	if sessionId != "" {
-		err = i.publisher.PublishSessionData(thingID, authorization, sessionId, data)
+	sessionsData := []sessionData{}
+	for _, sessionID := range sessions {
+		filter, err := i.sessionStore.GetFilter(ctx, sessionID)
+		if err != nil && i.sessionPolicy == SessionPolicySkip {
+			i.logger.Warn("data not sent to session "+sessionID+": ", err)
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("error sending message to client: %w", err)
+			return nil, fmt.Errorf("error getting filter of session %s: %w", sessionID, err)
+		}
+
+		filtered := filterSessionData(filter, thingID, tags, data)
+		if len(filtered) > 0 {
+			sessionsData = append(sessionsData, sessionData{sessionID, filtered})
 		}
 	}
 
-	return nil
+	return sessionsData, nil
+}
+
+// publishSessionData sends the data to every session even if some of them fail, so the
+// other ones still receive it. The failures are only logged, since handling the message
+// again would publish the data twice to the clients that already received it.
+func (i *ThingInteractor) publishSessionData(thingID, authorization string, sessions []sessionData) {
+	for _, session := range sessions {
+		err := i.publisher.PublishSessionData(thingID, authorization, session.sessionID, session.data)
+		if err != nil {
+			i.logger.Errorf("error sending data to session %s: %s", session.sessionID, err)
+		}
+	}
+}
+
+// filterSessionData returns the data that matches the session filter, which is empty when
+// the thing doesn't match it or none of its sensors are selected
+func filterSessionData(filter userEntities.SessionFilter, thingID string, tags []string, data []entities.Data) []entities.Data {
+	if len(filter.ThingIDs) > 0 && !hasThingID(filter.ThingIDs, thingID) {
+		return nil
+	}
+
+	if !hasTags(tags, filter.Tags) {
+		return nil
+	}
+
+	if len(filter.SensorIDs) == 0 {
+		return data
+	}
+
+	filtered := []entities.Data{}
+	for _, d := range data {
+		for _, id := range filter.SensorIDs {
+			if d.SensorID == id {
+				filtered = append(filtered, d)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+func hasThingID(ids []string, thingID string) bool {
+	for _, id := range ids {
+		if id == thingID {
+			return true
+		}
+	}
+
+	return false
 }
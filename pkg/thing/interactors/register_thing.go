@@ -1,45 +1,77 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 )
 
-// Register runs the use case to create a new thing
-func (i *ThingInteractor) Register(authorization, id, name string) error {
+// Policies of the registration of a thing that is already registered
+const (
+	// RegistrationPolicyFail fails the registration with ErrThingExists
+	RegistrationPolicyFail = "fail"
+	// RegistrationPolicyExisting returns the token of the thing registered with the same
+	// name, so a registration whose response was lost can be retried
+	RegistrationPolicyExisting = "existing"
+)
+
+// Register runs the use case to create a new thing, optionally described by the metadata.
+// It returns the thing's token, which is also sent to the clients through the registered event.
+// Registering an existing thing follows the registration policy, unless it's forced, which
+// re-creates the thing with a new token.
+func (i *ThingInteractor) Register(ctx context.Context, authorization, id, name string, metadata entities.Metadata, force bool) (string, error) {
 	if authorization == "" {
-		return ErrAuthNotProvided
+		return "", ErrAuthNotProvided
 	}
 	if id == "" {
-		return ErrIDNotProvided
+		return "", ErrIDNotProvided
 	}
 	if name == "" {
-		return ErrNameNotProvided
+		return "", ErrNameNotProvided
 	}
 
+	token, err := i.registerThing(ctx, authorization, id, name, metadata, force)
+	if WillRetry(ctx, err) {
+		return "", fmt.Errorf("error registering thing: %w", err)
+	}
+
+	sendErr := i.sendResponse(id, name, token, err)
+	if err != nil {
+		return "", fmt.Errorf("error registering thing: %w", sendErr)
+	}
+
+	return token, sendErr
+}
+
+// registerThing validates and creates the thing, without sending the result to the clients
+func (i *ThingInteractor) registerThing(ctx context.Context, authorization, id, name string, metadata entities.Metadata, force bool) (string, error) {
 	err := i.verifyThingID(id)
 	if err != nil {
-		sendErr := i.sendResponse(id, name, "", err)
-		return fmt.Errorf("error registering thing: %w", sendErr)
+		return "", err
+	}
+
+	err = validateMetadata(metadata)
+	if err != nil {
+		return "", err
 	}
 
 	// verify if thing is already registered
-	_, err = i.thingProxy.Get(authorization, id)
+	thing, err := i.thingProxy.Get(ctx, authorization, id)
 	if err == nil {
-		sendErr := i.sendResponse(id, name, "", entities.ErrThingExists)
-		return fmt.Errorf("error registering thing: %w", sendErr)
+		switch {
+		case force:
+			return i.thingProxy.Recreate(ctx, id, name, authorization, metadata)
+		case i.registrationPolicy == RegistrationPolicyExisting && thing.Name == name:
+			return thing.Token, nil
+		default:
+			return "", entities.ErrThingExists
+		}
 	}
 
 	// Get the id generated as a token and send in the response
-	token, err := i.thingProxy.Create(id, name, authorization)
-	sendErr := i.sendResponse(id, name, token, err)
-	if err != nil {
-		return fmt.Errorf("error registering thing: %w", sendErr)
-	}
-
-	return sendErr
+	return i.thingProxy.Create(ctx, id, name, authorization, metadata)
 }
 
 func (i *ThingInteractor) verifyThingID(id string) error {
@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -14,6 +15,9 @@ type registerTestCase struct {
 	authParam      string
 	idParam        string
 	nameParam      string
+	metadataParam  entities.Metadata
+	forceParam     bool
+	policy         string
 	errExpected    error
 	thingExpected  *entities.Thing
 	fakeLogger     *mocks.FakeLogger
@@ -37,6 +41,9 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"01234567890123456789",
 		"knot-thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		ErrIDLength,
 		nil,
 		&mocks.FakeLogger{},
@@ -48,17 +55,37 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"not hex string",
 		"test",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		ErrIDNotHex,
 		nil,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{},
 		&mocks.FakePublisher{SendError: ErrIDNotHex},
 	},
+	{
+		"thing's location is out of range",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"knot-thing",
+		entities.Metadata{Location: &entities.Location{Latitude: 91}},
+		false,
+		RegistrationPolicyFail,
+		ErrLocationInvalid,
+		nil,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{SendError: ErrLocationInvalid},
+	},
 	{
 		"thing already registered on thing's service",
 		"authorization-token",
 		"fc3fcf912d0c290a",
 		"test",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		entities.ErrThingExists,
 		thing,
 		&mocks.FakeLogger{},
@@ -70,6 +97,9 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"fc3fcf912d0c290a",
 		"knot-thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		errThingCreation,
 		nil,
 		&mocks.FakeLogger{},
@@ -81,6 +111,9 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"fc3fcf912d0c290a",
 		"knot-thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		nil,
 		nil,
 		&mocks.FakeLogger{},
@@ -92,6 +125,9 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"fc3fcf912d0c290a",
 		"knot-thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
 		errRegisterResponse,
 		nil,
 		&mocks.FakeLogger{},
@@ -103,11 +139,70 @@ var registerThingUseCases = []registerTestCase{
 		"authorization-token",
 		"fc3fcf912d0c290a",
 		"knot-thing",
+		entities.Metadata{Description: "door lock", Tags: []string{"door"}, FirmwareVersion: "1.0.0"},
+		false,
+		RegistrationPolicyFail,
 		nil,
 		nil,
 		&mocks.FakeLogger{},
 		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
-		&mocks.FakePublisher{},
+		&mocks.FakePublisher{Token: "thing-token"},
+	},
+	{
+		"thing with the same name already registered",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyFail,
+		entities.ErrThingExists,
+		thing,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{SendError: entities.ErrThingExists},
+	},
+	{
+		"registration retried with the thing's name",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"thing",
+		entities.Metadata{},
+		false,
+		RegistrationPolicyExisting,
+		nil,
+		thing,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{Token: thing.Token},
+	},
+	{
+		"thing re-created with a new token when forced",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"thing",
+		entities.Metadata{},
+		true,
+		RegistrationPolicyFail,
+		nil,
+		thing,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{Token: "new-thing-token"},
+	},
+	{
+		"thing kept when it fails to be re-created",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"thing",
+		entities.Metadata{},
+		true,
+		RegistrationPolicyFail,
+		errThingCreation,
+		thing,
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{CreateErr: errThingCreation},
+		&mocks.FakePublisher{SendError: errThingCreation},
 	},
 }
 
@@ -119,15 +214,28 @@ func TestRegisterThing(t *testing.T) {
 				Return(tc.thingExpected, tc.fakeThingProxy.ReturnErr).Maybe()
 			tc.fakePublisher.On("PublishRegisteredDevice", tc.idParam, tc.nameParam, tc.fakePublisher.Token, tc.fakePublisher.SendError).
 				Return(tc.fakePublisher.PublishErr).Maybe()
-			tc.fakeThingProxy.On("Create", tc.idParam, tc.nameParam, tc.authParam).
-				Return(tc.fakePublisher.Token, tc.fakeThingProxy.CreateErr).Maybe()
+			if tc.forceParam {
+				tc.fakeThingProxy.On("Recreate", tc.idParam, tc.nameParam, tc.authParam, tc.metadataParam).
+					Return(tc.fakePublisher.Token, tc.fakeThingProxy.CreateErr).Once()
+			} else {
+				tc.fakeThingProxy.On("Create", tc.idParam, tc.nameParam, tc.authParam, tc.metadataParam).
+					Return(tc.fakePublisher.Token, tc.fakeThingProxy.CreateErr).Maybe()
+			}
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			err := thingInteractor.Register(tc.authParam, tc.idParam, tc.nameParam)
+			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, tc.policy)
+			token, err := thingInteractor.Register(context.Background(), tc.authParam, tc.idParam, tc.nameParam, tc.metadataParam, tc.forceParam)
 			if err != nil && !assert.IsType(t, errors.Unwrap(err), tc.errExpected) {
 				t.Errorf("create thing failed with unexpected error. Error: %s", err)
 				return
 			}
+			if err == nil {
+				assert.Equal(t, tc.fakePublisher.Token, token)
+			}
+
+			if tc.thingExpected != nil {
+				tc.fakeThingProxy.AssertNotCalled(t, "Create", tc.idParam, tc.nameParam, tc.authParam, tc.metadataParam)
+				tc.fakeThingProxy.AssertNotCalled(t, "Remove", tc.authParam, tc.idParam)
+			}
 
 			tc.fakePublisher.AssertExpectations(t)
 			tc.fakeThingProxy.AssertExpectations(t)
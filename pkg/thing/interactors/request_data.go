@@ -1,13 +1,16 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 )
 
-// RequestData executes the use case operations to request data from the thing
-func (i *ThingInteractor) RequestData(authorization, thingID string, sensorIds []int) error {
+// RequestData executes the use case operations to request data from the thing. The
+// command is tracked until the thing sends data from the requested sensors, or queued
+// if the thing is offline.
+func (i *ThingInteractor) RequestData(ctx context.Context, authorization, thingID, commandID string, sensorIds []int) error {
 	if authorization == "" {
 		return ErrAuthNotProvided
 	}
@@ -18,7 +21,7 @@ func (i *ThingInteractor) RequestData(authorization, thingID string, sensorIds [
 		return ErrSensorsNotProvided
 	}
 
-	thing, err := i.thingProxy.Get(authorization, thingID)
+	thing, err := i.thingProxy.Get(ctx, authorization, thingID)
 	if err != nil {
 		i.logger.Error(err)
 		return err
@@ -35,13 +38,14 @@ func (i *ThingInteractor) RequestData(authorization, thingID string, sensorIds [
 		return err
 	}
 
-	err = i.publisher.PublishRequestData(thingID, sensorIds)
+	command := entities.Command{ID: commandID, ThingID: thingID, Kind: entities.CommandRequestData, SensorIDs: sensorIds}
+	err = i.sendCommand(ctx, &command)
 	if err != nil {
 		i.logger.Error(err)
 		return err
 	}
 
-	i.logger.Info("data request command successfully sent")
+	i.logger.Infof("data request command %s successfully %s", command.ID, command.Status)
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -201,13 +202,23 @@ func TestGetData(t *testing.T) {
 				Return(tc.expectedThing, tc.expectedThingError).
 				Maybe()
 			tc.fakePublisher.
-				On("PublishRequestData", tc.thingID, tc.sensorIds).
+				On("PublishRequestData", tc.thingID, "command-id", tc.sensorIds).
 				Return(tc.expectedRequestDataResponse).
 				Maybe()
 		})
 
-		thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-		err := thingInteractor.RequestData(tc.authorization, tc.thingID, tc.sensorIds)
+		fakeCommandTracker := &mocks.FakeCommandTracker{}
+		fakeCommandTracker.
+			On("Add", tc.thingID, "", entities.CommandRequestData, tc.sensorIds).
+			Return(&entities.Command{ID: "command-id"}, nil).
+			Maybe()
+		fakeCommandTracker.
+			On("Remove", "command-id").
+			Return(nil).
+			Maybe()
+
+		thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, fakeCommandTracker, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+		err := thingInteractor.RequestData(context.Background(), tc.authorization, tc.thingID, "", tc.sensorIds)
 		if tc.authorization == "" {
 			assert.EqualError(t, err, ErrAuthNotProvided.Error())
 		}
@@ -0,0 +1,18 @@
+package interactors
+
+import "context"
+
+type retryKey struct{}
+
+// WithRetry returns a copy of ctx signaling that the operation is handled again if it fails
+// transiently, so its failure isn't sent to the clients, who only receive the final outcome.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+// WillRetry verifies if the operation failed with an error that isn't caused by the request
+// and will be handled again, so the failure must not be sent to the clients yet
+func WillRetry(ctx context.Context, err error) bool {
+	retry, _ := ctx.Value(retryKey{}).(bool)
+	return retry && err != nil && !IsInvalidRequest(err)
+}
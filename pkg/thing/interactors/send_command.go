@@ -0,0 +1,71 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// sendCommand sends the command to the thing and starts tracking it. The command is
+// queued when the thing is offline, and its status is set accordingly.
+func (i *ThingInteractor) sendCommand(ctx context.Context, command *entities.Command) error {
+	tracked, err := i.tracker.Add(ctx, command.ThingID, command.ID, command.Kind, command.SensorIDs)
+	if err != nil {
+		return fmt.Errorf("error tracking command: %w", err)
+	}
+	command.ID = tracked.ID
+	command.Status = tracked.Status
+	command.CreatedAt = tracked.CreatedAt
+
+	switch command.Kind {
+	case entities.CommandRequestData:
+		err = i.publisher.PublishRequestData(command.ThingID, command.ID, command.SensorIDs)
+	case entities.CommandUpdateData:
+		err = i.publisher.PublishUpdateData(command.ThingID, command.ID, command.Data)
+	}
+	if err == nil {
+		return nil
+	}
+
+	removeErr := i.tracker.Remove(ctx, command.ID)
+	if removeErr != nil {
+		i.logger.Errorf("error removing command %s not sent: %s", command.ID, removeErr)
+	}
+	if !errors.Is(err, entities.ErrThingOffline) {
+		return err
+	}
+
+	err = i.queue.Enqueue(ctx, *command)
+	if err != nil {
+		return fmt.Errorf("error queueing command to offline thing: %w", err)
+	}
+
+	command.Status = entities.CommandQueued
+	return nil
+}
+
+// deliverQueuedCommands sends the commands queued while the thing was offline. The
+// commands that fail to be sent are queued again.
+func (i *ThingInteractor) deliverQueuedCommands(ctx context.Context, thingID string) {
+	queued, err := i.queue.Flush(ctx, thingID)
+	if err != nil {
+		i.logger.Errorf("error flushing the commands queued to thing %s: %s", thingID, err)
+		return
+	}
+
+	for _, command := range queued {
+		command := command
+		err := i.sendCommand(ctx, &command)
+		if err == nil {
+			continue
+		}
+
+		i.logger.Errorf("error delivering queued command %s: %s", command.ID, err)
+		err = i.queue.Enqueue(ctx, command)
+		if err != nil {
+			i.logger.Errorf("queued command %s dropped: %s", command.ID, err)
+		}
+	}
+}
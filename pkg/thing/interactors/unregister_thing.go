@@ -1,7 +1,9 @@
 package interactors
 
+import "context"
+
 // Unregister runs the use case to remove a registered thing
-func (i *ThingInteractor) Unregister(authorization, id string) error {
+func (i *ThingInteractor) Unregister(ctx context.Context, authorization, id string) error {
 	i.logger.Debug("executing unregister thing use case")
 
 	if authorization == "" {
@@ -12,7 +14,10 @@ func (i *ThingInteractor) Unregister(authorization, id string) error {
 		return ErrIDNotProvided
 	}
 
-	err := i.thingProxy.Remove(authorization, id)
+	err := i.thingProxy.Remove(ctx, authorization, id)
+	if WillRetry(ctx, err) {
+		return err
+	}
 	if err != nil {
 		sendErr := i.publisher.PublishUnregisteredDevice(id, authorization, err)
 		if sendErr != nil {
@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -108,8 +109,8 @@ func TestUnregisterThing(t *testing.T) {
 				Return(tc.fakePublisher.SendError).
 				Maybe()
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			err := thingInteractor.Unregister(tc.authParam, tc.idParam)
+			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.Unregister(context.Background(), tc.authParam, tc.idParam)
 
 			if err != nil {
 				assert.EqualError(t, err, tc.expectedErrMsg)
@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"reflect"
@@ -54,7 +55,7 @@ var rules = map[int]schemaType{
 // It returns two values:
 //   - error: indicates if something goes wrong
 //   - bool: indicates if the operation has changed something in the current thing's configuration
-func (i *ThingInteractor) UpdateConfig(authorization, id string, configList []entities.Config) (bool, error) {
+func (i *ThingInteractor) UpdateConfig(ctx context.Context, authorization, id string, configList []entities.Config) (bool, error) {
 	if authorization == "" {
 		return false, ErrAuthNotProvided
 	}
@@ -65,7 +66,7 @@ func (i *ThingInteractor) UpdateConfig(authorization, id string, configList []en
 		return false, ErrConfigNotProvided
 	}
 
-	err := i.validateConfig(authorization, id, configList)
+	err := i.validateConfig(ctx, authorization, id, configList)
 	if err != nil {
 		if err == ErrConfigEqual {
 			return false, nil
@@ -74,7 +75,7 @@ func (i *ThingInteractor) UpdateConfig(authorization, id string, configList []en
 		return false, fmt.Errorf("failed to validate if config is valid: %w", err)
 	}
 
-	err = i.thingProxy.UpdateConfig(authorization, id, configList)
+	err = i.thingProxy.UpdateConfig(ctx, authorization, id, configList)
 	if err != nil {
 		return false, err
 	}
@@ -82,8 +83,8 @@ func (i *ThingInteractor) UpdateConfig(authorization, id string, configList []en
 	return true, nil
 }
 
-func (i *ThingInteractor) validateConfig(authorization, id string, configList []entities.Config) error {
-	thing, err := i.thingProxy.Get(authorization, id)
+func (i *ThingInteractor) validateConfig(ctx context.Context, authorization, id string, configList []entities.Config) error {
+	thing, err := i.thingProxy.Get(ctx, authorization, id)
 	if err != nil {
 		return fmt.Errorf("error getting thing metadata: %w", err)
 	}
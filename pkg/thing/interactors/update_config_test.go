@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -371,8 +372,8 @@ func TestUpdateConfig(t *testing.T) {
 				Return(tc.fakeThingProxy.ReturnErr).
 				Maybe()
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			changed, err := thingInteractor.UpdateConfig(tc.authParam, tc.idParam, tc.configParam)
+			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			changed, err := thingInteractor.UpdateConfig(context.Background(), tc.authParam, tc.idParam, tc.configParam)
 
 			assert.EqualValues(t, tc.expectedChanged, changed)
 			assert.EqualValues(t, errors.Is(err, tc.expectedError), true)
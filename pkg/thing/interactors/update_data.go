@@ -1,14 +1,17 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 	"math"
 
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 )
 
-// UpdateData executes the use case operations to update data in thing
-func (i *ThingInteractor) UpdateData(authorization, thingID string, data []entities.Data) error {
+// UpdateData executes the use case operations to update data in thing. The command is
+// tracked until the thing sends data from the updated sensors, or queued if the thing
+// is offline.
+func (i *ThingInteractor) UpdateData(ctx context.Context, authorization, thingID, commandID string, data []entities.Data) error {
 	if authorization == "" {
 		return ErrAuthNotProvided
 	}
@@ -19,37 +22,48 @@ func (i *ThingInteractor) UpdateData(authorization, thingID string, data []entit
 		return ErrDataNotProvided
 	}
 
-	err := i.verifyThingData(authorization, thingID, data)
+	_, err := i.verifyThingData(ctx, authorization, thingID, data)
 	if err != nil {
 		return fmt.Errorf("error validating thing's data: %w", err)
 	}
 
-	err = i.publisher.PublishUpdateData(thingID, data)
+	command := entities.Command{ID: commandID, ThingID: thingID, Kind: entities.CommandUpdateData, SensorIDs: sensorIDs(data), Data: data}
+	err = i.sendCommand(ctx, &command)
 	if err != nil {
 		return fmt.Errorf("error sending message to client: %w", err)
 	}
 
-	i.logger.Info("data update command successfully sent")
+	i.logger.Infof("data update command %s successfully %s", command.ID, command.Status)
 	return nil
 }
 
-func (i *ThingInteractor) verifyThingData(authorization, thingID string, data []entities.Data) error {
-	thing, err := i.thingProxy.Get(authorization, thingID)
+// verifyThingData validates the data against the thing's schema and returns the thing
+func (i *ThingInteractor) verifyThingData(ctx context.Context, authorization, thingID string, data []entities.Data) (*entities.Thing, error) {
+	thing, err := i.thingProxy.Get(ctx, authorization, thingID)
 	if err != nil {
-		return fmt.Errorf("error getting thing metadata: %w", err)
+		return nil, fmt.Errorf("error getting thing metadata: %w", err)
 	}
 
 	if thing.Config == nil {
-		return ErrConfigUndefined
+		return nil, ErrConfigUndefined
 	}
 
 	for _, d := range data {
 		if !validateSchema(d, thing.Config) {
-			return ErrDataInvalid
+			return nil, ErrDataInvalid
 		}
 	}
 
-	return nil
+	return thing, nil
+}
+
+func sensorIDs(data []entities.Data) []int {
+	ids := make([]int, 0, len(data))
+	for _, d := range data {
+		ids = append(ids, d.SensorID)
+	}
+
+	return ids
 }
 
 func validateSchema(data entities.Data, configList []entities.Config) bool {
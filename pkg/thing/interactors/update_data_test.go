@@ -1,12 +1,14 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/CESARBR/knot-babeltower/pkg/mocks"
 	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 type UpdateDataTestCase struct {
@@ -151,6 +153,21 @@ var updateDataUseCases = []UpdateDataTestCase{
 		&mocks.FakePublisher{},
 		nil,
 	},
+	{
+		"message queued when the thing is offline",
+		"authorization-token",
+		"thing-id",
+		[]entities.Data{{SensorID: 0, Value: float64(5)}},
+		&mocks.FakeLogger{},
+		&mocks.FakeThingProxy{Thing: &entities.Thing{
+			ID:     "thing-id",
+			Token:  "thing-token",
+			Name:   "thing",
+			Config: configWithVoltageSchema,
+		}},
+		&mocks.FakePublisher{PublishErr: entities.ErrThingOffline},
+		nil,
+	},
 }
 
 func TestUpdateData(t *testing.T) {
@@ -161,12 +178,28 @@ func TestUpdateData(t *testing.T) {
 				Return(tc.fakeThingProxy.Thing, tc.fakeThingProxy.ReturnErr).
 				Maybe()
 			tc.fakePublisher.
-				On("PublishUpdateData", tc.idParam, tc.dataParam).
+				On("PublishUpdateData", tc.idParam, "command-id", tc.dataParam).
 				Return(tc.fakePublisher.PublishErr).
 				Maybe()
 
-			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{})
-			err := thingInteractor.UpdateData(tc.authParam, tc.idParam, tc.dataParam)
+			fakeCommandTracker := &mocks.FakeCommandTracker{}
+			fakeCommandTracker.
+				On("Add", tc.idParam, "", entities.CommandUpdateData, mock.Anything).
+				Return(&entities.Command{ID: "command-id"}, nil).
+				Maybe()
+			fakeCommandTracker.
+				On("Remove", "command-id").
+				Return(nil).
+				Maybe()
+
+			fakeCommandQueue := &mocks.FakeCommandQueue{}
+			fakeCommandQueue.
+				On("Enqueue", mock.MatchedBy(func(c entities.Command) bool { return c.ID == "command-id" })).
+				Return(nil).
+				Maybe()
+
+			thingInteractor := NewThingInteractor(tc.fakeLogger, tc.fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, fakeCommandTracker, fakeCommandQueue, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.UpdateData(context.Background(), tc.authParam, tc.idParam, "", tc.dataParam)
 
 			assert.EqualValues(t, errors.Is(err, tc.expectedError), true)
 
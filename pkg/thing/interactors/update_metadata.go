@@ -0,0 +1,46 @@
+package interactors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// UpdateMetadata executes the use case to replace the optional metadata that describes
+// the thing, i.e. its description, tags, location and versions.
+func (i *ThingInteractor) UpdateMetadata(ctx context.Context, authorization, id string, metadata entities.Metadata) error {
+	if authorization == "" {
+		return ErrAuthNotProvided
+	}
+	if id == "" {
+		return ErrIDNotProvided
+	}
+
+	err := validateMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	return i.thingProxy.UpdateMetadata(ctx, authorization, id, metadata)
+}
+
+func validateMetadata(metadata entities.Metadata) error {
+	for _, tag := range metadata.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return ErrTagInvalid
+		}
+	}
+
+	location := metadata.Location
+	if location != nil {
+		if location.Latitude < -90 || 90 < location.Latitude {
+			return ErrLocationInvalid
+		}
+		if location.Longitude < -180 || 180 < location.Longitude {
+			return ErrLocationInvalid
+		}
+	}
+
+	return nil
+}
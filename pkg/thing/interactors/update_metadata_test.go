@@ -0,0 +1,103 @@
+package interactors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type UpdateMetadataTestCase struct {
+	name           string
+	authParam      string
+	idParam        string
+	metadataParam  entities.Metadata
+	expectedErr    error
+	fakeThingProxy *mocks.FakeThingProxy
+}
+
+var updateMetadataCases = []UpdateMetadataTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"fc3fcf912d0c290a",
+		entities.Metadata{},
+		ErrAuthNotProvided,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's id not provided",
+		"authorization-token",
+		"",
+		entities.Metadata{},
+		ErrIDNotProvided,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's tag is empty",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		entities.Metadata{Tags: []string{"door", " "}},
+		ErrTagInvalid,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's latitude is out of range",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		entities.Metadata{Location: &entities.Location{Latitude: -90.5}},
+		ErrLocationInvalid,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's longitude is out of range",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		entities.Metadata{Location: &entities.Location{Longitude: 180.5}},
+		ErrLocationInvalid,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		entities.Metadata{Description: "door lock"},
+		entities.ErrThingNotFound,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+	},
+	{
+		"metadata updated",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		entities.Metadata{
+			Description:     "door lock",
+			Tags:            []string{"door", "entrance"},
+			Location:        &entities.Location{Latitude: -8.05, Longitude: -34.9},
+			FirmwareVersion: "1.2.0",
+			HardwareVersion: "rev-b",
+		},
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	for _, tc := range updateMetadataCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.On("UpdateMetadata", tc.authParam, tc.idParam, tc.metadataParam).
+				Return(tc.fakeThingProxy.ReturnErr).Maybe()
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, &mocks.FakePublisher{}, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			err := thingInteractor.UpdateMetadata(context.Background(), tc.authParam, tc.idParam, tc.metadataParam)
+			assert.Equal(t, tc.expectedErr, err)
+
+			if tc.expectedErr == nil || tc.fakeThingProxy.ReturnErr != nil {
+				tc.fakeThingProxy.AssertCalled(t, "UpdateMetadata", tc.authParam, tc.idParam, tc.metadataParam)
+			} else {
+				tc.fakeThingProxy.AssertNotCalled(t, "UpdateMetadata", tc.authParam, tc.idParam, tc.metadataParam)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// Update runs the use case to change the thing's name and the metadata that describes it.
+// The name is kept when it's empty and the metadata when it's nil. The result is sent to
+// the clients through the updated event and the updated thing is returned.
+func (i *ThingInteractor) Update(ctx context.Context, authorization, id, name string, metadata *entities.Metadata) (*entities.Thing, error) {
+	if authorization == "" {
+		return nil, ErrAuthNotProvided
+	}
+	if id == "" {
+		return nil, ErrIDNotProvided
+	}
+
+	thing, err := i.updateThing(ctx, authorization, id, name, metadata)
+	if WillRetry(ctx, err) {
+		return nil, err
+	}
+	if err != nil {
+		sendErr := i.publisher.PublishUpdatedDevice(id, name, entities.Metadata{}, err)
+		if sendErr != nil {
+			return nil, fmt.Errorf("error sending response to client: %v: %w", sendErr, err)
+		}
+		return nil, err
+	}
+
+	sendErr := i.publisher.PublishUpdatedDevice(thing.ID, thing.Name, thing.Metadata, nil)
+	if sendErr != nil {
+		return thing, fmt.Errorf("error sending response to client: %w", sendErr)
+	}
+
+	return thing, nil
+}
+
+func (i *ThingInteractor) updateThing(ctx context.Context, authorization, id, name string, metadata *entities.Metadata) (*entities.Thing, error) {
+	if name == "" && metadata == nil {
+		return nil, ErrUpdateNotProvided
+	}
+	if name != "" && strings.TrimSpace(name) == "" {
+		return nil, ErrNameInvalid
+	}
+	if metadata != nil {
+		err := validateMetadata(*metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	thing, err := i.thingProxy.Get(ctx, authorization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		thing.Name = name
+	}
+	if metadata != nil {
+		thing.Metadata = *metadata
+	}
+
+	err = i.thingProxy.Update(ctx, authorization, id, thing.Name, thing.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	thing.Token = "" // the thing's token isn't sent to the clients
+	return thing, nil
+}
@@ -0,0 +1,143 @@
+package interactors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type UpdateThingTestCase struct {
+	name           string
+	authParam      string
+	idParam        string
+	nameParam      string
+	metadataParam  *entities.Metadata
+	expectedErr    error
+	expectedThing  *entities.Thing
+	fakeThingProxy *mocks.FakeThingProxy
+}
+
+var (
+	registeredThing = &entities.Thing{
+		ID:       "fc3fcf912d0c290a",
+		Token:    "thing-token",
+		Name:     "thing",
+		Config:   []entities.Config{{SensorID: 1}},
+		Metadata: entities.Metadata{Description: "door lock"},
+	}
+	updatedMetadata = &entities.Metadata{Tags: []string{"door"}}
+)
+
+var updateThingCases = []UpdateThingTestCase{
+	{
+		"authorization token not provided",
+		"",
+		"fc3fcf912d0c290a",
+		"renamed",
+		nil,
+		ErrAuthNotProvided,
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's id not provided",
+		"authorization-token",
+		"",
+		"renamed",
+		nil,
+		ErrIDNotProvided,
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"nothing to update",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"",
+		nil,
+		ErrUpdateNotProvided,
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's name is blank",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"  ",
+		nil,
+		ErrNameInvalid,
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's metadata is invalid",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"",
+		&entities.Metadata{Location: &entities.Location{Latitude: 100}},
+		ErrLocationInvalid,
+		nil,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing not found",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"renamed",
+		nil,
+		entities.ErrThingNotFound,
+		nil,
+		&mocks.FakeThingProxy{ReturnErr: entities.ErrThingNotFound},
+	},
+	{
+		"thing renamed keeping its metadata",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"renamed",
+		nil,
+		nil,
+		&entities.Thing{ID: "fc3fcf912d0c290a", Name: "renamed", Config: registeredThing.Config, Metadata: registeredThing.Metadata},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"thing's metadata updated keeping its name",
+		"authorization-token",
+		"fc3fcf912d0c290a",
+		"",
+		updatedMetadata,
+		nil,
+		&entities.Thing{ID: "fc3fcf912d0c290a", Name: "thing", Config: registeredThing.Config, Metadata: *updatedMetadata},
+		&mocks.FakeThingProxy{},
+	},
+}
+
+func TestUpdateThing(t *testing.T) {
+	for _, tc := range updateThingCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakePublisher := &mocks.FakePublisher{}
+			thing := *registeredThing
+			tc.fakeThingProxy.On("Get", tc.authParam, tc.idParam).
+				Return(&thing, tc.fakeThingProxy.ReturnErr).Maybe()
+			if tc.expectedThing != nil {
+				tc.fakeThingProxy.On("Update", tc.authParam, tc.idParam, tc.expectedThing.Name, tc.expectedThing.Metadata).
+					Return(nil).Once()
+				fakePublisher.On("PublishUpdatedDevice", tc.idParam, tc.expectedThing.Name, tc.expectedThing.Metadata, nil).
+					Return(nil).Once()
+			} else if tc.authParam != "" && tc.idParam != "" {
+				fakePublisher.On("PublishUpdatedDevice", tc.idParam, tc.nameParam, entities.Metadata{}, tc.expectedErr).
+					Return(nil).Once()
+			}
+
+			thingInteractor := NewThingInteractor(&mocks.FakeLogger{}, fakePublisher, tc.fakeThingProxy, &mocks.FakeSessionStore{}, &mocks.FakeCommandTracker{}, &mocks.FakeCommandQueue{}, SessionPolicyFail, RegistrationPolicyFail)
+			updated, err := thingInteractor.Update(context.Background(), tc.authParam, tc.idParam, tc.nameParam, tc.metadataParam)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedThing, updated)
+
+			fakePublisher.AssertExpectations(t)
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 	"github.com/CESARBR/knot-babeltower/pkg/user/interactors"
+	"github.com/gorilla/mux"
 )
 
 // UserController represents the controller for user
@@ -17,6 +18,10 @@ type UserController struct {
 	createUserInteractor    *interactors.CreateUser
 	createTokenInteractor   *interactors.CreateToken
 	createSessionInteractor *interactors.CreateSession
+	listSessionsInteractor  *interactors.ListSessions
+	revokeSessionInteractor *interactors.RevokeSession
+	keepAliveInteractor     *interactors.KeepAliveSession
+	updateSessionInteractor *interactors.UpdateSession
 }
 
 // CreateTokenRequest represents the received parameters for CreateToken operation
@@ -43,8 +48,21 @@ func NewUserController(
 	logger logging.Logger,
 	createUserInteractor *interactors.CreateUser,
 	createTokenInteractor *interactors.CreateToken,
-	createSessionInteractor *interactors.CreateSession) *UserController {
-	return &UserController{logger, createUserInteractor, createTokenInteractor, createSessionInteractor}
+	createSessionInteractor *interactors.CreateSession,
+	listSessionsInteractor *interactors.ListSessions,
+	revokeSessionInteractor *interactors.RevokeSession,
+	keepAliveInteractor *interactors.KeepAliveSession,
+	updateSessionInteractor *interactors.UpdateSession) *UserController {
+	return &UserController{
+		logger,
+		createUserInteractor,
+		createTokenInteractor,
+		createSessionInteractor,
+		listSessionsInteractor,
+		revokeSessionInteractor,
+		keepAliveInteractor,
+		updateSessionInteractor,
+	}
 }
 
 // Create godoc
@@ -72,7 +90,7 @@ func (uc *UserController) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = uc.createUserInteractor.Execute(user)
+	err = uc.createUserInteractor.Execute(r.Context(), user)
 	if err != nil {
 		uc.logger.Errorf("failed to create user")
 		der := &DetailedErrorResponse{err.Error()}
@@ -111,7 +129,7 @@ func (uc *UserController) CreateToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := entities.User{Email: req.Email, Password: req.Password, Token: req.Token}
-	token, err := uc.createTokenInteractor.Execute(user, req.Type, req.Duration)
+	token, err := uc.createTokenInteractor.Execute(r.Context(), user, req.Type, req.Duration)
 	if err != nil {
 		uc.logger.Errorf("failed to create user's token: %s", err)
 		der := &DetailedErrorResponse{err.Error()}
@@ -128,8 +146,9 @@ func (uc *UserController) CreateToken(w http.ResponseWriter, r *http.Request) {
 // @Summary Generate a user's session ID
 // @Produce json
 // @Accept  json
-// @Param user body network.CreateSessionRequest true "User or application token"
+// @Param user body network.CreateSessionRequest true "User or application token and the optional filter of the session's data"
 // @Success 201 {object} network.CreateSessionResponse "Session ID"
+// @Failure 400 {object} DetailedErrorResponse "Invalid filter"
 // @Failure 403 {object} DetailedErrorResponse "Invalid credentials"
 // @Failure 500 {string} string "Internal server error"
 // @Router /sessions [post]
@@ -143,7 +162,7 @@ func (uc *UserController) CreateSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	id, err := uc.createSessionInteractor.Execute(req.Token)
+	id, err := uc.createSessionInteractor.Execute(r.Context(), req.Token, req.Filter)
 	if err != nil {
 		uc.logger.Errorf("failed to create user's messaging session: %s", err)
 		der := &DetailedErrorResponse{err.Error()}
@@ -155,6 +174,113 @@ func (uc *UserController) CreateSession(w http.ResponseWriter, r *http.Request)
 	uc.writeResponse(w, http.StatusCreated, ctr)
 }
 
+// ListSessions godoc
+// @Summary List the user's session IDs
+// @Produce json
+// @Param Authorization header string true "User or application token"
+// @Success 200 {object} network.ListSessionsResponse "Session IDs"
+// @Failure 403 {object} DetailedErrorResponse "Invalid credentials"
+// @Failure 500 {string} string "Internal server error"
+// @Router /sessions [get]
+// ListSessions handles the server request and calls ListSessionsInteractor
+func (uc *UserController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := uc.listSessionsInteractor.Execute(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		uc.logger.Errorf("failed to list user's messaging sessions: %s", err)
+		der := &DetailedErrorResponse{err.Error()}
+		uc.writeResponse(w, mapErrorToStatusCode(err), der)
+		return
+	}
+
+	lsr := &network.ListSessionsResponse{Sessions: sessions}
+	uc.writeResponse(w, http.StatusOK, lsr)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a user's session
+// @Produce json
+// @Param Authorization header string true "User or application token"
+// @Param id path string true "Session ID"
+// @Success 204 "Session revoked"
+// @Failure 403 {object} DetailedErrorResponse "Invalid credentials"
+// @Failure 404 {object} DetailedErrorResponse "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /sessions/{id} [delete]
+// RevokeSession handles the server request and calls RevokeSessionInteractor
+func (uc *UserController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := uc.revokeSessionInteractor.Execute(r.Context(), r.Header.Get("Authorization"), id)
+	if err != nil {
+		uc.logger.Errorf("failed to revoke user's messaging session %s: %s", id, err)
+		der := &DetailedErrorResponse{err.Error()}
+		uc.writeResponse(w, mapErrorToStatusCode(err), der)
+		return
+	}
+
+	uc.logger.Infof("session %s revoked", id)
+	uc.writeResponse(w, http.StatusNoContent, nil)
+}
+
+// KeepAliveSession godoc
+// @Summary Extend the expiration of a user's session
+// @Produce json
+// @Param Authorization header string true "User or application token"
+// @Param id path string true "Session ID"
+// @Success 204 "Session expiration extended"
+// @Failure 403 {object} DetailedErrorResponse "Invalid credentials"
+// @Failure 404 {object} DetailedErrorResponse "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /sessions/{id}/keepalive [post]
+// KeepAliveSession handles the server request and calls KeepAliveSessionInteractor
+func (uc *UserController) KeepAliveSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := uc.keepAliveInteractor.Execute(r.Context(), r.Header.Get("Authorization"), id)
+	if err != nil {
+		uc.logger.Errorf("failed to keep user's messaging session %s alive: %s", id, err)
+		der := &DetailedErrorResponse{err.Error()}
+		uc.writeResponse(w, mapErrorToStatusCode(err), der)
+		return
+	}
+
+	uc.writeResponse(w, http.StatusNoContent, nil)
+}
+
+// UpdateSession godoc
+// @Summary Replace the filter of a user's session data
+// @Produce json
+// @Accept  json
+// @Param Authorization header string true "User or application token"
+// @Param id path string true "Session ID"
+// @Param session body network.UpdateSessionRequest true "Filter of the session's data"
+// @Success 204 "Session updated"
+// @Failure 400 {object} DetailedErrorResponse "Invalid filter"
+// @Failure 403 {object} DetailedErrorResponse "Invalid credentials"
+// @Failure 404 {object} DetailedErrorResponse "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /sessions/{id} [patch]
+// UpdateSession handles the server request and calls UpdateSessionInteractor
+func (uc *UserController) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	var req network.UpdateSessionRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		uc.logger.Error("failed to parse request body")
+		uc.writeResponse(w, http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	err = uc.updateSessionInteractor.Execute(r.Context(), r.Header.Get("Authorization"), id, req.Filter)
+	if err != nil {
+		uc.logger.Errorf("failed to update user's messaging session %s: %s", id, err)
+		der := &DetailedErrorResponse{err.Error()}
+		uc.writeResponse(w, mapErrorToStatusCode(err), der)
+		return
+	}
+
+	uc.logger.Infof("session %s updated", id)
+	uc.writeResponse(w, http.StatusNoContent, nil)
+}
+
 func (uc *UserController) writeResponse(w http.ResponseWriter, statusCode int, msg interface{}) {
 	w.WriteHeader(statusCode)
 
@@ -178,11 +304,13 @@ func (uc *UserController) writeResponse(w http.ResponseWriter, statusCode int, m
 
 func mapErrorToStatusCode(err error) int {
 	switch err {
-	case entities.ErrUserForbidden:
+	case entities.ErrUserForbidden, entities.ErrTokenForbidden:
 		return http.StatusForbidden
+	case entities.ErrSessionNotFound:
+		return http.StatusNotFound
 	case entities.ErrUserExists:
 		return http.StatusConflict
-	case entities.ErrUserBadRequest:
+	case entities.ErrUserBadRequest, entities.ErrSessionFilterInvalid:
 		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
@@ -2,11 +2,14 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 )
 
@@ -16,7 +19,7 @@ import (
 // the platform.
 // https://github.com/mainflux/mainflux/blob/0.12.1/auth/openapi.yml
 type AuthProxy interface {
-	CreateAppToken(user entities.User, duration int) (token string, err error)
+	CreateAppToken(ctx context.Context, user entities.User, duration int) (token string, err error)
 }
 
 // authProxy takes a URL address that points to the Mainflux authProxy service and implements
@@ -24,14 +27,15 @@ type AuthProxy interface {
 type authProxy struct {
 	URL    string
 	logger logging.Logger
+	client *network.HTTPClient
 }
 
 // NewAuthProxy creates a new auth instance and returns a pointer to the AuthProxy interface
-// implementation.
-func NewAuthProxy(logger logging.Logger, hostname string, port uint16) *authProxy {
+// implementation. The requests are sent through the client.
+func NewAuthProxy(logger logging.Logger, client *network.HTTPClient, hostname string, port uint16) *authProxy {
 	URL := fmt.Sprintf("http://%s:%d", hostname, port)
 	logger.Debug("auth proxy configured to " + URL)
-	return &authProxy{URL, logger}
+	return &authProxy{URL, logger, client}
 }
 
 // authRequest represents a HTTP request to the Mainflux Auth service. It basically has
@@ -68,7 +72,7 @@ type keyResponseSchema struct {
 
 // CreateAppToken creates a new application token in the Mainflux platform. This type of
 // token has a configurable duration.
-func (a *authProxy) CreateAppToken(user entities.User, duration int) (string, error) {
+func (a *authProxy) CreateAppToken(ctx context.Context, user entities.User, duration int) (string, error) {
 	var response keyResponseSchema
 	request := authRequest{
 		Path:          "/keys",
@@ -77,7 +81,7 @@ func (a *authProxy) CreateAppToken(user entities.User, duration int) (string, er
 		Authorization: user.Token,
 	}
 
-	err := a.doRequest(request, &response)
+	err := a.doRequest(ctx, request, &response)
 	if err != nil {
 		return "", fmt.Errorf("error requesting a new app token: %w", err)
 	}
@@ -85,21 +89,23 @@ func (a *authProxy) CreateAppToken(user entities.User, duration int) (string, er
 	return response.Value, nil
 }
 
-func (a *authProxy) doRequest(request authRequest, response interface{}) error {
+func (a *authProxy) doRequest(ctx context.Context, request authRequest, response interface{}) error {
 	body, err := json.Marshal(&request.Body)
 	if err != nil {
 		return fmt.Errorf("error encoding body: %w", err)
 	}
 
-	req, err := http.NewRequest(request.Method, a.URL+request.Path, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, request.Method, a.URL+request.Path, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("error creating request object: %w", err)
 	}
 	req.Header.Add("Authorization", request.Authorization)
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.client.Do(req)
+	if errors.Is(err, network.ErrServiceFailure) {
+		return entities.ErrService
+	}
 	if err != nil {
 		return fmt.Errorf("error executing HTTP request: %w", err)
 	}
@@ -129,8 +135,6 @@ func (a *authProxy) mapErrorFromStatusCode(code int) error {
 		err = entities.ErrExistingID
 	case http.StatusUnsupportedMediaType:
 		err = entities.ErrMissingContentType
-	case http.StatusInternalServerError:
-		err = entities.ErrService
 	}
 
 	return err
@@ -2,25 +2,27 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/network"
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
 )
 
 // UsersProxy represents the interface to the user's proxy operations
 type UsersProxy interface {
-	Create(user entities.User) (err error)
-	CreateToken(user entities.User) (token string, err error)
+	Create(ctx context.Context, user entities.User) (err error)
+	CreateToken(ctx context.Context, user entities.User) (token string, err error)
 }
 
 // Users is responsible for implementing the user's proxy operations
 type Users struct {
 	URL    string
 	logger logging.Logger
+	client *network.HTTPClient
 }
 
 // UserTokenResponse represents the create token response from the user's service
@@ -28,26 +30,22 @@ type UserTokenResponse struct {
 	Token string `json:"token"`
 }
 
-// NewUsersProxy creates a new Proxy instance
-func NewUsersProxy(logger logging.Logger, userHost string, userPort uint16) *Users {
+// NewUsersProxy creates a new Proxy instance, which sends the requests through the client
+func NewUsersProxy(logger logging.Logger, client *network.HTTPClient, userHost string, userPort uint16) *Users {
 	URL := fmt.Sprintf("http://%s:%d", userHost, userPort)
 	logger.Debug("user proxy configured to " + URL)
-	return &Users{URL, logger}
+	return &Users{URL, logger, client}
 }
 
 // Create proxy the http request to user service
-func (p *Users) Create(user entities.User) (err error) {
+func (p *Users) Create(ctx context.Context, user entities.User) (err error) {
 	p.logger.Debug("proxying request to create user")
-	/**
-	 * Add Timeout in http.Client to avoid blocking the request.
-	 */
-	client := &http.Client{Timeout: 10 * time.Second}
 	jsonUser, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
 
-	resp, err := client.Post(p.URL+"/users", "application/json", bytes.NewBuffer(jsonUser))
+	resp, err := p.post(ctx, "/users", jsonUser)
 	if err != nil {
 		return err
 	}
@@ -57,7 +55,7 @@ func (p *Users) Create(user entities.User) (err error) {
 }
 
 // CreateToken creates a valid token for the specified user
-func (p *Users) CreateToken(user entities.User) (string, error) {
+func (p *Users) CreateToken(ctx context.Context, user entities.User) (string, error) {
 	var resp *http.Response
 
 	credentials, err := json.Marshal(user)
@@ -65,8 +63,7 @@ func (p *Users) CreateToken(user entities.User) (string, error) {
 		return "", err
 	}
 
-	client := &http.Client{}
-	resp, err = client.Post(p.URL+"/tokens", "application/json", bytes.NewBuffer(credentials))
+	resp, err = p.post(ctx, "/tokens", credentials)
 	if err != nil {
 		return "", err
 	}
@@ -87,6 +84,16 @@ func (p *Users) CreateToken(user entities.User) (string, error) {
 	return tr.Token, nil
 }
 
+func (p *Users) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL+path, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return p.client.Do(req)
+}
+
 func (p *Users) mapErrorFromStatusCode(code int) error {
 	var err error
 
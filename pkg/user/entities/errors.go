@@ -29,4 +29,11 @@ var (
 
 	// ErrUserBadRequest represents the error when request body is in wrong format
 	ErrUserBadRequest = errors.New("unsupported content type, verify e-mail format")
+
+	// ErrSessionNotFound is returned when the session doesn't exist or belongs to another user
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSessionFilterInvalid is returned when the session filter has empty thing IDs or
+	// tags, or negative sensor IDs
+	ErrSessionFilterInvalid = errors.New("invalid session filter")
 )
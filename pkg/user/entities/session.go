@@ -0,0 +1,17 @@
+package entities
+
+// Session represents a user's session, which receives the data published by the user's
+// things. It is identified by its ID.
+type Session struct {
+	ID    string
+	Email string
+}
+
+// SessionFilter selects the data published to a session. The data must match every
+// criterion provided, while the criteria left empty match any data: it must be sent by
+// one of the things, from one of the sensors and by a thing tagged with all the tags.
+type SessionFilter struct {
+	ThingIDs  []string `json:"thingIds,omitempty"`
+	SensorIDs []int    `json:"sensorIds,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
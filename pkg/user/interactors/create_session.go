@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/CESARBR/knot-babeltower/pkg/cache"
@@ -27,38 +28,69 @@ func NewCreateSession(
 	return &CreateSession{thingsProxy, generator, sessionStore}
 }
 
-// Execute creates a new user session by receiving the authorization token as parameter.
-// This function will verify if a session already exists for the users. If not, it will
-// create a new session ID and save it in some caching storage.
-func (cs *CreateSession) Execute(authorization string) (string, error) {
-	email, err := jwt.GetEmail(authorization)
+// Execute creates a new user session by receiving the authorization token and the filter
+// of the session's data as parameters. A user can have several sessions at once, so a new
+// session ID is always created and saved in some caching storage.
+func (cs *CreateSession) Execute(ctx context.Context, authorization string, filter entities.SessionFilter) (string, error) {
+	if !isFilterValid(filter) {
+		return "", entities.ErrSessionFilterInvalid
+	}
+
+	email, err := authenticateUser(ctx, cs.thingsProxy, authorization)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user email from authorization token: %w", err)
+		return "", err
 	}
 
-	if !cs.isValidToken(authorization) {
-		return "", entities.ErrTokenForbidden
+	id := cs.generator.ID()
+	err = cs.sessionStore.Save(ctx, email, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to save user session: %w", err)
 	}
 
-	session, err := cs.sessionStore.Get(email)
+	err = cs.sessionStore.SetFilter(ctx, id, filter)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user session: %w", err)
+		return "", fmt.Errorf("failed to save user session filter: %w", err)
 	}
 
-	if session != "" {
-		return session, nil
+	return id, err
+}
+
+// authenticateUser returns the e-mail of the user who owns the authorization token, which
+// is validated against the thing's service
+func authenticateUser(ctx context.Context, thingsProxy http.ThingProxy, authorization string) (string, error) {
+	email, err := jwt.GetEmail(authorization)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user email from authorization token: %w", err)
 	}
 
-	id := cs.generator.ID()
-	err = cs.sessionStore.Save(email, id)
+	_, err = thingsProxy.List(ctx, authorization)
 	if err != nil {
-		return "", fmt.Errorf("failed to save user session: %w", err)
+		return "", entities.ErrTokenForbidden
 	}
 
-	return id, err
+	return email, nil
 }
 
-func (cs *CreateSession) isValidToken(authorization string) bool {
-	_, err := cs.thingsProxy.List(authorization)
-	return err == nil
+// isFilterValid verifies if the filter has neither empty thing IDs or tags nor negative
+// sensor IDs
+func isFilterValid(filter entities.SessionFilter) bool {
+	for _, id := range filter.ThingIDs {
+		if id == "" {
+			return false
+		}
+	}
+
+	for _, id := range filter.SensorIDs {
+		if id < 0 {
+			return false
+		}
+	}
+
+	for _, tag := range filter.Tags {
+		if tag == "" {
+			return false
+		}
+	}
+
+	return true
 }
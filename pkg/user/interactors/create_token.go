@@ -1,6 +1,8 @@
 package interactors
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	"github.com/CESARBR/knot-babeltower/pkg/user/delivery/http"
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
@@ -21,11 +23,11 @@ func NewCreateToken(logger logging.Logger, usersProxy http.UsersProxy, authProxy
 
 // Execute receives the user entity filled with e-mail and password properties and try
 // to create a token on the user proxy service. If it succeed, the token is returned.
-func (ct *CreateToken) Execute(user entities.User, tokenType string, duration int) (token string, err error) {
+func (ct *CreateToken) Execute(ctx context.Context, user entities.User, tokenType string, duration int) (token string, err error) {
 	if tokenType == "user" {
-		token, err = ct.usersProxy.CreateToken(user)
+		token, err = ct.usersProxy.CreateToken(ctx, user)
 	} else if tokenType == "app" {
-		token, err = ct.authProxy.CreateAppToken(user, duration)
+		token, err = ct.authProxy.CreateAppToken(ctx, user, duration)
 	} else {
 		err = entities.ErrInvalidTokenType
 	}
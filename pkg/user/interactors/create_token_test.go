@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -100,7 +101,7 @@ func TestCreateToken(t *testing.T) {
 				Return(tc.fakeAuthProxy.Token, tc.fakeUsersProxy.Err)
 
 			createTokenInteractor := NewCreateToken(tc.fakeLogger, tc.fakeUsersProxy, tc.fakeAuthProxy)
-			token, err := createTokenInteractor.Execute(tc.user, tc.tokenType, tc.duration)
+			token, err := createTokenInteractor.Execute(context.Background(), tc.user, tc.tokenType, tc.duration)
 
 			assert.Equal(t, tc.expected.token, token)
 			if err != nil {
@@ -1,6 +1,8 @@
 package interactors
 
 import (
+	"context"
+
 	"github.com/CESARBR/knot-babeltower/pkg/logging"
 	"github.com/CESARBR/knot-babeltower/pkg/user/delivery/http"
 	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
@@ -18,8 +20,8 @@ func NewCreateUser(logger logging.Logger, usersProxy http.UsersProxy) *CreateUse
 }
 
 // Execute runs the use case
-func (cu *CreateUser) Execute(user entities.User) (err error) {
-	err = cu.usersProxy.Create(user)
+func (cu *CreateUser) Execute(ctx context.Context, user entities.User) (err error) {
+	err = cu.usersProxy.Create(ctx, user)
 	if err != nil {
 		cu.logger.Errorf("failed to create a new user: %s", err.Error())
 	}
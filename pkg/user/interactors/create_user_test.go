@@ -1,6 +1,7 @@
 package interactors
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,7 +54,7 @@ func TestCreateUser(t *testing.T) {
 			tc.fakeUsersProxy.On("Create", user).
 				Return(tc.fakeUsersProxy.Err).Once()
 
-			err := createUserInteractor.Execute(user)
+			err := createUserInteractor.Execute(context.Background(), user)
 			if err != nil && !assert.IsType(t, err, tc.expected) {
 				t.Errorf("create user failed. Error: %s", err)
 				tc.fakeUsersProxy.AssertExpectations(t)
@@ -0,0 +1,113 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/logging"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	thingEntities "github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+)
+
+// ExpireSessions is a use case operation that removes the sessions whose expiration has
+// passed. A session whose data is still being consumed is refreshed instead, so it only
+// expires once its clients are gone.
+type ExpireSessions struct {
+	logger       logging.Logger
+	sessionStore cache.SessionStore
+	publisher    amqp.Publisher
+	quit         chan struct{}
+}
+
+// NewExpireSessions creates a new ExpireSessions instance by receiving its dependencies.
+func NewExpireSessions(logger logging.Logger, sessionStore cache.SessionStore, publisher amqp.Publisher) *ExpireSessions {
+	return &ExpireSessions{logger, sessionStore, publisher, make(chan struct{})}
+}
+
+// Start executes the operation on every interval until Stop is called
+func (es *ExpireSessions) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := es.Execute(context.Background())
+			if err != nil {
+				es.logger.Error(err)
+			}
+		case <-es.quit:
+			return
+		}
+	}
+}
+
+// Stop stops executing the operation periodically
+func (es *ExpireSessions) Stop() {
+	close(es.quit)
+}
+
+// Execute removes the expired sessions, deleting the exchanges where their data was
+// published, and publishes them to the clients through the session.expired event. Each
+// session is claimed first, so the sessions claimed by other instances are skipped. The
+// sessions that fail to be removed are released to be retried on the next execution.
+func (es *ExpireSessions) Execute(ctx context.Context) error {
+	sessions, err := es.sessionStore.Expired(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get expired sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		claimed, err := es.sessionStore.Claim(ctx, session.ID)
+		if err != nil {
+			es.logger.Errorf("failed to claim session %s: %s", session.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		err = es.expire(ctx, session.Email, session.ID)
+		if err != nil {
+			es.logger.Errorf("failed to expire session %s: %s", session.ID, err)
+			es.release(ctx, session.ID)
+		}
+	}
+
+	return nil
+}
+
+func (es *ExpireSessions) release(ctx context.Context, id string) {
+	err := es.sessionStore.Release(ctx, id)
+	if err != nil {
+		es.logger.Errorf("failed to release session %s: %s", id, err)
+	}
+}
+
+// expire removes the session unless its data is being consumed. When the transport can't
+// verify it, e.g. MQTT, the session is only kept by being refreshed and is removed.
+func (es *ExpireSessions) expire(ctx context.Context, email, id string) error {
+	err := es.publisher.DeleteSessionExchange(id, true)
+	if errors.Is(err, thingEntities.ErrSessionConsumed) {
+		es.logger.Infof("session %s is being consumed and was refreshed", id)
+		return es.sessionStore.Refresh(ctx, id)
+	}
+	if errors.Is(err, thingEntities.ErrSessionConsumersUnknown) {
+		es.logger.Infof("session %s consumers aren't known and it wasn't refreshed", id)
+		err = es.publisher.DeleteSessionExchange(id, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = es.sessionStore.Delete(ctx, email, id)
+	if err != nil {
+		return err
+	}
+
+	es.logger.Infof("session %s expired", id)
+	return es.publisher.PublishSessionExpired(id)
+}
@@ -0,0 +1,119 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	thingEntities "github.com/CESARBR/knot-babeltower/pkg/thing/entities"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	errExpiredSessions = errors.New("failed to get expired sessions on store")
+	errSessionConsumed = fmt.Errorf("session's exchange not deleted: %w", thingEntities.ErrSessionConsumed)
+)
+
+func TestExpireSessions(t *testing.T) {
+	ctx := context.Background()
+	sessionStore := cache.NewMemorySessionStore("100ms")
+	assert.NoError(t, sessionStore.Save(ctx, sessionOwner, "expired-session"))
+	assert.NoError(t, sessionStore.Save(ctx, sessionOwner, "consumed-session"))
+	assert.NoError(t, sessionStore.Save(ctx, "user2@cesar.org.br", "failed-session"))
+	time.Sleep(120 * time.Millisecond)
+
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.On("DeleteSessionExchange", "expired-session", true).Return(nil).Once()
+	fakePublisher.On("DeleteSessionExchange", "consumed-session", true).Return(errSessionConsumed).Once()
+	fakePublisher.On("DeleteSessionExchange", "failed-session", true).Return(errDeleteExchange).Once()
+	fakePublisher.On("PublishSessionExpired", "expired-session").Return(nil).Once()
+
+	expireSessions := NewExpireSessions(&mocks.FakeLogger{}, sessionStore, fakePublisher)
+	err := expireSessions.Execute(ctx)
+	assert.NoError(t, err)
+
+	sessions, err := sessionStore.List(ctx, sessionOwner)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"consumed-session"}, sessions)
+
+	// the session that failed to be expired is retried on the next execution
+	expired, err := sessionStore.Expired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Session{{ID: "failed-session", Email: "user2@cesar.org.br"}}, expired)
+
+	fakePublisher.AssertExpectations(t)
+}
+
+func TestExpireSessionsSkipsSessionClaimedByAnotherInstance(t *testing.T) {
+	fakeSessionStore := &mocks.FakeSessionStore{}
+	fakeSessionStore.On("Expired").Return([]entities.Session{{ID: "expired-session", Email: sessionOwner}}, nil)
+	fakeSessionStore.On("Claim", "expired-session").Return(false, nil)
+	fakePublisher := &mocks.FakePublisher{}
+
+	expireSessions := NewExpireSessions(&mocks.FakeLogger{}, fakeSessionStore, fakePublisher)
+	err := expireSessions.Execute(context.Background())
+	assert.NoError(t, err)
+
+	fakePublisher.AssertNotCalled(t, "DeleteSessionExchange", "expired-session", true)
+	fakeSessionStore.AssertNotCalled(t, "Delete", sessionOwner, "expired-session")
+}
+
+func TestExpireSessionsWhoseConsumersAreUnknown(t *testing.T) {
+	ctx := context.Background()
+	sessionStore := cache.NewMemorySessionStore("50ms")
+	assert.NoError(t, sessionStore.Save(ctx, sessionOwner, "expired-session"))
+	time.Sleep(60 * time.Millisecond)
+
+	errConsumersUnknown := fmt.Errorf("session's exchange not deleted: %w", thingEntities.ErrSessionConsumersUnknown)
+	fakePublisher := &mocks.FakePublisher{}
+	fakePublisher.On("DeleteSessionExchange", "expired-session", true).Return(errConsumersUnknown).Once()
+	fakePublisher.On("DeleteSessionExchange", "expired-session", false).Return(nil).Once()
+	fakePublisher.On("PublishSessionExpired", "expired-session").Return(nil).Once()
+
+	expireSessions := NewExpireSessions(&mocks.FakeLogger{}, sessionStore, fakePublisher)
+	assert.NoError(t, expireSessions.Execute(ctx))
+
+	sessions, err := sessionStore.List(ctx, sessionOwner)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	fakePublisher.AssertExpectations(t)
+}
+
+func TestExpireSessionsFailsToGetExpiredSessions(t *testing.T) {
+	fakeSessionStore := &mocks.FakeSessionStore{}
+	fakeSessionStore.On("Expired").Return([]entities.Session(nil), errExpiredSessions)
+
+	expireSessions := NewExpireSessions(&mocks.FakeLogger{}, fakeSessionStore, &mocks.FakePublisher{})
+	err := expireSessions.Execute(context.Background())
+	assert.True(t, errors.Is(err, errExpiredSessions))
+}
+
+func TestExpireSessionsPeriodically(t *testing.T) {
+	executed := make(chan bool, 1)
+	fakeSessionStore := &mocks.FakeSessionStore{}
+	fakeSessionStore.
+		On("Expired").
+		Return([]entities.Session{}, nil).
+		Run(func(_ mock.Arguments) {
+			select {
+			case executed <- true:
+			default:
+			}
+		})
+
+	expireSessions := NewExpireSessions(&mocks.FakeLogger{}, fakeSessionStore, &mocks.FakePublisher{})
+	go expireSessions.Start(10 * time.Millisecond)
+	defer expireSessions.Stop()
+
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the sessions to be expired")
+	}
+}
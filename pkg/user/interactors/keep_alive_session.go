@@ -0,0 +1,47 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+)
+
+// KeepAliveSession is a use case operation that extends the expiration of one of the
+// user's sessions, which keeps receiving the thing's data.
+type KeepAliveSession struct {
+	thingsProxy  http.ThingProxy
+	sessionStore cache.SessionStore
+}
+
+// NewKeepAliveSession creates a new KeepAliveSession instance by receiving its dependencies.
+func NewKeepAliveSession(thingsProxy http.ThingProxy, sessionStore cache.SessionStore) *KeepAliveSession {
+	return &KeepAliveSession{thingsProxy, sessionStore}
+}
+
+// Execute refreshes the session expiration. Only the sessions owned by the user of the
+// authorization token can be kept alive.
+func (ks *KeepAliveSession) Execute(ctx context.Context, authorization, id string) error {
+	email, err := authenticateUser(ctx, ks.thingsProxy, authorization)
+	if err != nil {
+		return err
+	}
+
+	owner, err := ks.sessionStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get user session: %w", err)
+	}
+
+	if owner != email {
+		return entities.ErrSessionNotFound
+	}
+
+	err = ks.sessionStore.Refresh(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to refresh user session: %w", err)
+	}
+
+	return nil
+}
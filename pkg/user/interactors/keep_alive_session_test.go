@@ -0,0 +1,108 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type keepAliveSession struct {
+	name           string
+	authorization  string
+	id             string
+	expectedErr    error
+	refreshed      bool
+	fakeThingProxy *mocks.FakeThingProxy
+}
+
+var errRefreshSession = errors.New("failed to refresh session on store")
+
+var kasCases = []keepAliveSession{
+	{
+		"user session successfully kept alive",
+		validToken,
+		"session-id",
+		nil,
+		true,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to keep session alive if token is invalid",
+		validToken,
+		"session-id",
+		entities.ErrTokenForbidden,
+		false,
+		&mocks.FakeThingProxy{ReturnErr: errTokenValidation},
+	},
+	{
+		"failed to keep alive session that doesn't exist",
+		validToken,
+		"unknown-session",
+		entities.ErrSessionNotFound,
+		false,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to keep alive session owned by another user",
+		validToken,
+		"other-user-session",
+		entities.ErrSessionNotFound,
+		false,
+		&mocks.FakeThingProxy{},
+	},
+}
+
+func TestKeepAliveSession(t *testing.T) {
+	for _, tc := range kasCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("List", tc.authorization).
+				Return(tc.fakeThingProxy.Things, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+
+			ctx := context.Background()
+			sessionStore := cache.NewMemorySessionStore("100ms")
+			assert.NoError(t, sessionStore.Save(ctx, sessionOwner, "session-id"))
+			assert.NoError(t, sessionStore.Save(ctx, "user2@cesar.org.br", "other-user-session"))
+			time.Sleep(60 * time.Millisecond)
+
+			keepAliveInteractor := NewKeepAliveSession(tc.fakeThingProxy, sessionStore)
+			err := keepAliveInteractor.Execute(ctx, tc.authorization, tc.id)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			time.Sleep(60 * time.Millisecond)
+			expired, err := sessionStore.Expired(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, !tc.refreshed, containsSession(expired, "session-id"))
+
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKeepAliveSessionFailsToRefreshSession(t *testing.T) {
+	fakeThingProxy := &mocks.FakeThingProxy{}
+	fakeThingProxy.On("List", validToken).Return(fakeThingProxy.Things, nil)
+	fakeSessionStore := &mocks.FakeSessionStore{}
+	fakeSessionStore.On("Get", "session-id").Return(sessionOwner, nil)
+	fakeSessionStore.On("Refresh", "session-id").Return(errRefreshSession)
+
+	keepAliveInteractor := NewKeepAliveSession(fakeThingProxy, fakeSessionStore)
+	err := keepAliveInteractor.Execute(context.Background(), validToken, "session-id")
+	assert.True(t, errors.Is(err, errRefreshSession))
+}
+
+func containsSession(sessions []entities.Session, id string) bool {
+	for _, s := range sessions {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
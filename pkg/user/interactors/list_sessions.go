@@ -0,0 +1,35 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
+)
+
+// ListSessions is a use case operation that lists the user's live sessions.
+type ListSessions struct {
+	thingsProxy  http.ThingProxy
+	sessionStore cache.SessionStore
+}
+
+// NewListSessions creates a new ListSessions instance by receiving its dependencies.
+func NewListSessions(thingsProxy http.ThingProxy, sessionStore cache.SessionStore) *ListSessions {
+	return &ListSessions{thingsProxy, sessionStore}
+}
+
+// Execute lists the IDs of the sessions owned by the user of the authorization token.
+func (ls *ListSessions) Execute(ctx context.Context, authorization string) ([]string, error) {
+	email, err := authenticateUser(ctx, ls.thingsProxy, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := ls.sessionStore.List(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user sessions: %w", err)
+	}
+
+	return sessions, nil
+}
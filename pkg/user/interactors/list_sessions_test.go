@@ -0,0 +1,94 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/jwt"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type listSessions struct {
+	name             string
+	authorization    string
+	expectedSessions []string
+	expectedErr      error
+	sessionStore     cache.SessionStore
+	fakeThingProxy   *mocks.FakeThingProxy
+}
+
+var errListSessions = errors.New("failed to list sessions on store")
+
+var lsCases = []listSessions{
+	{
+		"user sessions successfully listed",
+		validToken,
+		[]string{"another-session-id", "session-id"},
+		nil,
+		newSessionStore(map[string]string{
+			"session-id":         sessionOwner,
+			"another-session-id": sessionOwner,
+			"other-user-session": "user2@cesar.org.br",
+		}),
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"no sessions listed for user without sessions",
+		validToken,
+		[]string{},
+		nil,
+		newSessionStore(map[string]string{"other-user-session": "user2@cesar.org.br"}),
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to list sessions if token is invalid",
+		validToken,
+		nil,
+		entities.ErrTokenForbidden,
+		newSessionStore(nil),
+		&mocks.FakeThingProxy{ReturnErr: errTokenValidation},
+	},
+	{
+		"failed to list sessions if token can't be parsed",
+		"invalid-token",
+		nil,
+		jwt.ErrParseToken,
+		newSessionStore(nil),
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to list sessions on session store",
+		validToken,
+		nil,
+		errListSessions,
+		func() cache.SessionStore {
+			fakeSessionStore := &mocks.FakeSessionStore{}
+			fakeSessionStore.On("List", sessionOwner).Return([]string(nil), errListSessions)
+			return fakeSessionStore
+		}(),
+		&mocks.FakeThingProxy{},
+	},
+}
+
+func TestListSessions(t *testing.T) {
+	for _, tc := range lsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("List", tc.authorization).
+				Return(tc.fakeThingProxy.Things, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+
+			listSessionsInteractor := NewListSessions(tc.fakeThingProxy, tc.sessionStore)
+			sessions, err := listSessionsInteractor.Execute(context.Background(), tc.authorization)
+
+			assert.Equal(t, tc.expectedSessions, sessions)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
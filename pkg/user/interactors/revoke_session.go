@@ -0,0 +1,55 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/amqp"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+)
+
+// RevokeSession is a use case operation that revokes one of the user's sessions, which
+// stops receiving the thing's data.
+type RevokeSession struct {
+	thingsProxy  http.ThingProxy
+	sessionStore cache.SessionStore
+	publisher    amqp.Publisher
+}
+
+// NewRevokeSession creates a new RevokeSession instance by receiving its dependencies.
+func NewRevokeSession(thingsProxy http.ThingProxy, sessionStore cache.SessionStore, publisher amqp.Publisher) *RevokeSession {
+	return &RevokeSession{thingsProxy, sessionStore, publisher}
+}
+
+// Execute removes the session from the caching storage and deletes the exchange where its
+// data was published. Only the sessions owned by the user of the authorization token can
+// be revoked.
+func (rs *RevokeSession) Execute(ctx context.Context, authorization, id string) error {
+	email, err := authenticateUser(ctx, rs.thingsProxy, authorization)
+	if err != nil {
+		return err
+	}
+
+	owner, err := rs.sessionStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get user session: %w", err)
+	}
+
+	if owner != email {
+		return entities.ErrSessionNotFound
+	}
+
+	err = rs.publisher.DeleteSessionExchange(id, false)
+	if err != nil {
+		return fmt.Errorf("failed to delete session's exchange: %w", err)
+	}
+
+	err = rs.sessionStore.Delete(ctx, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type revokeSession struct {
+	name           string
+	authorization  string
+	id             string
+	expectedErr    error
+	expectedOwner  string
+	sessionStore   cache.SessionStore
+	fakeThingProxy *mocks.FakeThingProxy
+	fakePublisher  *mocks.FakePublisher
+}
+
+var (
+	errDeleteSession  = errors.New("failed to delete session on store")
+	errDeleteExchange = errors.New("failed to delete session's exchange")
+)
+
+var rsCases = []revokeSession{
+	{
+		"user session successfully revoked",
+		validToken,
+		"session-id",
+		nil,
+		"",
+		newSessionStore(map[string]string{"session-id": sessionOwner}),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{},
+	},
+	{
+		"failed to revoke session if token is invalid",
+		validToken,
+		"session-id",
+		entities.ErrTokenForbidden,
+		sessionOwner,
+		newSessionStore(map[string]string{"session-id": sessionOwner}),
+		&mocks.FakeThingProxy{ReturnErr: errTokenValidation},
+		&mocks.FakePublisher{},
+	},
+	{
+		"failed to revoke session that doesn't exist",
+		validToken,
+		"session-id",
+		entities.ErrSessionNotFound,
+		"",
+		newSessionStore(nil),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{},
+	},
+	{
+		"failed to revoke session owned by another user",
+		validToken,
+		"session-id",
+		entities.ErrSessionNotFound,
+		"user2@cesar.org.br",
+		newSessionStore(map[string]string{"session-id": "user2@cesar.org.br"}),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{},
+	},
+	{
+		"failed to get session from session store",
+		validToken,
+		"session-id",
+		errGetSession,
+		"",
+		func() cache.SessionStore {
+			fakeSessionStore := &mocks.FakeSessionStore{}
+			fakeSessionStore.On("Get", "session-id").Return("", errGetSession)
+			return fakeSessionStore
+		}(),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{},
+	},
+	{
+		"failed to delete session's exchange",
+		validToken,
+		"session-id",
+		errDeleteExchange,
+		sessionOwner,
+		newSessionStore(map[string]string{"session-id": sessionOwner}),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{Err: errDeleteExchange},
+	},
+	{
+		"failed to delete session from session store",
+		validToken,
+		"session-id",
+		errDeleteSession,
+		sessionOwner,
+		func() cache.SessionStore {
+			fakeSessionStore := &mocks.FakeSessionStore{}
+			fakeSessionStore.On("Get", "session-id").Return(sessionOwner, nil)
+			fakeSessionStore.On("Delete", sessionOwner, "session-id").Return(errDeleteSession)
+			return fakeSessionStore
+		}(),
+		&mocks.FakeThingProxy{},
+		&mocks.FakePublisher{},
+	},
+}
+
+func TestRevokeSession(t *testing.T) {
+	for _, tc := range rsCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("List", tc.authorization).
+				Return(tc.fakeThingProxy.Things, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+			tc.fakePublisher.
+				On("DeleteSessionExchange", tc.id, false).
+				Return(tc.fakePublisher.Err).
+				Maybe()
+
+			revokeSessionInteractor := NewRevokeSession(tc.fakeThingProxy, tc.sessionStore, tc.fakePublisher)
+			err := revokeSessionInteractor.Execute(context.Background(), tc.authorization, tc.id)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			owner, _ := tc.sessionStore.Get(context.Background(), tc.id)
+			assert.Equal(t, tc.expectedOwner, owner)
+			if tc.expectedErr == entities.ErrSessionNotFound {
+				tc.fakePublisher.AssertNotCalled(t, "DeleteSessionExchange", tc.id, false)
+			}
+
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
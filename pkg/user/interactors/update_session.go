@@ -0,0 +1,51 @@
+package interactors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CESARBR/knot-babeltower/pkg/cache"
+	"github.com/CESARBR/knot-babeltower/pkg/thing/delivery/http"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+)
+
+// UpdateSession is a use case operation that updates one of the user's sessions, replacing
+// the filter of the thing's data published to it.
+type UpdateSession struct {
+	thingsProxy  http.ThingProxy
+	sessionStore cache.SessionStore
+}
+
+// NewUpdateSession creates a new UpdateSession instance by receiving its dependencies.
+func NewUpdateSession(thingsProxy http.ThingProxy, sessionStore cache.SessionStore) *UpdateSession {
+	return &UpdateSession{thingsProxy, sessionStore}
+}
+
+// Execute replaces the session filter. Only the sessions owned by the user of the
+// authorization token can be updated.
+func (us *UpdateSession) Execute(ctx context.Context, authorization, id string, filter entities.SessionFilter) error {
+	if !isFilterValid(filter) {
+		return entities.ErrSessionFilterInvalid
+	}
+
+	email, err := authenticateUser(ctx, us.thingsProxy, authorization)
+	if err != nil {
+		return err
+	}
+
+	owner, err := us.sessionStore.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get user session: %w", err)
+	}
+
+	if owner != email {
+		return entities.ErrSessionNotFound
+	}
+
+	err = us.sessionStore.SetFilter(ctx, id, filter)
+	if err != nil {
+		return fmt.Errorf("failed to save user session filter: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package interactors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CESARBR/knot-babeltower/pkg/mocks"
+	"github.com/CESARBR/knot-babeltower/pkg/user/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type updateSession struct {
+	name           string
+	authorization  string
+	id             string
+	filter         entities.SessionFilter
+	expectedErr    error
+	expectedFilter entities.SessionFilter
+	fakeThingProxy *mocks.FakeThingProxy
+}
+
+var (
+	previousFilter = entities.SessionFilter{Tags: []string{"kitchen"}}
+	kitchenFilter  = entities.SessionFilter{ThingIDs: []string{"fc3fcf912d0c290a"}, SensorIDs: []int{0}}
+)
+
+var usCases = []updateSession{
+	{
+		"user session filter successfully replaced",
+		validToken,
+		"session-id",
+		kitchenFilter,
+		nil,
+		kitchenFilter,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"user session filter successfully removed",
+		validToken,
+		"session-id",
+		entities.SessionFilter{},
+		nil,
+		entities.SessionFilter{},
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to update session if filter has empty thing IDs",
+		validToken,
+		"session-id",
+		entities.SessionFilter{ThingIDs: []string{""}},
+		entities.ErrSessionFilterInvalid,
+		previousFilter,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to update session if token is invalid",
+		validToken,
+		"session-id",
+		kitchenFilter,
+		entities.ErrTokenForbidden,
+		previousFilter,
+		&mocks.FakeThingProxy{ReturnErr: errTokenValidation},
+	},
+	{
+		"failed to update session that doesn't exist",
+		validToken,
+		"unknown-session",
+		kitchenFilter,
+		entities.ErrSessionNotFound,
+		previousFilter,
+		&mocks.FakeThingProxy{},
+	},
+	{
+		"failed to update session owned by another user",
+		validToken,
+		"other-user-session",
+		kitchenFilter,
+		entities.ErrSessionNotFound,
+		previousFilter,
+		&mocks.FakeThingProxy{},
+	},
+}
+
+func TestUpdateSession(t *testing.T) {
+	for _, tc := range usCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fakeThingProxy.
+				On("List", tc.authorization).
+				Return(tc.fakeThingProxy.Things, tc.fakeThingProxy.ReturnErr).
+				Maybe()
+
+			ctx := context.Background()
+			sessionStore := newSessionStore(map[string]string{
+				"session-id":         sessionOwner,
+				"other-user-session": "user2@cesar.org.br",
+			})
+			assert.NoError(t, sessionStore.SetFilter(ctx, "session-id", previousFilter))
+			assert.NoError(t, sessionStore.SetFilter(ctx, "other-user-session", previousFilter))
+
+			updateSessionInteractor := NewUpdateSession(tc.fakeThingProxy, sessionStore)
+			err := updateSessionInteractor.Execute(ctx, tc.authorization, tc.id, tc.filter)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+
+			filter, err := sessionStore.GetFilter(ctx, "session-id")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFilter, filter)
+
+			filter, err = sessionStore.GetFilter(ctx, "other-user-session")
+			assert.NoError(t, err)
+			assert.Equal(t, previousFilter, filter)
+
+			tc.fakeThingProxy.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateSessionFailsToSaveFilter(t *testing.T) {
+	fakeThingProxy := &mocks.FakeThingProxy{}
+	fakeThingProxy.On("List", validToken).Return(fakeThingProxy.Things, nil)
+	fakeSessionStore := &mocks.FakeSessionStore{}
+	fakeSessionStore.On("Get", "session-id").Return(sessionOwner, nil)
+	fakeSessionStore.On("SetFilter", "session-id", kitchenFilter).Return(errSaveSession)
+
+	updateSessionInteractor := NewUpdateSession(fakeThingProxy, fakeSessionStore)
+	err := updateSessionInteractor.Execute(context.Background(), validToken, "session-id", kitchenFilter)
+	assert.True(t, errors.Is(err, errSaveSession))
+}